@@ -0,0 +1,93 @@
+// Package fuzzing contains the fuzz tests of the conversions which are shared by the spoke versions
+package fuzzing
+
+import (
+	"math/rand"
+	"testing"
+
+	fuzz "github.com/google/gofuzz"
+	v1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
+	"k8s.io/apimachinery/pkg/api/apitesting/fuzzer"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metafuzzer "k8s.io/apimachinery/pkg/apis/meta/fuzzer"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/diff"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+const fuzzIterations = 1000
+
+// Note: Quantities only contain unexported fields which cannot be fuzzed
+func quantityFuzzerFuncs(codecs serializer.CodecFactory) []interface{} {
+	return []interface{}{
+		func(quantity *resource.Quantity, c fuzz.Continue) {
+			*quantity = *resource.NewQuantity(c.Int63n(1000), resource.DecimalSI)
+		},
+	}
+}
+
+func newFuzzer(t *testing.T, addToScheme func(*runtime.Scheme) error) *fuzz.Fuzzer {
+	scheme := runtime.NewScheme()
+	if err := addToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	funcs := fuzzer.MergeFuzzerFuncs(metafuzzer.Funcs, quantityFuzzerFuncs)
+	return fuzzer.FuzzerFor(funcs, rand.NewSource(rand.Int63()), serializer.NewCodecFactory(scheme))
+}
+
+// Note: The type meta is set by the API server and not part of the conversion
+func equalWithoutTypeMeta(original runtime.Object, converted runtime.Object) bool {
+	original.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{})
+	converted.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{})
+	return equality.Semantic.DeepEqual(original, converted)
+}
+
+// TestHubRoundTrip checks that fuzzed v1 applications are converted to the spoke version and back without losses
+func TestHubRoundTrip(t *testing.T, version string, addToScheme func(*runtime.Scheme) error, newSpoke func() conversion.Convertible) {
+	fuzzer := newFuzzer(t, addToScheme)
+	for i := 0; i < fuzzIterations; i++ {
+		original := &v1.Application{}
+		fuzzer.Fuzz(original)
+
+		spoke := newSpoke()
+		if err := spoke.ConvertFrom(original.DeepCopy()); err != nil {
+			t.Fatalf("ConvertFrom failed: %v", err)
+		}
+		hub := &v1.Application{}
+		if err := spoke.ConvertTo(hub); err != nil {
+			t.Fatalf("ConvertTo failed: %v", err)
+		}
+
+		if !equalWithoutTypeMeta(original, hub) {
+			t.Fatalf("v1 -> %s -> v1 is not lossless:\n%s", version, diff.ObjectReflectDiff(original, hub))
+		}
+	}
+}
+
+// TestSpokeRoundTrip checks that fuzzed applications of the spoke version are converted to v1 and back without losses
+func TestSpokeRoundTrip(t *testing.T, version string, addToScheme func(*runtime.Scheme) error, newSpoke func() conversion.Convertible) {
+	fuzzer := newFuzzer(t, addToScheme)
+	for i := 0; i < fuzzIterations; i++ {
+		original := newSpoke()
+		fuzzer.Fuzz(original)
+
+		hub := &v1.Application{}
+		if err := original.DeepCopyObject().(conversion.Convertible).ConvertTo(hub); err != nil {
+			t.Fatalf("ConvertTo failed: %v", err)
+		}
+		spoke := newSpoke()
+		if err := spoke.ConvertFrom(hub); err != nil {
+			t.Fatalf("ConvertFrom failed: %v", err)
+		}
+
+		if !equalWithoutTypeMeta(original, spoke) {
+			t.Fatalf("%s -> v1 -> %s is not lossless:\n%s", version, version, diff.ObjectReflectDiff(original, spoke))
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nheidloff/operator-sample-go/operator-application/utilities"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Note: Namespaces can override the defaults of the operator configuration for all applications in the namespace
+const DefaultAmountPodsAnnotation = "application.sample.ibm.com/default-amount-pods"
+const DefaultDatabaseNameAnnotation = "application.sample.ibm.com/default-database-name"
+const DefaultDatabaseNamespaceAnnotation = "application.sample.ibm.com/default-database-namespace"
+
+// Note: The webhook records which defaults it has applied and where they came from, e.g. {"spec.workload.amountPods":"namespace prod"}
+const AppliedDefaultsAnnotation = "application.sample.ibm.com/applied-defaults"
+
+const defaultSourceNamespace = "namespace "
+const defaultSourceNamespaceSelector = "operator configuration for namespaces "
+const defaultSourceOperatorConfiguration = "operator configuration"
+
+// Note: Defaults are only applied to fields which are not set, so explicit values always win. Annotations of the
+// namespace take precedence over the defaults which the operator configuration defines for the labels of the namespace,
+// which take precedence over the defaults of the operator configuration.
+func (r *Application) applyDefaults() {
+	namespaceAnnotations := map[string]string{}
+	labelDefaults := &utilities.NamespaceDefaults{}
+	if namespace := r.readNamespace(); namespace != nil {
+		if namespace.Annotations != nil {
+			namespaceAnnotations = namespace.Annotations
+		}
+		configuredDefaults, err := utilities.GetConfiguredNamespaceDefaults(namespace.Labels)
+		if err != nil {
+			// Note: Defaulters cannot reject requests, invalid defaults for namespace labels are ignored
+			applicationlog.Info("Invalid operator configuration. Ignoring the defaults for namespace labels.", "error", err.Error())
+		} else if configuredDefaults != nil {
+			labelDefaults = configuredDefaults
+		}
+	}
+	appliedDefaults := map[string]string{}
+
+	if r.Spec.Workload.AmountPods == nil {
+		amountPods, source := r.defaultAmountPods(namespaceAnnotations, labelDefaults)
+		r.Spec.Workload.AmountPods = &amountPods
+		appliedDefaults["spec.workload.amountPods"] = source
+	}
+	if r.Spec.Database.Name == "" {
+		databaseName, source := r.defaultString(namespaceAnnotations, DefaultDatabaseNameAnnotation, labelDefaults,
+			labelDefaults.DatabaseName, utilities.GetConfiguredDefaultDatabaseName())
+		r.Spec.Database.Name = databaseName
+		appliedDefaults["spec.database.name"] = source
+	}
+	if r.Spec.Database.Namespace == "" {
+		databaseNamespace, source := r.defaultString(namespaceAnnotations, DefaultDatabaseNamespaceAnnotation, labelDefaults,
+			labelDefaults.DatabaseNamespace, utilities.GetConfiguredDefaultDatabaseNamespace())
+		r.Spec.Database.Namespace = databaseNamespace
+		appliedDefaults["spec.database.namespace"] = source
+	}
+
+	if len(appliedDefaults) == 0 {
+		return
+	}
+	// Note: Defaults applied in earlier requests are kept in the record
+	if previous, ok := r.Annotations[AppliedDefaultsAnnotation]; ok {
+		previousDefaults := map[string]string{}
+		if json.Unmarshal([]byte(previous), &previousDefaults) == nil {
+			for fieldName, source := range previousDefaults {
+				if _, ok := appliedDefaults[fieldName]; !ok {
+					appliedDefaults[fieldName] = source
+				}
+			}
+		}
+	}
+	record, err := json.Marshal(appliedDefaults)
+	if err != nil {
+		applicationlog.Info("Applied defaults could not be recorded", "error", err.Error())
+		return
+	}
+	if r.Annotations == nil {
+		r.Annotations = map[string]string{}
+	}
+	r.Annotations[AppliedDefaultsAnnotation] = string(record)
+}
+
+func (r *Application) defaultAmountPods(namespaceAnnotations map[string]string, labelDefaults *utilities.NamespaceDefaults) (int32, string) {
+	if value, ok := namespaceAnnotations[DefaultAmountPodsAnnotation]; ok {
+		amountPods, err := utilities.ParseAmountPods(DefaultAmountPodsAnnotation, value)
+		if err == nil {
+			return amountPods, defaultSourceNamespace + r.Namespace
+		}
+		applicationlog.Info("Namespace "+r.Namespace+" has an invalid default. Using the operator configuration.", "error", err.Error())
+	}
+	if labelDefaults.AmountPods != nil {
+		return *labelDefaults.AmountPods, defaultSourceNamespaceSelector + labelDefaults.NamespaceSelector
+	}
+	amountPods, err := utilities.GetConfiguredDefaultAmountPods()
+	if err != nil {
+		// Note: Defaulters cannot reject requests, an invalid operator configuration falls back to one pod
+		applicationlog.Info("Invalid operator configuration. Using one pod.", "error", err.Error())
+		amountPods = 1
+	}
+	return amountPods, defaultSourceOperatorConfiguration
+}
+
+func (r *Application) defaultString(namespaceAnnotations map[string]string, annotation string,
+	labelDefaults *utilities.NamespaceDefaults, labelValue string, configuredValue string) (string, string) {
+
+	if value, ok := namespaceAnnotations[annotation]; ok && value != "" {
+		return value, defaultSourceNamespace + r.Namespace
+	}
+	if labelValue != "" {
+		return labelValue, defaultSourceNamespaceSelector + labelDefaults.NamespaceSelector
+	}
+	return configuredValue, defaultSourceOperatorConfiguration
+}
+
+func (r *Application) readNamespace() *corev1.Namespace {
+	if webhookReader == nil || r.Namespace == "" {
+		return nil
+	}
+	namespace := &corev1.Namespace{}
+	err := webhookReader.Get(context.Background(), types.NamespacedName{Name: r.Namespace}, namespace)
+	if err != nil {
+		applicationlog.Info("Namespace " + r.Namespace + " could not be read.")
+		return nil
+	}
+	return namespace
+}
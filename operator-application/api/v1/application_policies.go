@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/nheidloff/operator-sample-go/operator-application/policies"
+	"github.com/nheidloff/operator-sample-go/operator-application/utilities"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Note: The rules are compiled once per version of the config map, so changes apply without restarting the operator.
+// The config map is read for every request since webhooks run in all replicas, not only in the leader.
+var policyRulesCache = struct {
+	sync.Mutex
+	resourceVersion string
+	rules           []*policies.Rule
+}{}
+
+// Note: Admins define rules in a config map in the namespace of the operator, see policies.Rule
+func (r *Application) validatePolicies(oldApplication *Application) field.ErrorList {
+	var errorList field.ErrorList
+	rules := loadPolicyRules()
+	if len(rules) == 0 {
+		return errorList
+	}
+
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(r)
+	if err != nil {
+		return append(errorList, field.InternalError(field.NewPath("spec"), err))
+	}
+	var oldObject map[string]interface{}
+	if oldApplication != nil {
+		oldObject, err = runtime.DefaultUnstructuredConverter.ToUnstructured(oldApplication)
+		if err != nil {
+			return append(errorList, field.InternalError(field.NewPath("spec"), err))
+		}
+	}
+	namespaceObject := map[string]interface{}{}
+	if namespace := r.readNamespace(); namespace != nil {
+		namespaceObject, err = runtime.DefaultUnstructuredConverter.ToUnstructured(namespace)
+		if err != nil {
+			return append(errorList, field.InternalError(field.NewPath("metadata", "namespace"), err))
+		}
+	}
+
+	for _, violation := range policies.Evaluate(rules, object, oldObject, namespaceObject) {
+		path := field.NewPath("spec")
+		if violation.Field != "" {
+			fields := strings.Split(violation.Field, ".")
+			path = field.NewPath(fields[0], fields[1:]...)
+		}
+		errorList = append(errorList, field.Forbidden(path, violation.Message))
+	}
+	return errorList
+}
+
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get
+func loadPolicyRules() []*policies.Rule {
+	if webhookReader == nil {
+		return nil
+	}
+	name := utilities.GetConfiguredPolicyConfigMapName()
+	namespace := utilities.GetOperatorNamespace()
+	configMap := &corev1.ConfigMap{}
+	err := webhookReader.Get(context.Background(), types.NamespacedName{Name: name, Namespace: namespace}, configMap)
+	policyRulesCache.Lock()
+	defer policyRulesCache.Unlock()
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			policyRulesCache.resourceVersion = ""
+			policyRulesCache.rules = nil
+			return nil
+		}
+		applicationlog.Info("Config map "+namespace+"/"+name+" with policies could not be read. Using the last known policies.", "error", err.Error())
+		return policyRulesCache.rules
+	}
+
+	if configMap.ResourceVersion != policyRulesCache.resourceVersion {
+		rules, errs := policies.ParseRules(configMap.Data)
+		for _, err := range errs {
+			applicationlog.Info("Ignoring policy rule in config map "+namespace+"/"+name, "error", err.Error())
+		}
+		applicationlog.Info("Loaded policy rules from config map "+namespace+"/"+name, "rules", len(rules))
+		policyRulesCache.resourceVersion = configMap.ResourceVersion
+		policyRulesCache.rules = rules
+	}
+	return policyRulesCache.rules
+}
@@ -0,0 +1,283 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/nheidloff/operator-sample-go/operator-application/utilities"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Note: See https://semver.org/#is-there-a-suggested-regular-expression-regex-to-check-a-semver-string
+var semanticVersionRegex = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// Note: All problems are returned at once so that users don't have to fix them one by one
+func (r *Application) validateApplication() error {
+	var errorList field.ErrorList
+	errorList = append(errorList, r.validateSpec()...)
+	errorList = append(errorList, r.validatePodSecurity()...)
+	errorList = append(errorList, r.validatePolicies(nil)...)
+	if len(errorList) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Application").GroupKind(), r.Name, errorList)
+}
+
+// Note: Only changes of the spec are validated, so that e.g. finalizers of applications which were stored before a rule
+// was introduced can still be removed and the no-op updates of the storage version migration are not rejected. Applications
+// which are being deleted are not validated either.
+func (r *Application) validateApplicationUpdate(oldApplication *Application) error {
+	if r.DeletionTimestamp != nil || equality.Semantic.DeepEqual(r.Spec, oldApplication.Spec) {
+		return nil
+	}
+	var errorList field.ErrorList
+	errorList = append(errorList, ratchetErrors(r.validateSpec(), oldApplication.validateSpec())...)
+	errorList = append(errorList, r.validateDatabaseImmutable(oldApplication)...)
+	errorList = append(errorList, ratchetErrors(r.validatePodSecurity(), oldApplication.validatePodSecurity())...)
+	errorList = append(errorList, ratchetErrors(r.validatePolicies(oldApplication), oldApplication.validatePolicies(nil))...)
+	if len(errorList) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Application").GroupKind(), r.Name, errorList)
+}
+
+// Note: Problems which the old application has too are not reported, only the ones of changed fields. Internal errors
+// are always reported.
+func ratchetErrors(errorList field.ErrorList, oldErrorList field.ErrorList) field.ErrorList {
+	var ratchetedErrorList field.ErrorList
+	existingErrors := map[string]bool{}
+	for _, oldError := range oldErrorList {
+		existingErrors[oldError.Error()] = true
+	}
+	for _, err := range errorList {
+		if err.Type == field.ErrorTypeInternal || !existingErrors[err.Error()] {
+			ratchetedErrorList = append(ratchetedErrorList, err)
+		}
+	}
+	return ratchetedErrorList
+}
+
+// Note: Changing the database without relocation would orphan the old database
+func (r *Application) validateDatabaseImmutable(oldApplication *Application) field.ErrorList {
+	var errorList field.ErrorList
+	if r.Annotations[RelocateDatabaseAnnotation] == "true" {
+		return errorList
+	}
+	message := "cannot be changed unless the annotation " + RelocateDatabaseAnnotation + "=true is set to relocate the database"
+	databasePath := field.NewPath("spec", "database")
+	if r.Spec.Database.Name != oldApplication.Spec.Database.Name {
+		errorList = append(errorList, field.Forbidden(databasePath.Child("name"), message))
+	}
+	if r.Spec.Database.Namespace != oldApplication.Spec.Database.Namespace {
+		errorList = append(errorList, field.Forbidden(databasePath.Child("namespace"), message))
+	}
+	return errorList
+}
+
+func (r *Application) validateSpec() field.ErrorList {
+	var errorList field.ErrorList
+	specPath := field.NewPath("spec")
+	workload := r.Spec.Workload
+	workloadPath := specPath.Child("workload")
+
+	if !semanticVersionRegex.MatchString(r.Spec.Version) {
+		errorList = append(errorList, field.Invalid(specPath.Child("version"), r.Spec.Version, "must be a semantic version, e.g. 1.0.0"))
+	}
+
+	maxAmountPods, err := utilities.GetConfiguredMaxAmountPods()
+	if err != nil {
+		errorList = append(errorList, field.InternalError(workloadPath.Child("amountPods"), err))
+	} else if maxAmountPods > 0 && workload.AmountPods != nil && *workload.AmountPods > maxAmountPods {
+		errorList = append(errorList, field.Invalid(workloadPath.Child("amountPods"), *workload.AmountPods, fmt.Sprintf("must not be greater than %d", maxAmountPods)))
+	}
+
+	databasePath := specPath.Child("database")
+	for _, message := range validation.IsDNS1123Subdomain(r.Spec.Database.Name) {
+		errorList = append(errorList, field.Invalid(databasePath.Child("name"), r.Spec.Database.Name, message))
+	}
+	for _, message := range validation.IsDNS1123Label(r.Spec.Database.Namespace) {
+		errorList = append(errorList, field.Invalid(databasePath.Child("namespace"), r.Spec.Database.Namespace, message))
+	}
+
+	errorList = append(errorList, validateSchemaUrl(specPath.Child("schema", "url"), r.Spec.Schema.Url)...)
+
+	if workload.ServiceAccount != nil {
+		errorList = append(errorList, ValidatePermissions(workloadPath.Child("serviceAccount", "permissions"), workload.ServiceAccount.Permissions)...)
+	}
+
+	if workload.Probes != nil {
+		probesPath := workloadPath.Child("probes")
+		errorList = append(errorList, validateProbe(probesPath.Child("readiness"), workload.Probes.Readiness, true)...)
+		errorList = append(errorList, validateProbe(probesPath.Child("liveness"), workload.Probes.Liveness, false)...)
+		errorList = append(errorList, validateProbe(probesPath.Child("startup"), workload.Probes.Startup, false)...)
+	}
+	return errorList
+}
+
+func validateSchemaUrl(path *field.Path, schemaUrl string) field.ErrorList {
+	var errorList field.ErrorList
+	if schemaUrl == "" {
+		return errorList
+	}
+	parsedUrl, err := url.Parse(schemaUrl)
+	if err != nil || parsedUrl.Host == "" {
+		return append(errorList, field.Invalid(path, schemaUrl, "must be an absolute URL"))
+	}
+	allowedSchemes := utilities.GetConfiguredAllowedSchemaUrlSchemes()
+	for _, allowedScheme := range allowedSchemes {
+		if strings.ToLower(parsedUrl.Scheme) == allowedScheme {
+			return errorList
+		}
+	}
+	return append(errorList, field.NotSupported(path.Child("scheme"), parsedUrl.Scheme, allowedSchemes))
+}
+
+// Note: The operator neither escalates nor binds roles, so it can only grant permissions which it holds itself.
+// Service accounts of applications may therefore only read these resources of the core API group.
+var (
+	allowedPermissionResources = []string{"configmaps", "endpoints", "pods", "services"}
+	allowedPermissionVerbs     = []string{"get", "list", "watch"}
+)
+
+// ValidatePermissions returns the problems of the permissions of the service account
+// Note: Everything outside the allow-list is rejected instead of trying to find the harmful rules
+func ValidatePermissions(path *field.Path, permissions []rbacv1.PolicyRule) field.ErrorList {
+	var errorList field.ErrorList
+	for i, permission := range permissions {
+		permissionPath := path.Index(i)
+		for j, verb := range permission.Verbs {
+			if !containsString(allowedPermissionVerbs, verb) {
+				errorList = append(errorList, field.NotSupported(permissionPath.Child("verbs").Index(j), verb, allowedPermissionVerbs))
+			}
+		}
+		for j, apiGroup := range permission.APIGroups {
+			if apiGroup != "" {
+				errorList = append(errorList, field.NotSupported(permissionPath.Child("apiGroups").Index(j), apiGroup, []string{""}))
+			}
+		}
+		for j, resource := range permission.Resources {
+			if !containsString(allowedPermissionResources, resource) {
+				errorList = append(errorList, field.NotSupported(permissionPath.Child("resources").Index(j), resource, allowedPermissionResources))
+			}
+		}
+		if len(permission.NonResourceURLs) > 0 {
+			errorList = append(errorList, field.Forbidden(permissionPath.Child("nonResourceURLs"), "cannot be granted in namespaces"))
+		}
+	}
+	return errorList
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+func validateProbe(path *field.Path, probe *ApplicationProbe, isReadiness bool) field.ErrorList {
+	var errorList field.ErrorList
+	if probe == nil {
+		return errorList
+	}
+	if probe.Type == "Exec" && len(probe.Command) == 0 {
+		errorList = append(errorList, field.Required(path.Child("command"), "is required for Exec probes"))
+	}
+	if !isReadiness && probe.SuccessThreshold != nil && *probe.SuccessThreshold != 1 {
+		errorList = append(errorList, field.Invalid(path.Child("successThreshold"), *probe.SuccessThreshold, "must be 1"))
+	}
+	return errorList
+}
+
+// Note: The security contexts are validated against the Pod Security Standard level enforced in the namespace
+func (r *Application) validatePodSecurity() field.ErrorList {
+	var errorList field.ErrorList
+	if r.Spec.Workload.PodSecurityContext == nil && r.Spec.Workload.SecurityContext == nil {
+		return errorList
+	}
+	if webhookReader == nil {
+		return errorList
+	}
+
+	// Note: Without the namespace the enforced level is unknown, so the request is rejected rather than admitted unchecked
+	workloadPath := field.NewPath("spec", "workload")
+	namespace := &corev1.Namespace{}
+	err := webhookReader.Get(context.Background(), types.NamespacedName{Name: r.Namespace}, namespace)
+	if err != nil {
+		applicationlog.Info("Namespace " + r.Namespace + " could not be read. Pod security cannot be validated.")
+		return append(errorList, field.InternalError(workloadPath, fmt.Errorf("namespace %s could not be read: %v", r.Namespace, err)))
+	}
+	level := namespace.Labels[utilities.PodSecurityEnforceLabelName]
+
+	podSecurityContext := utilities.DefaultPodSecurityContext()
+	if r.Spec.Workload.PodSecurityContext != nil {
+		podSecurityContext = r.Spec.Workload.PodSecurityContext
+	}
+	containerSecurityContext := utilities.DefaultContainerSecurityContext()
+	if r.Spec.Workload.SecurityContext != nil {
+		containerSecurityContext = r.Spec.Workload.SecurityContext
+	}
+	for _, violation := range utilities.CheckPodSecurity(level, podSecurityContext, containerSecurityContext) {
+		errorList = append(errorList, field.Forbidden(workloadPath.Child(violation.Context, violation.Field),
+			violation.Message+" by the "+level+" Pod Security Standard enforced in namespace "+r.Namespace))
+	}
+	return errorList
+}
+
+// Note: Protected applications can only be deleted if they don't own live data
+func (r *Application) validateDeletion() error {
+	if !r.Spec.DeletionProtection {
+		return nil
+	}
+	var reasons []string
+	if r.Status.SchemaCreated {
+		reasons = append(reasons, "the database schema has been created")
+	}
+	consumers, err := r.otherDatabaseConsumers()
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+	if len(consumers) > 0 {
+		reasons = append(reasons, "the database is also used by "+strings.Join(consumers, ", "))
+	}
+	if len(reasons) == 0 {
+		return nil
+	}
+	return apierrors.NewForbidden(GroupVersion.WithResource("applications").GroupResource(), r.Name,
+		fmt.Errorf("deletion protection is enabled and %s. To delete the application anyway, set spec.deletionProtection to false first, "+
+			"e.g. kubectl patch applications.application.sample.ibm.com %s -n %s --type merge -p '{\"spec\":{\"deletionProtection\":false}}'",
+			strings.Join(reasons, " and "), r.Name, r.Namespace))
+}
+
+func (r *Application) otherDatabaseConsumers() ([]string, error) {
+	consumers := []string{}
+	if webhookReader == nil {
+		return consumers, nil
+	}
+	applications := &ApplicationList{}
+	err := webhookReader.List(context.Background(), applications)
+	if err != nil {
+		return nil, err
+	}
+	for _, application := range applications.Items {
+		if application.Namespace == r.Namespace && application.Name == r.Name {
+			continue
+		}
+		if application.Spec.Database == r.Spec.Database {
+			consumers = append(consumers, application.Namespace+"/"+application.Name)
+		}
+	}
+	return consumers, nil
+}
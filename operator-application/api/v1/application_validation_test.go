@@ -0,0 +1,73 @@
+package v1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nheidloff/operator-sample-go/operator-application/utilities"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestUpdatesOnlyValidateChangedFields(t *testing.T) {
+	// Note: Applications stored before the validation was introduced may have the old default database name
+	oldApplication := validApplication("stored")
+	oldApplication.Spec.Database.Name = "Niklas db name"
+
+	application := oldApplication.DeepCopy()
+	application.Labels = map[string]string{"team": "movies"}
+	if err := application.validateApplicationUpdate(oldApplication); err != nil {
+		t.Fatalf("expected update of the metadata to be admitted, got %v", err)
+	}
+
+	amountPods := int32(2)
+	application.Spec.Workload.AmountPods = &amountPods
+	if err := application.validateApplicationUpdate(oldApplication); err != nil {
+		t.Fatalf("expected update of a valid field to be admitted, got %v", err)
+	}
+
+	application.Spec.Version = "latest"
+	err := application.validateApplicationUpdate(oldApplication)
+	if err == nil || !strings.Contains(err.Error(), "spec.version") || strings.Contains(err.Error(), "spec.database.name") {
+		t.Fatalf("expected only the changed version to be rejected, got %v", err)
+	}
+}
+
+func TestUpdatesOnlyReportNewPolicyViolations(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	policies := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: utilities.GetConfiguredPolicyConfigMapName(), Namespace: utilities.GetOperatorNamespace()},
+		Data: map[string]string{"max-pods": "expression: object.spec.workload.amountPods <= 3\n" +
+			"message: Only three pods are allowed\nfield: spec.workload.amountPods\n"},
+	}
+	webhookReader = fake.NewClientBuilder().WithScheme(scheme).WithObjects(policies).Build()
+	defer func() { webhookReader = nil }()
+
+	oldApplication := validApplication("policies")
+	application := oldApplication.DeepCopy()
+	amountPods := int32(5)
+	application.Spec.Workload.AmountPods = &amountPods
+	err := application.validateApplicationUpdate(oldApplication)
+	if err == nil || !strings.Contains(err.Error(), "Only three pods are allowed") {
+		t.Fatalf("expected new policy violation to be rejected, got %v", err)
+	}
+
+	// Note: Applications which violated the policy before it was introduced can still be changed and deleted
+	changedApplication := application.DeepCopy()
+	changedApplication.Spec.Version = "1.0.1"
+	if err := changedApplication.validateApplicationUpdate(application); err != nil {
+		t.Fatalf("expected existing policy violation to be ignored, got %v", err)
+	}
+	deletedApplication := changedApplication.DeepCopy()
+	deletedApplication.Spec.Version = "latest"
+	deletedApplication.DeletionTimestamp = &metav1.Time{}
+	if err := deletedApplication.validateApplicationUpdate(changedApplication); err != nil {
+		t.Fatalf("expected application which is being deleted not to be validated, got %v", err)
+	}
+}
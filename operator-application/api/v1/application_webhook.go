@@ -14,11 +14,12 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-package v1beta1
+package v1
 
 import (
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
@@ -26,7 +27,11 @@ import (
 // log is for logging in this package.
 var applicationlog = logf.Log.WithName("application-resource")
 
+// Note: The webhook functions don't get a client passed in, so the reader of the manager is stored
+var webhookReader client.Reader
+
 func (r *Application) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	webhookReader = mgr.GetAPIReader()
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
 		Complete()
@@ -34,7 +39,7 @@ func (r *Application) SetupWebhookWithManager(mgr ctrl.Manager) error {
 
 // TODO(user): EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 
-//+kubebuilder:webhook:path=/mutate-application-sample-ibm-com-v1beta1-application,mutating=true,failurePolicy=fail,sideEffects=None,groups=application.sample.ibm.com,resources=applications,verbs=create;update,versions=v1beta1,name=mapplication.kb.io,admissionReviewVersions={v1alpha1,v1beta1}
+//+kubebuilder:webhook:path=/mutate-application-sample-ibm-com-v1-application,mutating=true,failurePolicy=fail,sideEffects=None,groups=application.sample.ibm.com,resources=applications,verbs=create;update,versions=v1,name=mapplication.kb.io,admissionReviewVersions=v1
 
 var _ webhook.Defaulter = &Application{}
 
@@ -42,13 +47,10 @@ var _ webhook.Defaulter = &Application{}
 func (r *Application) Default() {
 	applicationlog.Info("niklas default")
 	applicationlog.Info("default", "name", r.Name)
-	if r.Spec.DatabaseName == "" {
-		r.Spec.DatabaseName = "Niklas db name"
-	}
+	r.applyDefaults()
 }
 
-// TODO(user): change verbs to "verbs=create;update;delete" if you want to enable deletion validation.
-//+kubebuilder:webhook:path=/validate-application-sample-ibm-com-v1beta1-application,mutating=false,failurePolicy=fail,sideEffects=None,groups=application.sample.ibm.com,resources=applications,verbs=create;update,versions=v1beta1,name=vapplication.kb.io,admissionReviewVersions={v1alpha1,v1beta1}
+//+kubebuilder:webhook:path=/validate-application-sample-ibm-com-v1-application,mutating=false,failurePolicy=fail,sideEffects=None,groups=application.sample.ibm.com,resources=applications,verbs=create;update;delete,versions=v1,name=vapplication.kb.io,admissionReviewVersions=v1
 
 var _ webhook.Validator = &Application{}
 
@@ -57,8 +59,7 @@ func (r *Application) ValidateCreate() error {
 	applicationlog.Info("niklas create")
 	applicationlog.Info("validate create", "name", r.Name)
 
-	// TODO(user): fill in your validation logic upon object creation.
-	return nil
+	return r.validateApplication()
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
@@ -66,14 +67,16 @@ func (r *Application) ValidateUpdate(old runtime.Object) error {
 	applicationlog.Info("niklas update")
 	applicationlog.Info("validate update", "name", r.Name)
 
-	// TODO(user): fill in your validation logic upon object update.
-	return nil
+	oldApplication, ok := old.(*Application)
+	if !ok {
+		return r.validateApplication()
+	}
+	return r.validateApplicationUpdate(oldApplication)
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
 func (r *Application) ValidateDelete() error {
 	applicationlog.Info("validate delete", "name", r.Name)
 
-	// TODO(user): fill in your validation logic upon object deletion.
-	return nil
+	return r.validateDeletion()
 }
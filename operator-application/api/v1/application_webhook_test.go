@@ -0,0 +1,200 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/nheidloff/operator-sample-go/operator-application/utilities"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func validApplication(name string) *Application {
+	var amountPods int32 = 1
+	return &Application{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: ApplicationSpec{
+			Version:  "1.0.0",
+			Title:    "Movies",
+			Workload: ApplicationWorkload{AmountPods: &amountPods},
+			Database: ApplicationDatabase{Name: "database", Namespace: "database"},
+			Schema:   ApplicationSchema{Url: "https://raw.githubusercontent.com/IBM/multi-tenancy/main/installapp/postgres-config/create-populate-tenant-a.sql"},
+		},
+	}
+}
+
+var _ = Describe("Application validating webhook", func() {
+	AfterEach(func() {
+		os.Unsetenv(utilities.ConfigurationMaxAmountPods)
+	})
+
+	table.DescribeTable("validates applications on creation",
+		func(name string, mutate func(*Application), expectedErrors []string) {
+			application := validApplication(name)
+			mutate(application)
+
+			err := k8sClient.Create(ctx, application)
+			if len(expectedErrors) == 0 {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(k8sClient.Delete(ctx, application)).To(Succeed())
+				return
+			}
+			Expect(err).To(HaveOccurred())
+			for _, expectedError := range expectedErrors {
+				Expect(err.Error()).To(ContainSubstring(expectedError))
+			}
+		},
+		table.Entry("valid application", "valid", func(application *Application) {}, nil),
+		table.Entry("pre-release version", "prerelease", func(application *Application) {
+			application.Spec.Version = "1.2.3-beta.1+build.5"
+		}, nil),
+		table.Entry("invalid version", "invalid-version", func(application *Application) {
+			application.Spec.Version = "1.0"
+		}, []string{"spec.version"}),
+		table.Entry("amount of pods above the configured maximum", "too-many-pods", func(application *Application) {
+			os.Setenv(utilities.ConfigurationMaxAmountPods, "3")
+			amountPods := int32(4)
+			application.Spec.Workload.AmountPods = &amountPods
+		}, []string{"spec.workload.amountPods", "must not be greater than 3"}),
+		table.Entry("amount of pods at the configured maximum", "max-pods", func(application *Application) {
+			os.Setenv(utilities.ConfigurationMaxAmountPods, "3")
+			amountPods := int32(3)
+			application.Spec.Workload.AmountPods = &amountPods
+		}, nil),
+		table.Entry("invalid database name", "invalid-database-name", func(application *Application) {
+			application.Spec.Database.Name = "Niklas db name"
+		}, []string{"spec.database.name"}),
+		table.Entry("invalid database namespace", "invalid-database-namespace", func(application *Application) {
+			application.Spec.Database.Namespace = "database.namespace"
+		}, []string{"spec.database.namespace"}),
+		table.Entry("unsupported schema URL scheme", "invalid-schema-scheme", func(application *Application) {
+			application.Spec.Schema.Url = "ftp://example.com/schema.sql"
+		}, []string{"spec.schema.url.scheme"}),
+		table.Entry("relative schema URL", "relative-schema-url", func(application *Application) {
+			application.Spec.Schema.Url = "schema.sql"
+		}, []string{"spec.schema.url", "must be an absolute URL"}),
+		table.Entry("exec probe without command", "exec-probe", func(application *Application) {
+			application.Spec.Workload.Probes = &ApplicationProbes{Liveness: &ApplicationProbe{Type: "Exec"}}
+		}, []string{"spec.workload.probes.liveness.command"}),
+		table.Entry("wildcard permissions", "wildcard-permissions", func(application *Application) {
+			application.Spec.Workload.ServiceAccount = &ApplicationServiceAccount{Permissions: []rbacv1.PolicyRule{{
+				APIGroups: []string{""}, Resources: []string{"*"}, Verbs: []string{"get", "*"},
+			}}}
+		}, []string{"spec.workload.serviceAccount.permissions[0].verbs[1]", "spec.workload.serviceAccount.permissions[0].resources[0]"}),
+		table.Entry("permissions outside of the allow-list", "disallowed-permissions", func(application *Application) {
+			application.Spec.Workload.ServiceAccount = &ApplicationServiceAccount{Permissions: []rbacv1.PolicyRule{{
+				APIGroups: []string{"", "apps"}, Resources: []string{"pods", "secrets"}, Verbs: []string{"get", "create"},
+			}}}
+		}, []string{"spec.workload.serviceAccount.permissions[0].verbs[1]", "spec.workload.serviceAccount.permissions[0].apiGroups[1]", "spec.workload.serviceAccount.permissions[0].resources[1]"}),
+		table.Entry("all problems at once", "multiple-problems", func(application *Application) {
+			application.Spec.Version = "latest"
+			application.Spec.Database.Name = "Database"
+			application.Spec.Schema.Url = "http://example.com/schema.sql"
+		}, []string{"spec.version", "spec.database.name", "spec.schema.url.scheme"}),
+	)
+
+	It("rejects changes of the database without the relocation annotation", func() {
+		application := validApplication("immutable-database")
+		Expect(k8sClient.Create(ctx, application)).To(Succeed())
+
+		application.Spec.Database.Name = "other-database"
+		err := k8sClient.Update(ctx, application)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.database.name"))
+
+		application.Annotations = map[string]string{RelocateDatabaseAnnotation: "true"}
+		Expect(k8sClient.Update(ctx, application)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, application)).To(Succeed())
+	})
+
+	It("reports the security context field which violates the enforced Pod Security Standard", func() {
+		namespace := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "restricted",
+				Labels: map[string]string{utilities.PodSecurityEnforceLabelName: utilities.PodSecurityLevelRestricted},
+			},
+		}
+		Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+
+		var rootUser int64 = 0
+		application := validApplication("root-user")
+		application.Namespace = namespace.Name
+		application.Spec.Workload.PodSecurityContext = utilities.DefaultPodSecurityContext()
+		application.Spec.Workload.PodSecurityContext.RunAsUser = &rootUser
+		err := k8sClient.Create(ctx, application)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.workload.podSecurityContext.runAsUser"))
+		Expect(err.Error()).NotTo(ContainSubstring("spec.workload.securityContext"))
+	})
+
+	It("applies the defaults of the namespace and its labels and records them", func() {
+		os.Setenv(utilities.ConfigurationNamespaceDefaults, `[
+			{"namespaceSelector": "env=test", "amountPods": 2},
+			{"namespaceSelector": "env=prod", "amountPods": 3, "databaseNamespace": "database-shared"}
+		]`)
+		defer os.Unsetenv(utilities.ConfigurationNamespaceDefaults)
+		namespace := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "prod",
+				Labels:      map[string]string{"env": "prod"},
+				Annotations: map[string]string{DefaultDatabaseNamespaceAnnotation: "database-prod"},
+			},
+		}
+		Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+
+		application := validApplication("defaulted")
+		application.Namespace = namespace.Name
+		application.Spec.Workload.AmountPods = nil
+		application.Spec.Database = ApplicationDatabase{}
+		Expect(k8sClient.Create(ctx, application)).To(Succeed())
+
+		Expect(*application.Spec.Workload.AmountPods).To(Equal(int32(3)))
+		Expect(application.Spec.Database.Namespace).To(Equal("database-prod"))
+		Expect(application.Spec.Database.Name).To(Equal("database"))
+		Expect(application.Annotations[AppliedDefaultsAnnotation]).To(MatchJSON(`{
+			"spec.workload.amountPods": "operator configuration for namespaces env=prod",
+			"spec.database.name": "operator configuration",
+			"spec.database.namespace": "namespace prod"
+		}`))
+		Expect(k8sClient.Delete(ctx, application)).To(Succeed())
+	})
+
+	It("refuses to delete protected applications with live data", func() {
+		application := validApplication("protected")
+		application.Spec.DeletionProtection = true
+		Expect(k8sClient.Create(ctx, application)).To(Succeed())
+		application.Status.SchemaCreated = true
+		Expect(k8sClient.Status().Update(ctx, application)).To(Succeed())
+
+		err := k8sClient.Delete(ctx, application)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.deletionProtection"))
+
+		application.Spec.DeletionProtection = false
+		Expect(k8sClient.Update(ctx, application)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, application)).To(Succeed())
+	})
+})
@@ -1,38 +1,134 @@
 package v1alpha1
 
 import (
-	"github.com/nheidloff/operator-sample-go/operator-application/api/v1beta1"
+	"encoding/json"
+
+	v1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
+	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/conversion"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 var applicationlog = logf.Log.WithName("application-resource")
 
-// convert this application to the hub version (v1beta1)
+// Note: Fields which only exist in the hub version are stored in this annotation when converting to v1alpha1,
+// so that they are not lost when the application is written back via v1alpha1
+const HubFieldsAnnotation = "application.sample.ibm.com/hub-fields"
+
+// Note: Applications which were written via v1alpha1 while v1beta1 was the hub version still have the fields stored in
+// this annotation, they have the same format as the fields of the hub version
+const v1beta1FieldsAnnotation = "application.sample.ibm.com/v1beta1-fields"
+
+// Note: Every field of the hub version which doesn't exist in v1alpha1 needs to be listed here
+type hubFields struct {
+	Title                     string                        `json:"title,omitempty"`
+	AmountPodsUnset           bool                          `json:"amountPodsUnset,omitempty"`
+	Resources                 *corev1.ResourceRequirements  `json:"resources,omitempty"`
+	Probes                    *v1.ApplicationProbes         `json:"probes,omitempty"`
+	PodSecurityContext        *corev1.PodSecurityContext    `json:"podSecurityContext,omitempty"`
+	SecurityContext           *corev1.SecurityContext       `json:"securityContext,omitempty"`
+	ServiceAccount            *v1.ApplicationServiceAccount `json:"serviceAccount,omitempty"`
+	Network                   *v1.ApplicationNetwork        `json:"network,omitempty"`
+	DeletionProtection        bool                          `json:"deletionProtection,omitempty"`
+	DatabaseName              string                        `json:"statusDatabaseName,omitempty"`
+	DatabaseNamespace         string                        `json:"statusDatabaseNamespace,omitempty"`
+	PreviousDatabaseName      string                        `json:"statusPreviousDatabaseName,omitempty"`
+	PreviousDatabaseNamespace string                        `json:"statusPreviousDatabaseNamespace,omitempty"`
+}
+
+// convert this application to the hub version (v1)
 func (src *Application) ConvertTo(dstRaw conversion.Hub) error {
 	applicationlog.Info("Calling ConvertTo")
-	dst := dstRaw.(*v1beta1.Application)
-	dst.Spec.AmountPods = src.Spec.AmountPods
-	dst.Spec.DatabaseName = src.Spec.DatabaseName
-	dst.Spec.DatabaseNamespace = src.Spec.DatabaseNamespace
-	dst.Spec.SchemaUrl = src.Spec.SchemaUrl
+	dst := dstRaw.(*v1.Application)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+
+	fields := hubFields{}
+	stashed, ok := dst.Annotations[HubFieldsAnnotation]
+	if !ok {
+		stashed, ok = dst.Annotations[v1beta1FieldsAnnotation]
+	}
+	if ok {
+		err := json.Unmarshal([]byte(stashed), &fields)
+		if err != nil {
+			return err
+		}
+		delete(dst.Annotations, HubFieldsAnnotation)
+		delete(dst.Annotations, v1beta1FieldsAnnotation)
+		if len(dst.Annotations) == 0 {
+			dst.Annotations = nil
+		}
+	}
+
+	amountPods := src.Spec.AmountPods
+	dst.Spec.Workload.AmountPods = &amountPods
+	if fields.AmountPodsUnset && amountPods == 0 {
+		dst.Spec.Workload.AmountPods = nil
+	}
+	dst.Spec.Database.Name = src.Spec.DatabaseName
+	dst.Spec.Database.Namespace = src.Spec.DatabaseNamespace
+	dst.Spec.Schema.Url = src.Spec.SchemaUrl
 	dst.Spec.Version = src.Spec.Version
-	dst.Spec.Title = "undefined"
-	dst.ObjectMeta = src.ObjectMeta
-	dst.Status.Conditions = src.Status.Conditions
+	dst.Spec.Title = fields.Title
+	dst.Spec.Workload.Resources = fields.Resources
+	dst.Spec.Workload.Probes = fields.Probes
+	dst.Spec.Workload.PodSecurityContext = fields.PodSecurityContext
+	dst.Spec.Workload.SecurityContext = fields.SecurityContext
+	dst.Spec.Workload.ServiceAccount = fields.ServiceAccount
+	dst.Spec.Exposure.Network = fields.Network
+	dst.Spec.DeletionProtection = fields.DeletionProtection
+
+	dst.Status.Conditions = src.DeepCopy().Status.Conditions
+	dst.Status.SchemaCreated = src.Status.SchemaCreated
+	dst.Status.DatabaseName = fields.DatabaseName
+	dst.Status.DatabaseNamespace = fields.DatabaseNamespace
+	dst.Status.PreviousDatabaseName = fields.PreviousDatabaseName
+	dst.Status.PreviousDatabaseNamespace = fields.PreviousDatabaseNamespace
 	return nil
 }
 
-// convert from the hub version (v1beta1) to this version
+// convert from the hub version (v1) to this version
 func (dst *Application) ConvertFrom(srcRaw conversion.Hub) error {
 	applicationlog.Info("Calling ConvertFrom")
-	src := srcRaw.(*v1beta1.Application)
-	dst.Spec.AmountPods = src.Spec.AmountPods
-	dst.Spec.DatabaseName = src.Spec.DatabaseName
-	dst.Spec.DatabaseNamespace = src.Spec.DatabaseNamespace
-	dst.Spec.SchemaUrl = src.Spec.SchemaUrl
+	src := srcRaw.(*v1.Application)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+
+	dst.Spec.AmountPods = 0
+	if src.Spec.Workload.AmountPods != nil {
+		dst.Spec.AmountPods = *src.Spec.Workload.AmountPods
+	}
+	dst.Spec.DatabaseName = src.Spec.Database.Name
+	dst.Spec.DatabaseNamespace = src.Spec.Database.Namespace
+	dst.Spec.SchemaUrl = src.Spec.Schema.Url
 	dst.Spec.Version = src.Spec.Version
-	dst.ObjectMeta = src.ObjectMeta
-	dst.Status.Conditions = src.Status.Conditions
+
+	dst.Status.Conditions = src.DeepCopy().Status.Conditions
+	dst.Status.SchemaCreated = src.Status.SchemaCreated
+
+	fields := hubFields{
+		Title:                     src.Spec.Title,
+		AmountPodsUnset:           src.Spec.Workload.AmountPods == nil,
+		Resources:                 src.Spec.Workload.Resources,
+		Probes:                    src.Spec.Workload.Probes,
+		PodSecurityContext:        src.Spec.Workload.PodSecurityContext,
+		SecurityContext:           src.Spec.Workload.SecurityContext,
+		ServiceAccount:            src.Spec.Workload.ServiceAccount,
+		Network:                   src.Spec.Exposure.Network,
+		DeletionProtection:        src.Spec.DeletionProtection,
+		DatabaseName:              src.Status.DatabaseName,
+		DatabaseNamespace:         src.Status.DatabaseNamespace,
+		PreviousDatabaseName:      src.Status.PreviousDatabaseName,
+		PreviousDatabaseNamespace: src.Status.PreviousDatabaseNamespace,
+	}
+	if fields == (hubFields{}) {
+		return nil
+	}
+	stashed, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	if dst.Annotations == nil {
+		dst.Annotations = map[string]string{}
+	}
+	dst.Annotations[HubFieldsAnnotation] = string(stashed)
 	return nil
 }
@@ -0,0 +1,39 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/nheidloff/operator-sample-go/operator-application/api/internal/fuzzing"
+	v1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+func newSpoke() conversion.Convertible {
+	return &Application{}
+}
+
+func TestHubRoundTrip(t *testing.T) {
+	fuzzing.TestHubRoundTrip(t, "v1alpha1", AddToScheme, newSpoke)
+}
+
+func TestSpokeRoundTrip(t *testing.T) {
+	fuzzing.TestSpokeRoundTrip(t, "v1alpha1", AddToScheme, newSpoke)
+}
+
+func TestConvertToReadsV1beta1Fields(t *testing.T) {
+	src := &Application{}
+	src.Annotations = map[string]string{
+		v1beta1FieldsAnnotation: `{"title":"Movies","amountPodsUnset":true,"deletionProtection":true,"statusDatabaseName":"database"}`,
+	}
+	dst := &v1.Application{}
+	if err := src.ConvertTo(dst); err != nil {
+		t.Fatal(err)
+	}
+	if dst.Spec.Title != "Movies" || dst.Spec.Workload.AmountPods != nil || !dst.Spec.DeletionProtection ||
+		dst.Status.DatabaseName != "database" {
+		t.Errorf("expected the fields of the v1beta1 annotation to be restored, got %+v", dst)
+	}
+	if dst.Annotations != nil {
+		t.Errorf("expected the v1beta1 annotation to be removed, got %v", dst.Annotations)
+	}
+}
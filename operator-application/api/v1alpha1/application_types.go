@@ -12,7 +12,9 @@ type ApplicationSpec struct {
 	AmountPods int32 `json:"amountPods"`
 	// +kubebuilder:default:="database"
 	DatabaseName string `json:"databaseName,omitempty"`
-	// +kubebuilder:default:="databaseNamespace"
+	// Note: The default was 'databaseNamespace' which is not a valid namespace name and is rejected by the webhook
+
+	// +kubebuilder:default:="database"
 	DatabaseNamespace string `json:"databaseNamespace,omitempty"`
 	// +kubebuilder:default:="https://raw.githubusercontent.com/IBM/multi-tenancy/main/installapp/postgres-config/create-populate-tenant-a.sql"
 	SchemaUrl string `json:"schemaUrl,omitempty"`
@@ -29,6 +31,7 @@ type ApplicationStatus struct {
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:deprecatedversion:warning="application.sample.ibm.com/v1alpha1 Application is deprecated and will be removed, use application.sample.ibm.com/v1 Application instead"
 
 type Application struct {
 	metav1.TypeMeta   `json:",inline"`
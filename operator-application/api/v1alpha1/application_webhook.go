@@ -0,0 +1,56 @@
+package v1alpha1
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const DeprecationWarning = "application.sample.ibm.com/v1alpha1 Application is deprecated and will be removed, use application.sample.ibm.com/v1 Application instead"
+const TitleWarning = "application.sample.ibm.com/v1alpha1 Application cannot define a title and the other fields added in later versions"
+
+const deprecationWebhookPath = "/warn-application-sample-ibm-com-v1alpha1-application"
+
+// Note: The metric is used to find the namespaces which still need to be migrated before v1alpha1 can be removed
+var v1alpha1Requests = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "application_v1alpha1_requests_total",
+		Help: "Number of create and update requests of v1alpha1 applications",
+	},
+	[]string{"namespace", "operation"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(v1alpha1Requests)
+}
+
+// Note: The API server converts v1alpha1 requests to v1 before the other webhooks are invoked,
+// so only this webhook with the 'Exact' match policy sees the version the client used
+//+kubebuilder:webhook:path=/warn-application-sample-ibm-com-v1alpha1-application,mutating=false,failurePolicy=ignore,matchPolicy=Exact,sideEffects=None,groups=application.sample.ibm.com,resources=applications,verbs=create;update,versions=v1alpha1,name=wapplication.kb.io,admissionReviewVersions=v1
+
+func SetupDeprecationWebhookWithManager(mgr ctrl.Manager) {
+	mgr.GetWebhookServer().Register(deprecationWebhookPath, &webhook.Admission{Handler: &deprecationWarner{}})
+}
+
+type deprecationWarner struct{}
+
+// Note: Requests are never rejected, the clients only get warnings
+func (warner *deprecationWarner) Handle(ctx context.Context, req admission.Request) admission.Response {
+	version := req.Kind.Version
+	if req.RequestKind != nil {
+		version = req.RequestKind.Version
+	}
+	if version != GroupVersion.Version {
+		return admission.Allowed("")
+	}
+
+	applicationlog.Info("v1alpha1 request", "name", req.Name, "namespace", req.Namespace, "operation", req.Operation)
+	v1alpha1Requests.WithLabelValues(req.Namespace, string(req.Operation)).Inc()
+	return admission.Allowed("").WithWarnings(DeprecationWarning, TitleWarning)
+}
+
+var _ admission.Handler = &deprecationWarner{}
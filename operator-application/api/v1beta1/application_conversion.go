@@ -1,3 +1,108 @@
 package v1beta1
 
-func (*Application) Hub() {}
+import (
+	v1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var applicationlog = logf.Log.WithName("application-resource")
+
+// Note: v1 contains the same fields as v1beta1 grouped in blocks, so no fields need to be stored in annotations
+
+// convert this application to the hub version (v1)
+func (src *Application) ConvertTo(dstRaw conversion.Hub) error {
+	applicationlog.Info("Calling ConvertTo")
+	dst := dstRaw.(*v1.Application)
+	src = src.DeepCopy()
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec.Version = src.Spec.Version
+	dst.Spec.Title = src.Spec.Title
+	dst.Spec.Workload = v1.ApplicationWorkload{
+		AmountPods:         src.Spec.AmountPods,
+		Resources:          src.Spec.Resources,
+		Probes:             convertProbesTo(src.Spec.Probes),
+		PodSecurityContext: src.Spec.PodSecurityContext,
+		SecurityContext:    src.Spec.SecurityContext,
+		ServiceAccount:     (*v1.ApplicationServiceAccount)(src.Spec.ServiceAccount),
+	}
+	dst.Spec.Database = v1.ApplicationDatabase{
+		Name:      src.Spec.DatabaseName,
+		Namespace: src.Spec.DatabaseNamespace,
+	}
+	dst.Spec.Schema = v1.ApplicationSchema{Url: src.Spec.SchemaUrl}
+	dst.Spec.Exposure = v1.ApplicationExposure{Network: convertNetworkTo(src.Spec.Network)}
+	dst.Spec.DeletionProtection = src.Spec.DeletionProtection
+
+	dst.Status = v1.ApplicationStatus(src.Status)
+	return nil
+}
+
+// convert from the hub version (v1) to this version
+func (dst *Application) ConvertFrom(srcRaw conversion.Hub) error {
+	applicationlog.Info("Calling ConvertFrom")
+	src := srcRaw.(*v1.Application).DeepCopy()
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec.Version = src.Spec.Version
+	dst.Spec.Title = src.Spec.Title
+	dst.Spec.AmountPods = src.Spec.Workload.AmountPods
+	dst.Spec.Resources = src.Spec.Workload.Resources
+	dst.Spec.Probes = convertProbesFrom(src.Spec.Workload.Probes)
+	dst.Spec.PodSecurityContext = src.Spec.Workload.PodSecurityContext
+	dst.Spec.SecurityContext = src.Spec.Workload.SecurityContext
+	dst.Spec.ServiceAccount = (*ApplicationServiceAccount)(src.Spec.Workload.ServiceAccount)
+	dst.Spec.DatabaseName = src.Spec.Database.Name
+	dst.Spec.DatabaseNamespace = src.Spec.Database.Namespace
+	dst.Spec.SchemaUrl = src.Spec.Schema.Url
+	dst.Spec.Network = convertNetworkFrom(src.Spec.Exposure.Network)
+	dst.Spec.DeletionProtection = src.Spec.DeletionProtection
+
+	dst.Status = ApplicationStatus(src.Status)
+	return nil
+}
+
+func convertProbesTo(probes *ApplicationProbes) *v1.ApplicationProbes {
+	if probes == nil {
+		return nil
+	}
+	return &v1.ApplicationProbes{
+		Readiness: (*v1.ApplicationProbe)(probes.Readiness),
+		Liveness:  (*v1.ApplicationProbe)(probes.Liveness),
+		Startup:   (*v1.ApplicationProbe)(probes.Startup),
+	}
+}
+
+func convertProbesFrom(probes *v1.ApplicationProbes) *ApplicationProbes {
+	if probes == nil {
+		return nil
+	}
+	return &ApplicationProbes{
+		Readiness: (*ApplicationProbe)(probes.Readiness),
+		Liveness:  (*ApplicationProbe)(probes.Liveness),
+		Startup:   (*ApplicationProbe)(probes.Startup),
+	}
+}
+
+func convertNetworkTo(network *ApplicationNetwork) *v1.ApplicationNetwork {
+	if network == nil {
+		return nil
+	}
+	output := &v1.ApplicationNetwork{RestrictEgress: network.RestrictEgress}
+	for _, peer := range network.Ingress {
+		output.Ingress = append(output.Ingress, v1.ApplicationNetworkPeer(peer))
+	}
+	return output
+}
+
+func convertNetworkFrom(network *v1.ApplicationNetwork) *ApplicationNetwork {
+	if network == nil {
+		return nil
+	}
+	output := &ApplicationNetwork{RestrictEgress: network.RestrictEgress}
+	for _, peer := range network.Ingress {
+		output.Ingress = append(output.Ingress, ApplicationNetworkPeer(peer))
+	}
+	return output
+}
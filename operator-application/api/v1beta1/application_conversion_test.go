@@ -0,0 +1,20 @@
+package v1beta1
+
+import (
+	"testing"
+
+	"github.com/nheidloff/operator-sample-go/operator-application/api/internal/fuzzing"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+func newSpoke() conversion.Convertible {
+	return &Application{}
+}
+
+func TestHubRoundTrip(t *testing.T) {
+	fuzzing.TestHubRoundTrip(t, "v1beta1", AddToScheme, newSpoke)
+}
+
+func TestSpokeRoundTrip(t *testing.T) {
+	fuzzing.TestSpokeRoundTrip(t, "v1beta1", AddToScheme, newSpoke)
+}
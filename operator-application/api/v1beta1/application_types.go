@@ -1,22 +1,136 @@
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type ApplicationSpec struct {
 	//+kubebuilder:default:="1.0.0"
 	Version string `json:"version,omitempty"`
+	// Amount of pods of the microservice. If not set, the mutating webhook applies the default
+	// from the namespace annotations or the operator configuration.
 	//+kubebuilder:validation:Minimum=0
-	//+kubebuilder:default:=1
-	AmountPods int32 `json:"amountPods"`
-	// +kubebuilder:default:="database"
+	// +optional
+	AmountPods *int32 `json:"amountPods,omitempty"`
+	// Name of the database. If not set, the mutating webhook applies the default from the
+	// namespace annotations or the operator configuration.
+	// +optional
 	DatabaseName string `json:"databaseName,omitempty"`
-	// +kubebuilder:default:="databaseNamespace"
+	// Namespace of the database. If not set, the mutating webhook applies the default from the
+	// namespace annotations or the operator configuration.
+	// +optional
 	DatabaseNamespace string `json:"databaseNamespace,omitempty"`
 	// +kubebuilder:default:="https://raw.githubusercontent.com/IBM/multi-tenancy/main/installapp/postgres-config/create-populate-tenant-a.sql"
 	SchemaUrl string `json:"schemaUrl,omitempty"`
 	Title     string `json:"title,omitempty"`
+	// Resources of the microservice container. If not set, defaults are taken from the
+	// namespace LimitRange or from the operator configuration.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Health probes of the microservice container. If not set, the Quarkus health endpoints are used.
+	// +optional
+	Probes *ApplicationProbes `json:"probes,omitempty"`
+	// Security context of the microservice pods. If not set, a context complying to the
+	// restricted Pod Security Standard is used.
+	// +optional
+	PodSecurityContext *corev1.PodSecurityContext `json:"podSecurityContext,omitempty"`
+	// Security context of the microservice container. If not set, a context complying to the
+	// restricted Pod Security Standard is used.
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+	// Service account which is created for the microservice pods
+	// +optional
+	ServiceAccount *ApplicationServiceAccount `json:"serviceAccount,omitempty"`
+	// Network isolation of the microservice pods. If set, NetworkPolicies are created which deny all
+	// other traffic.
+	// +optional
+	Network *ApplicationNetwork `json:"network,omitempty"`
+	// Deletion of the application is refused while its schema has been created or its database is
+	// used by other applications. Set to false to allow the deletion.
+	// +optional
+	DeletionProtection bool `json:"deletionProtection,omitempty"`
+}
+
+type ApplicationServiceAccount struct {
+	// Permissions in the namespace of the application which are granted to the service account via a Role and RoleBinding
+	// +optional
+	Permissions []rbacv1.PolicyRule `json:"permissions,omitempty"`
+	// Names of secrets in the namespace of the application which are used to pull the image
+	// +optional
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+	// +kubebuilder:default:=false
+	// +optional
+	AutomountServiceAccountToken *bool `json:"automountServiceAccountToken,omitempty"`
+}
+
+type ApplicationNetwork struct {
+	// Sources from which ingress traffic to the service port is allowed
+	// +optional
+	Ingress []ApplicationNetworkPeer `json:"ingress,omitempty"`
+	// Egress traffic is only allowed to the database namespace and to DNS if true. During relocations the namespace of
+	// the previous database is allowed as well.
+	// +kubebuilder:default:=true
+	// +optional
+	RestrictEgress *bool `json:"restrictEgress,omitempty"`
+}
+
+// Note: All defined fields of a peer need to match
+type ApplicationNetworkPeer struct {
+	// Name of the source namespace
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Labels of the source namespaces
+	// +optional
+	NamespaceLabels map[string]string `json:"namespaceLabels,omitempty"`
+	// Labels of the source pods. Without namespace, pods in the application namespace are selected.
+	// +optional
+	PodLabels map[string]string `json:"podLabels,omitempty"`
+}
+
+type ApplicationProbes struct {
+	// +optional
+	Readiness *ApplicationProbe `json:"readiness,omitempty"`
+	// +optional
+	Liveness *ApplicationProbe `json:"liveness,omitempty"`
+	// Note: The startup probe is only defined if set
+	// +optional
+	Startup *ApplicationProbe `json:"startup,omitempty"`
+}
+
+type ApplicationProbe struct {
+	// +kubebuilder:validation:Enum=HTTP;TCP;Exec
+	// +kubebuilder:default:="HTTP"
+	// +optional
+	Type string `json:"type,omitempty"`
+	// Path of the HTTP endpoint. Defaults to the Quarkus health endpoint of the probe.
+	// +optional
+	Path string `json:"path,omitempty"`
+	// Port of the HTTP or TCP endpoint. Defaults to the port of the microservice.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int32 `json:"port,omitempty"`
+	// Command of the Exec probe
+	// +optional
+	Command []string `json:"command,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	InitialDelaySeconds *int32 `json:"initialDelaySeconds,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	PeriodSeconds *int32 `json:"periodSeconds,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+	// Note: Kubernetes only accepts 1 for liveness and startup probes
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	SuccessThreshold *int32 `json:"successThreshold,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	FailureThreshold *int32 `json:"failureThreshold,omitempty"`
 }
 
 type ApplicationStatus struct {
@@ -26,11 +140,23 @@ type ApplicationStatus struct {
 	// +listMapKey=type
 	Conditions    []metav1.Condition `json:"conditions"`
 	SchemaCreated bool               `json:"schemaCreated"`
+	// Name of the database which is currently used by the microservice
+	// +optional
+	DatabaseName string `json:"databaseName,omitempty"`
+	// Namespace of the database which is currently used by the microservice
+	// +optional
+	DatabaseNamespace string `json:"databaseNamespace,omitempty"`
+	// Name of the database which has been used before a relocation. It is deleted after the microservice has been
+	// rolled out with the new database.
+	// +optional
+	PreviousDatabaseName string `json:"previousDatabaseName,omitempty"`
+	// Namespace of the database which has been used before a relocation
+	// +optional
+	PreviousDatabaseNamespace string `json:"previousDatabaseNamespace,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
-//+kubebuilder:storageversion
 
 type Application struct {
 	metav1.TypeMeta   `json:",inline"`
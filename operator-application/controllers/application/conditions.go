@@ -2,8 +2,9 @@ package applicationcontroller
 
 import (
 	"context"
+	"strings"
 
-	applicationsamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1beta1"
+	applicationsamplev1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
 	"github.com/nheidloff/operator-sample-go/operator-application/utilities"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -19,7 +20,7 @@ const CONDITION_REASON_RESOURCE_FOUND = "ResourceFound"
 const CONDITION_MESSAGE_RESOURCE_FOUND = "Resource found in k18n"
 
 func (reconciler *ApplicationReconciler) setConditionResourceFound(ctx context.Context,
-	application *applicationsamplev1beta1.Application) error {
+	application *applicationsamplev1.Application) error {
 
 	if !reconciler.containsCondition(ctx, application, CONDITION_REASON_RESOURCE_FOUND) {
 		return utilities.AppendCondition(ctx, reconciler.Client, application, CONDITION_TYPE_RESOURCE_FOUND, CONDITION_STATUS_TRUE,
@@ -34,7 +35,7 @@ const CONDITION_REASON_INSTALL_READY = "AllRequirementsMet"
 const CONDITION_MESSAGE_INSTALL_READY = "All requirements met, attempting install"
 
 func (reconciler *ApplicationReconciler) setConditionInstallReady(ctx context.Context,
-	application *applicationsamplev1beta1.Application) error {
+	application *applicationsamplev1.Application) error {
 
 	reconciler.deleteCondition(ctx, application, CONDITION_TYPE_FAILED, CONDITION_REASON_FAILED_INSTALL_READY)
 	if !reconciler.containsCondition(ctx, application, CONDITION_REASON_INSTALL_READY) {
@@ -50,7 +51,7 @@ const CONDITION_REASON_FAILED_INSTALL_READY = "RequirementsNotMet"
 const CONDITION_MESSAGE_FAILED_INSTALL_READY = "Not all requirements met"
 
 func (reconciler *ApplicationReconciler) setConditionFailed(ctx context.Context,
-	application *applicationsamplev1beta1.Application, reason string) error {
+	application *applicationsamplev1.Application, reason string) error {
 
 	var message string
 	switch reason {
@@ -71,7 +72,7 @@ const CONDITION_REASON_DATABASE_EXISTS = "DatabaseExists"
 const CONDITION_MESSAGE_DATABASE_EXISTS = "The database exists"
 
 func (reconciler *ApplicationReconciler) setConditionDatabaseExists(ctx context.Context,
-	application *applicationsamplev1beta1.Application, status metav1.ConditionStatus) error {
+	application *applicationsamplev1.Application, status metav1.ConditionStatus) error {
 
 	if !reconciler.containsCondition(ctx, application, CONDITION_REASON_DATABASE_EXISTS) {
 		return utilities.AppendCondition(ctx, reconciler.Client, application, CONDITION_TYPE_DATABASE_EXISTS, status,
@@ -87,13 +88,105 @@ func (reconciler *ApplicationReconciler) setConditionDatabaseExists(ctx context.
 	return nil
 }
 
+// Note: Status of BEST_EFFORT can be True or False
+// Note: True is a warning that pods might be evicted first and are rejected in namespaces with a ResourceQuota
+const CONDITION_TYPE_BEST_EFFORT = "BestEffort"
+const CONDITION_REASON_BEST_EFFORT = "BestEffortQoS"
+const CONDITION_MESSAGE_BEST_EFFORT = "Pods have the QoS class BestEffort if the condition is true since no resources are defined"
+
+func (reconciler *ApplicationReconciler) setConditionBestEffort(ctx context.Context,
+	application *applicationsamplev1.Application, status metav1.ConditionStatus) error {
+
+	if !reconciler.containsCondition(ctx, application, CONDITION_REASON_BEST_EFFORT) {
+		return utilities.AppendCondition(ctx, reconciler.Client, application, CONDITION_TYPE_BEST_EFFORT, status,
+			CONDITION_REASON_BEST_EFFORT, CONDITION_MESSAGE_BEST_EFFORT)
+	} else {
+		currentStatus := reconciler.getConditionStatus(ctx, application, CONDITION_TYPE_BEST_EFFORT)
+		if currentStatus != status {
+			reconciler.deleteCondition(ctx, application, CONDITION_TYPE_BEST_EFFORT, CONDITION_REASON_BEST_EFFORT)
+			return utilities.AppendCondition(ctx, reconciler.Client, application, CONDITION_TYPE_BEST_EFFORT, status,
+				CONDITION_REASON_BEST_EFFORT, CONDITION_MESSAGE_BEST_EFFORT)
+		}
+	}
+	return nil
+}
+
+// Note: Status of POD_SECURITY_RESTRICTED can be True or False
+const CONDITION_TYPE_POD_SECURITY_RESTRICTED = "PodSecurityRestricted"
+const CONDITION_REASON_POD_SECURITY_RESTRICTED = "PodSecurityRestricted"
+const CONDITION_MESSAGE_POD_SECURITY_RESTRICTED = "Pods comply to the restricted Pod Security Standard"
+const CONDITION_MESSAGE_POD_SECURITY_NOT_RESTRICTED = "Pods violate the restricted Pod Security Standard: "
+
+func (reconciler *ApplicationReconciler) setConditionPodSecurityRestricted(ctx context.Context,
+	application *applicationsamplev1.Application, violations []utilities.PodSecurityViolation) error {
+
+	var status metav1.ConditionStatus = CONDITION_STATUS_TRUE
+	message := CONDITION_MESSAGE_POD_SECURITY_RESTRICTED
+	if len(violations) > 0 {
+		status = CONDITION_STATUS_FALSE
+		messages := []string{}
+		for _, violation := range violations {
+			messages = append(messages, violation.Message)
+		}
+		message = CONDITION_MESSAGE_POD_SECURITY_NOT_RESTRICTED + strings.Join(messages, ", ")
+	}
+
+	if !reconciler.containsCondition(ctx, application, CONDITION_REASON_POD_SECURITY_RESTRICTED) {
+		return utilities.AppendCondition(ctx, reconciler.Client, application, CONDITION_TYPE_POD_SECURITY_RESTRICTED, status,
+			CONDITION_REASON_POD_SECURITY_RESTRICTED, message)
+	} else {
+		currentMessage := reconciler.getConditionMessage(ctx, application, CONDITION_TYPE_POD_SECURITY_RESTRICTED)
+		if currentMessage != message {
+			reconciler.deleteCondition(ctx, application, CONDITION_TYPE_POD_SECURITY_RESTRICTED, CONDITION_REASON_POD_SECURITY_RESTRICTED)
+			return utilities.AppendCondition(ctx, reconciler.Client, application, CONDITION_TYPE_POD_SECURITY_RESTRICTED, status,
+				CONDITION_REASON_POD_SECURITY_RESTRICTED, message)
+		}
+	}
+	return nil
+}
+
+// Note: Status of DATABASE_RELOCATED can be True or False, the reason describes the state of the relocation
+// Note: The condition only exists after the database of the application has been changed
+const CONDITION_TYPE_DATABASE_RELOCATED = "DatabaseRelocated"
+const CONDITION_REASON_DATABASE_RELOCATION_IN_PROGRESS = "RelocationInProgress"
+const CONDITION_MESSAGE_DATABASE_RELOCATION_IN_PROGRESS = "The application is being moved to the new database"
+const CONDITION_REASON_DATABASE_RELOCATION_FAILED = "DataCopyFailed"
+const CONDITION_MESSAGE_DATABASE_RELOCATION_FAILED = "The data could not be copied to the new database, the old database is still used"
+const CONDITION_REASON_DATABASE_RELOCATION_SUCCEEDED = "RelocationSucceeded"
+const CONDITION_MESSAGE_DATABASE_RELOCATION_SUCCEEDED = "The application uses the new database and the old database has been deleted"
+const CONDITION_REASON_DATABASE_RELOCATION_SUCCEEDED_DATABASE_KEPT = "RelocationSucceededDatabaseKept"
+const CONDITION_MESSAGE_DATABASE_RELOCATION_SUCCEEDED_DATABASE_KEPT = "The application uses the new database, the old database is kept since other applications use it"
+
+func (reconciler *ApplicationReconciler) setConditionDatabaseRelocated(ctx context.Context,
+	application *applicationsamplev1.Application, status metav1.ConditionStatus, reason string) error {
+
+	var message string
+	switch reason {
+	case CONDITION_REASON_DATABASE_RELOCATION_IN_PROGRESS:
+		message = CONDITION_MESSAGE_DATABASE_RELOCATION_IN_PROGRESS
+	case CONDITION_REASON_DATABASE_RELOCATION_FAILED:
+		message = CONDITION_MESSAGE_DATABASE_RELOCATION_FAILED
+	case CONDITION_REASON_DATABASE_RELOCATION_SUCCEEDED:
+		message = CONDITION_MESSAGE_DATABASE_RELOCATION_SUCCEEDED
+	case CONDITION_REASON_DATABASE_RELOCATION_SUCCEEDED_DATABASE_KEPT:
+		message = CONDITION_MESSAGE_DATABASE_RELOCATION_SUCCEEDED_DATABASE_KEPT
+	}
+
+	if !reconciler.containsCondition(ctx, application, reason) {
+		reconciler.deleteCondition(ctx, application, CONDITION_TYPE_DATABASE_RELOCATED, reason)
+		return utilities.AppendCondition(ctx, reconciler.Client, application, CONDITION_TYPE_DATABASE_RELOCATED, status,
+			reason, message)
+	}
+	return nil
+}
+
 // Note: Status of SUCCEEDED can only be True
 const CONDITION_TYPE_SUCCEEDED = "Succeeded"
 const CONDITION_REASON_SUCCEEDED = "InstallSucceeded"
 const CONDITION_MESSAGE_SUCCEEDED = "Application has been installed"
 
 func (reconciler *ApplicationReconciler) setConditionSucceeded(ctx context.Context,
-	application *applicationsamplev1beta1.Application) error {
+	application *applicationsamplev1.Application) error {
 
 	if !reconciler.containsCondition(ctx, application, CONDITION_REASON_SUCCEEDED) {
 		return utilities.AppendCondition(ctx, reconciler.Client, application, CONDITION_TYPE_SUCCEEDED, CONDITION_STATUS_TRUE,
@@ -108,7 +201,7 @@ const CONDITION_REASON_DELETION_REQUEST_RECEIVED = "DeletionRequestReceived"
 const CONDITION_MESSAGE_DELETION_REQUEST_RECEIVED = "Application is supposed to be deleted"
 
 func (reconciler *ApplicationReconciler) setConditionDeletionRequestReceived(ctx context.Context,
-	application *applicationsamplev1beta1.Application) error {
+	application *applicationsamplev1.Application) error {
 
 	if !reconciler.containsCondition(ctx, application, CONDITION_REASON_DELETION_REQUEST_RECEIVED) {
 		return utilities.AppendCondition(ctx, reconciler.Client, application, CONDITION_TYPE_DELETION_REQUEST_RECEIVED, CONDITION_STATUS_TRUE,
@@ -117,7 +210,7 @@ func (reconciler *ApplicationReconciler) setConditionDeletionRequestReceived(ctx
 	return nil
 }
 
-func (reconciler *ApplicationReconciler) getConditionStatus(ctx context.Context, application *applicationsamplev1beta1.Application,
+func (reconciler *ApplicationReconciler) getConditionStatus(ctx context.Context, application *applicationsamplev1.Application,
 	typeName string) metav1.ConditionStatus {
 
 	var output metav1.ConditionStatus = CONDITION_STATUS_UNKNOWN
@@ -129,7 +222,19 @@ func (reconciler *ApplicationReconciler) getConditionStatus(ctx context.Context,
 	return output
 }
 
-func (reconciler *ApplicationReconciler) deleteCondition(ctx context.Context, application *applicationsamplev1beta1.Application,
+func (reconciler *ApplicationReconciler) getConditionMessage(ctx context.Context, application *applicationsamplev1.Application,
+	typeName string) string {
+
+	var output string
+	for _, condition := range application.Status.Conditions {
+		if condition.Type == typeName {
+			output = condition.Message
+		}
+	}
+	return output
+}
+
+func (reconciler *ApplicationReconciler) deleteCondition(ctx context.Context, application *applicationsamplev1.Application,
 	typeName string, reason string) error {
 
 	log := log.FromContext(ctx)
@@ -150,7 +255,7 @@ func (reconciler *ApplicationReconciler) deleteCondition(ctx context.Context, ap
 
 // TODO: Move to uti
 func (reconciler *ApplicationReconciler) containsCondition(ctx context.Context,
-	application *applicationsamplev1beta1.Application, reason string) bool {
+	application *applicationsamplev1.Application, reason string) bool {
 
 	output := false
 	for _, condition := range application.Status.Conditions {
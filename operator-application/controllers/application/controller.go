@@ -6,7 +6,10 @@ import (
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -15,7 +18,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
-	applicationsamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1beta1"
+	applicationsamplev1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
 )
 
 type ApplicationReconciler struct {
@@ -29,13 +32,21 @@ type ApplicationReconciler struct {
 //+kubebuilder:rbac:groups=application.sample.ibm.com,resources=applications/finalizers,verbs=update
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps;endpoints,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=limitranges,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get
+//+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 func (reconciler *ApplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 	log.Info("Reconcile started")
 
-	application := &applicationsamplev1beta1.Application{}
+	application := &applicationsamplev1.Application{}
 	err := reconciler.Get(ctx, req.NamespacedName, application)
 	if err != nil {
 		if errors.IsNotFound(err) {
@@ -85,11 +96,26 @@ func (reconciler *ApplicationReconciler) Reconcile(ctx context.Context, req ctrl
 		return ctrl.Result{}, err
 	}
 
+	result, err := reconciler.reconcileDatabaseRelocation(ctx, application)
+	if err != nil || result.RequeueAfter > 0 {
+		return result, err
+	}
+
 	_, err = reconciler.reconcileSecret(ctx, application)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
+	_, err = reconciler.reconcileServiceAccount(ctx, application)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	_, err = reconciler.reconcileRole(ctx, application)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
 	_, err = reconciler.reconcileDeployment(ctx, application)
 	if err != nil {
 		return ctrl.Result{}, err
@@ -100,6 +126,16 @@ func (reconciler *ApplicationReconciler) Reconcile(ctx context.Context, req ctrl
 		return ctrl.Result{}, err
 	}
 
+	_, err = reconciler.reconcileNetworkPolicies(ctx, application)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	result, err = reconciler.reconcileDatabaseRelocationCompletion(ctx, application)
+	if err != nil || result.RequeueAfter > 0 {
+		return result, err
+	}
+
 	// Note: Commented out for dev productivity only
 	/*
 		_, err = reconciler.addFinalizer(ctx, application)
@@ -120,10 +156,15 @@ func (reconciler *ApplicationReconciler) SetupWithManager(mgr ctrl.Manager) erro
 	managerConfig = mgr.GetConfig()
 
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&applicationsamplev1beta1.Application{}).
+		For(&applicationsamplev1.Application{}).
 		Owns(&appsv1.Deployment{}).
 		Owns(&corev1.Service{}).
 		Owns(&corev1.Secret{}).
+		Owns(&corev1.ServiceAccount{}).
+		Owns(&rbacv1.Role{}).
+		Owns(&rbacv1.RoleBinding{}).
+		Owns(&networkingv1.NetworkPolicy{}).
+		Owns(&batchv1.Job{}).
 		// Note: Possible, but not used in this scenario
 		//Owns(&databasesamplev1alpha1.Database{}).
 		Complete(reconciler)
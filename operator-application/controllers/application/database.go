@@ -4,7 +4,7 @@ import (
 	"context"
 	"time"
 
-	applicationsamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1beta1"
+	applicationsamplev1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
 	databasesamplev1alpha1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1alpha1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -13,17 +13,16 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-func (reconciler *ApplicationReconciler) defineDatabase(application *applicationsamplev1beta1.Application) *databasesamplev1alpha1.Database {
+func (reconciler *ApplicationReconciler) defineDatabase(application *applicationsamplev1.Application) *databasesamplev1alpha1.Database {
 	database := &databasesamplev1alpha1.Database{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      application.Spec.DatabaseName,
-			Namespace: application.Spec.DatabaseNamespace,
+			Name:      application.Spec.Database.Name,
+			Namespace: application.Spec.Database.Namespace,
 		},
+		// Note: The database operator rejects plaintext passwords and generates a password instead
 		Spec: databasesamplev1alpha1.DatabaseSpec{
-			User:        databaseUser,
-			Password:    databasePassword,
-			Url:         databaseUrl,
-			Certificate: databaseCertificate,
+			User: databaseUser,
+			Url:  databaseUrl,
 		},
 	}
 
@@ -32,14 +31,14 @@ func (reconciler *ApplicationReconciler) defineDatabase(application *application
 	return database
 }
 
-func (reconciler *ApplicationReconciler) reconcileDatabase(ctx context.Context, application *applicationsamplev1beta1.Application) (ctrl.Result, error) {
+func (reconciler *ApplicationReconciler) reconcileDatabase(ctx context.Context, application *applicationsamplev1.Application) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 	database := &databasesamplev1alpha1.Database{}
 	databaseDefinition := reconciler.defineDatabase(application)
-	err := reconciler.Get(ctx, types.NamespacedName{Name: application.Spec.DatabaseName, Namespace: application.Spec.DatabaseNamespace}, database)
+	err := reconciler.Get(ctx, types.NamespacedName{Name: application.Spec.Database.Name, Namespace: application.Spec.Database.Namespace}, database)
 	if err != nil {
 		if errors.IsNotFound(err) {
-			log.Info("Database resource " + application.Spec.DatabaseName + " not found. Creating or re-creating database")
+			log.Info("Database resource " + application.Spec.Database.Name + " not found. Creating or re-creating database")
 			err = reconciler.setConditionDatabaseExists(ctx, application, CONDITION_STATUS_FALSE)
 			if err != nil {
 				return ctrl.Result{}, err
@@ -54,7 +53,7 @@ func (reconciler *ApplicationReconciler) reconcileDatabase(ctx context.Context,
 				return ctrl.Result{RequeueAfter: time.Second * 1}, nil
 			}
 		} else {
-			log.Info("Failed to get database resource " + application.Spec.DatabaseName + ". Re-running reconcile.")
+			log.Info("Failed to get database resource " + application.Spec.Database.Name + ". Re-running reconcile.")
 			return ctrl.Result{}, err
 		}
 	}
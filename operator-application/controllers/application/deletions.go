@@ -4,17 +4,19 @@ import (
 	"context"
 	"fmt"
 
-	applicationsamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1beta1"
+	applicationsamplev1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
 	databasesamplev1alpha1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1alpha1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-func (reconciler *ApplicationReconciler) finalizeApplication(ctx context.Context, application *applicationsamplev1beta1.Application) error {
+func (reconciler *ApplicationReconciler) finalizeApplication(ctx context.Context, application *applicationsamplev1.Application) error {
 	database := &databasesamplev1alpha1.Database{}
-	err := reconciler.Get(ctx, types.NamespacedName{Name: application.Spec.DatabaseName, Namespace: application.Spec.DatabaseNamespace}, database)
+	err := reconciler.Get(ctx, types.NamespacedName{Name: application.Spec.Database.Name, Namespace: application.Spec.Database.Namespace}, database)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			return nil
@@ -23,7 +25,7 @@ func (reconciler *ApplicationReconciler) finalizeApplication(ctx context.Context
 	return fmt.Errorf("Database not deleted yet")
 }
 
-func (reconciler *ApplicationReconciler) addFinalizer(ctx context.Context, application *applicationsamplev1beta1.Application) (ctrl.Result, error) {
+func (reconciler *ApplicationReconciler) addFinalizer(ctx context.Context, application *applicationsamplev1.Application) (ctrl.Result, error) {
 	if !controllerutil.ContainsFinalizer(application, finalizer) {
 		controllerutil.AddFinalizer(application, finalizer)
 		err := reconciler.Update(ctx, application)
@@ -34,7 +36,7 @@ func (reconciler *ApplicationReconciler) addFinalizer(ctx context.Context, appli
 	return ctrl.Result{}, nil
 }
 
-func (reconciler *ApplicationReconciler) tryDeletions(ctx context.Context, application *applicationsamplev1beta1.Application) (ctrl.Result, error) {
+func (reconciler *ApplicationReconciler) tryDeletions(ctx context.Context, application *applicationsamplev1.Application) (ctrl.Result, error) {
 	isApplicationMarkedToBeDeleted := application.GetDeletionTimestamp() != nil
 	if isApplicationMarkedToBeDeleted {
 		if controllerutil.ContainsFinalizer(application, finalizer) {
@@ -52,3 +54,22 @@ func (reconciler *ApplicationReconciler) tryDeletions(ctx context.Context, appli
 	}
 	return ctrl.Result{}, nil
 }
+
+func (reconciler *ApplicationReconciler) deleteIfExists(ctx context.Context, object client.Object, name string, namespace string) error {
+	log := log.FromContext(ctx)
+	err := reconciler.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, object)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		log.Info("Failed to get resource " + name + ". Re-running reconcile.")
+		return err
+	}
+	log.Info("Resource " + name + " not needed anymore. Deleting it")
+	err = reconciler.Delete(ctx, object)
+	if err != nil && !errors.IsNotFound(err) {
+		log.Info("Failed to delete resource " + name + ". Re-running reconcile.")
+		return err
+	}
+	return nil
+}
@@ -3,22 +3,29 @@ package applicationcontroller
 import (
 	"context"
 
-	applicationsamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1beta1"
+	applicationsamplev1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
 	"github.com/nheidloff/operator-sample-go/operator-application/utilities"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-func (reconciler *ApplicationReconciler) defineDeployment(application *applicationsamplev1beta1.Application) *appsv1.Deployment {
-	replicas := application.Spec.AmountPods
+func (reconciler *ApplicationReconciler) defineDeployment(application *applicationsamplev1.Application, resources corev1.ResourceRequirements) *appsv1.Deployment {
+	replicas := defaultAmountPods
+	if application.Spec.Workload.AmountPods != nil {
+		replicas = *application.Spec.Workload.AmountPods
+	}
 	labels := map[string]string{labelKey: labelValue}
+	podLabels := getPodLabels(application)
+	readinessProbe, livenessProbe, startupProbe := reconciler.defineProbes(application)
+	podSecurityContext, containerSecurityContext := reconciler.defineSecurityContexts(application)
+	automountServiceAccountToken := reconciler.defineServiceAccount(application).AutomountServiceAccountToken
 
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
@@ -32,9 +39,12 @@ func (reconciler *ApplicationReconciler) defineDeployment(application *applicati
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: labels,
+					Labels: podLabels,
 				},
 				Spec: corev1.PodSpec{
+					ServiceAccountName:           serviceAccountName,
+					AutomountServiceAccountToken: automountServiceAccountToken,
+					SecurityContext:              podSecurityContext,
 					Containers: []corev1.Container{{
 						Image: image,
 						Name:  containerName,
@@ -50,24 +60,18 @@ func (reconciler *ApplicationReconciler) defineDeployment(application *applicati
 									},
 									Key: secretGreetingMessageLabel,
 								},
-							}},
-						},
-						ReadinessProbe: &v1.Probe{
-							ProbeHandler: v1.ProbeHandler{
-								HTTPGet: &v1.HTTPGetAction{Path: "/q/health/live", Port: intstr.IntOrString{
-									IntVal: port,
-								}},
-							},
-							InitialDelaySeconds: 20,
-						},
-						LivenessProbe: &v1.Probe{
-							ProbeHandler: v1.ProbeHandler{
-								HTTPGet: &v1.HTTPGetAction{Path: "/q/health/ready", Port: intstr.IntOrString{
-									IntVal: port,
-								}},
-							},
-							InitialDelaySeconds: 40,
-						},
+							}}, {
+							Name:  databaseNameEnvName,
+							Value: application.Status.DatabaseName,
+						}, {
+							Name:  databaseNamespaceEnvName,
+							Value: application.Status.DatabaseNamespace,
+						}},
+						Resources:       resources,
+						ReadinessProbe:  readinessProbe,
+						LivenessProbe:   livenessProbe,
+						StartupProbe:    startupProbe,
+						SecurityContext: containerSecurityContext,
 					}},
 				},
 			},
@@ -81,11 +85,38 @@ func (reconciler *ApplicationReconciler) defineDeployment(application *applicati
 	return deployment
 }
 
-func (reconciler *ApplicationReconciler) reconcileDeployment(ctx context.Context, application *applicationsamplev1beta1.Application) (ctrl.Result, error) {
+// Note: The instance label tells the pods of applications in the same namespace apart, e.g. in the NetworkPolicies. It is
+// not part of the selector of the deployment, since selectors cannot be changed for existing deployments.
+func getPodLabels(application *applicationsamplev1.Application) map[string]string {
+	return map[string]string{labelKey: labelValue, instanceLabelKey: application.Name}
+}
+
+func (reconciler *ApplicationReconciler) reconcileDeployment(ctx context.Context, application *applicationsamplev1.Application) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 	deployment := &appsv1.Deployment{}
-	deploymentDefinition := reconciler.defineDeployment(application)
-	err := reconciler.Get(ctx, types.NamespacedName{Name: deploymentName, Namespace: application.Namespace}, deployment)
+	resources, err := reconciler.defineResources(ctx, application)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	deploymentDefinition := reconciler.defineDeployment(application, resources)
+
+	var bestEffortStatus metav1.ConditionStatus = CONDITION_STATUS_FALSE
+	if isBestEffort(&deploymentDefinition.Spec.Template.Spec) {
+		bestEffortStatus = CONDITION_STATUS_TRUE
+	}
+	err = reconciler.setConditionBestEffort(ctx, application, bestEffortStatus)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	violations := utilities.CheckPodSecurity(utilities.PodSecurityLevelRestricted,
+		deploymentDefinition.Spec.Template.Spec.SecurityContext, deploymentDefinition.Spec.Template.Spec.Containers[0].SecurityContext)
+	err = reconciler.setConditionPodSecurityRestricted(ctx, application, violations)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	err = reconciler.Get(ctx, types.NamespacedName{Name: deploymentName, Namespace: application.Namespace}, deployment)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			log.Info("Deployment resource " + deploymentName + " not found. Creating or re-creating deployment")
@@ -107,8 +138,55 @@ func (reconciler *ApplicationReconciler) reconcileDeployment(ctx context.Context
 		//if specHashActual != specHashTarget {
 		var current int32 = *deployment.Spec.Replicas
 		var expected int32 = *deploymentDefinition.Spec.Replicas
+		updateRequired := false
 		if current != expected {
 			deployment.Spec.Replicas = &expected
+			updateRequired = true
+		}
+		// Note: The labels of the pods are changed back as well, since the NetworkPolicies select the pods by them
+		if !equality.Semantic.DeepEqual(deployment.Spec.Template.Labels, deploymentDefinition.Spec.Template.Labels) {
+			deployment.Spec.Template.Labels = deploymentDefinition.Spec.Template.Labels
+			updateRequired = true
+		}
+		// Note: The controller also changes back environment variables, resources, probes, security contexts and service accounts, if changed manually in 'Deployment' or in 'Application'
+		podSpec := &deployment.Spec.Template.Spec
+		expectedPodSpec := &deploymentDefinition.Spec.Template.Spec
+		if podSpec.ServiceAccountName != expectedPodSpec.ServiceAccountName ||
+			!equality.Semantic.DeepEqual(podSpec.AutomountServiceAccountToken, expectedPodSpec.AutomountServiceAccountToken) {
+			podSpec.ServiceAccountName = expectedPodSpec.ServiceAccountName
+			podSpec.AutomountServiceAccountToken = expectedPodSpec.AutomountServiceAccountToken
+			updateRequired = true
+		}
+		if !equality.Semantic.DeepEqual(deployment.Spec.Template.Spec.SecurityContext, deploymentDefinition.Spec.Template.Spec.SecurityContext) {
+			deployment.Spec.Template.Spec.SecurityContext = deploymentDefinition.Spec.Template.Spec.SecurityContext
+			updateRequired = true
+		}
+		currentContainers := deployment.Spec.Template.Spec.Containers
+		expectedContainer := deploymentDefinition.Spec.Template.Spec.Containers[0]
+		if len(currentContainers) > 0 {
+			if !equality.Semantic.DeepEqual(currentContainers[0].Env, expectedContainer.Env) {
+				currentContainers[0].Env = expectedContainer.Env
+				updateRequired = true
+			}
+			expectedResources := defaultResourceRequests(expectedContainer.Resources)
+			if !equality.Semantic.DeepEqual(currentContainers[0].Resources, expectedResources) {
+				currentContainers[0].Resources = expectedResources
+				updateRequired = true
+			}
+			if !equality.Semantic.DeepEqual(currentContainers[0].ReadinessProbe, expectedContainer.ReadinessProbe) ||
+				!equality.Semantic.DeepEqual(currentContainers[0].LivenessProbe, expectedContainer.LivenessProbe) ||
+				!equality.Semantic.DeepEqual(currentContainers[0].StartupProbe, expectedContainer.StartupProbe) {
+				currentContainers[0].ReadinessProbe = expectedContainer.ReadinessProbe
+				currentContainers[0].LivenessProbe = expectedContainer.LivenessProbe
+				currentContainers[0].StartupProbe = expectedContainer.StartupProbe
+				updateRequired = true
+			}
+			if !equality.Semantic.DeepEqual(currentContainers[0].SecurityContext, expectedContainer.SecurityContext) {
+				currentContainers[0].SecurityContext = expectedContainer.SecurityContext
+				updateRequired = true
+			}
+		}
+		if updateRequired {
 			deployment.Labels = utilities.SetHashToLabels(deployment.Labels, specHashTarget)
 			err = reconciler.Update(ctx, deployment)
 			if err != nil {
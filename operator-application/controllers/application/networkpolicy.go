@@ -0,0 +1,184 @@
+package applicationcontroller
+
+import (
+	"context"
+
+	applicationsamplev1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Note: Without peers no ingress traffic is allowed at all
+// Note: The policies only select the pods of the application, see getPodLabels
+func (reconciler *ApplicationReconciler) defineIngressNetworkPolicy(application *applicationsamplev1.Application) *networkingv1.NetworkPolicy {
+	labels := map[string]string{labelKey: labelValue}
+	protocol := corev1.ProtocolTCP
+
+	peers := []networkingv1.NetworkPolicyPeer{}
+	for _, peerSpec := range application.Spec.Exposure.Network.Ingress {
+		peer := networkingv1.NetworkPolicyPeer{}
+		namespaceLabels := map[string]string{}
+		for key, value := range peerSpec.NamespaceLabels {
+			namespaceLabels[key] = value
+		}
+		if peerSpec.Namespace != "" {
+			namespaceLabels[namespaceNameLabelKey] = peerSpec.Namespace
+		}
+		if len(namespaceLabels) > 0 {
+			peer.NamespaceSelector = &metav1.LabelSelector{MatchLabels: namespaceLabels}
+		}
+		if len(peerSpec.PodLabels) > 0 {
+			peer.PodSelector = &metav1.LabelSelector{MatchLabels: peerSpec.PodLabels}
+		}
+		if peer.NamespaceSelector == nil && peer.PodSelector == nil {
+			continue
+		}
+		peers = append(peers, peer)
+	}
+
+	ingressRules := []networkingv1.NetworkPolicyIngressRule{}
+	if len(peers) > 0 {
+		ingressRules = append(ingressRules, networkingv1.NetworkPolicyIngressRule{
+			Ports: []networkingv1.NetworkPolicyPort{{
+				Protocol: &protocol,
+				Port:     &intstr.IntOrString{IntVal: port},
+			}},
+			From: peers,
+		})
+	}
+
+	networkPolicy := &networkingv1.NetworkPolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "NetworkPolicy"},
+		ObjectMeta: metav1.ObjectMeta{Name: ingressNetworkPolicyName, Namespace: application.Namespace, Labels: labels},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: getPodLabels(application)},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     ingressRules,
+		},
+	}
+
+	ctrl.SetControllerReference(application, networkPolicy, reconciler.Scheme)
+	return networkPolicy
+}
+
+// Note: Egress is allowed to the namespaces of all databases of the application, during relocations also to the
+// namespaces of the database in use and of the previous one until the relocation has been completed
+func (reconciler *ApplicationReconciler) defineEgressNetworkPolicy(application *applicationsamplev1.Application) *networkingv1.NetworkPolicy {
+	labels := map[string]string{labelKey: labelValue}
+	protocolUDP := corev1.ProtocolUDP
+	protocolTCP := corev1.ProtocolTCP
+
+	databasePeers := []networkingv1.NetworkPolicyPeer{}
+	for _, namespace := range getDatabaseNamespaces(application) {
+		databasePeers = append(databasePeers, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{namespaceNameLabelKey: namespace},
+			},
+		})
+	}
+
+	networkPolicy := &networkingv1.NetworkPolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "NetworkPolicy"},
+		ObjectMeta: metav1.ObjectMeta{Name: egressNetworkPolicyName, Namespace: application.Namespace, Labels: labels},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: getPodLabels(application)},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress: []networkingv1.NetworkPolicyEgressRule{{
+				To: databasePeers,
+			}, {
+				Ports: []networkingv1.NetworkPolicyPort{{
+					Protocol: &protocolUDP,
+					Port:     &intstr.IntOrString{IntVal: dnsPort},
+				}, {
+					Protocol: &protocolTCP,
+					Port:     &intstr.IntOrString{IntVal: dnsPort},
+				}},
+			}},
+		},
+	}
+
+	ctrl.SetControllerReference(application, networkPolicy, reconciler.Scheme)
+	return networkPolicy
+}
+
+func getDatabaseNamespaces(application *applicationsamplev1.Application) []string {
+	namespaces := []string{}
+	for _, namespace := range []string{application.Spec.Database.Namespace, application.Status.DatabaseNamespace,
+		application.Status.PreviousDatabaseNamespace} {
+		if namespace != "" && !containsString(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// Note: The network policies only exist if the network section is defined in the application
+func (reconciler *ApplicationReconciler) reconcileNetworkPolicies(ctx context.Context, application *applicationsamplev1.Application) (ctrl.Result, error) {
+	if application.Spec.Exposure.Network == nil {
+		err := reconciler.deleteIfExists(ctx, &networkingv1.NetworkPolicy{}, ingressNetworkPolicyName, application.Namespace)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		err = reconciler.deleteIfExists(ctx, &networkingv1.NetworkPolicy{}, egressNetworkPolicyName, application.Namespace)
+		return ctrl.Result{}, err
+	}
+
+	err := reconciler.reconcileNetworkPolicy(ctx, reconciler.defineIngressNetworkPolicy(application))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	restrictEgress := application.Spec.Exposure.Network.RestrictEgress == nil || *application.Spec.Exposure.Network.RestrictEgress
+	if restrictEgress {
+		err = reconciler.reconcileNetworkPolicy(ctx, reconciler.defineEgressNetworkPolicy(application))
+	} else {
+		err = reconciler.deleteIfExists(ctx, &networkingv1.NetworkPolicy{}, egressNetworkPolicyName, application.Namespace)
+	}
+	return ctrl.Result{}, err
+}
+
+func (reconciler *ApplicationReconciler) reconcileNetworkPolicy(ctx context.Context, networkPolicyDefinition *networkingv1.NetworkPolicy) error {
+	log := log.FromContext(ctx)
+	networkPolicy := &networkingv1.NetworkPolicy{}
+	name := networkPolicyDefinition.Name
+	err := reconciler.Get(ctx, types.NamespacedName{Name: name, Namespace: networkPolicyDefinition.Namespace}, networkPolicy)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("NetworkPolicy resource " + name + " not found. Creating or re-creating network policy")
+			err = reconciler.Create(ctx, networkPolicyDefinition)
+			if err != nil {
+				log.Info("Failed to create network policy resource. Re-running reconcile.")
+				return err
+			}
+		} else {
+			log.Info("Failed to get network policy resource " + name + ". Re-running reconcile.")
+			return err
+		}
+	} else {
+		if !equality.Semantic.DeepEqual(networkPolicy.Spec, networkPolicyDefinition.Spec) {
+			networkPolicy.Spec = networkPolicyDefinition.Spec
+			err = reconciler.Update(ctx, networkPolicy)
+			if err != nil {
+				log.Info("Failed to update network policy resource. Re-running reconcile.")
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package applicationcontroller
+
+import (
+	applicationsamplev1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func (reconciler *ApplicationReconciler) defineProbes(application *applicationsamplev1.Application) (readiness *corev1.Probe, liveness *corev1.Probe, startup *corev1.Probe) {
+	probes := application.Spec.Workload.Probes
+	if probes == nil {
+		probes = &applicationsamplev1.ApplicationProbes{}
+	}
+
+	readiness = defineProbe(probes.Readiness, readinessProbePath, readinessProbeInitialDelaySeconds)
+	liveness = defineProbe(probes.Liveness, livenessProbePath, livenessProbeInitialDelaySeconds)
+	if probes.Startup != nil {
+		startup = defineProbe(probes.Startup, startupProbePath, startupProbeInitialDelaySeconds)
+	}
+	return readiness, liveness, startup
+}
+
+// Note: All values are set explicitly, since Kubernetes defaults missing values which would be detected as drift otherwise
+func defineProbe(probeSpec *applicationsamplev1.ApplicationProbe, defaultPath string, defaultInitialDelaySeconds int32) *corev1.Probe {
+	if probeSpec == nil {
+		probeSpec = &applicationsamplev1.ApplicationProbe{}
+	}
+
+	probePort := port
+	if probeSpec.Port != 0 {
+		probePort = probeSpec.Port
+	}
+
+	probe := &corev1.Probe{
+		InitialDelaySeconds: valueOrDefault(probeSpec.InitialDelaySeconds, defaultInitialDelaySeconds),
+		PeriodSeconds:       valueOrDefault(probeSpec.PeriodSeconds, probePeriodSeconds),
+		TimeoutSeconds:      valueOrDefault(probeSpec.TimeoutSeconds, probeTimeoutSeconds),
+		SuccessThreshold:    valueOrDefault(probeSpec.SuccessThreshold, probeSuccessThreshold),
+		FailureThreshold:    valueOrDefault(probeSpec.FailureThreshold, probeFailureThreshold),
+	}
+
+	switch probeSpec.Type {
+	case probeTypeTCP:
+		probe.ProbeHandler = corev1.ProbeHandler{
+			TCPSocket: &corev1.TCPSocketAction{Port: intstr.IntOrString{
+				IntVal: probePort,
+			}},
+		}
+	case probeTypeExec:
+		probe.ProbeHandler = corev1.ProbeHandler{
+			Exec: &corev1.ExecAction{Command: probeSpec.Command},
+		}
+	default:
+		path := defaultPath
+		if probeSpec.Path != "" {
+			path = probeSpec.Path
+		}
+		probe.ProbeHandler = corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{Path: path, Scheme: corev1.URISchemeHTTP, Port: intstr.IntOrString{
+				IntVal: probePort,
+			}},
+		}
+	}
+	return probe
+}
+
+func valueOrDefault(value *int32, defaultValue int32) int32 {
+	if value != nil {
+		return *value
+	}
+	return defaultValue
+}
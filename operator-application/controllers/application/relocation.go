@@ -0,0 +1,333 @@
+package applicationcontroller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	applicationsamplev1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
+	"github.com/nheidloff/operator-sample-go/operator-application/utilities"
+	databasesamplev1alpha1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func (reconciler *ApplicationReconciler) defineRelocationJob(application *applicationsamplev1.Application,
+	sourceDatabase *databasesamplev1alpha1.Database, targetDatabase *databasesamplev1alpha1.Database) *batchv1.Job {
+
+	backoffLimit := relocationJobBackoffLimit
+	podSecurityContext := utilities.DefaultPodSecurityContext()
+	podSecurityContext.RunAsUser = &relocationJobUser
+
+	job := &batchv1.Job{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+		ObjectMeta: metav1.ObjectMeta{Name: relocationJobName, Namespace: application.Namespace},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy:   corev1.RestartPolicyNever,
+					SecurityContext: podSecurityContext,
+					Containers: []corev1.Container{{
+						Name:    "copy-database-data",
+						Image:   relocationJobImage,
+						Command: []string{"sh", "-c", relocationJobScript},
+						Env: []corev1.EnvVar{
+							{Name: "SOURCE_DATABASE_URL", Value: sourceDatabase.Spec.Url},
+							{Name: "SOURCE_DATABASE_USER", Value: sourceDatabase.Spec.User},
+							relocationSecretEnvVar(relocationSourcePasswordKey),
+							{Name: "TARGET_DATABASE_URL", Value: targetDatabase.Spec.Url},
+							{Name: "TARGET_DATABASE_USER", Value: targetDatabase.Spec.User},
+							relocationSecretEnvVar(relocationTargetPasswordKey),
+						},
+						SecurityContext: utilities.DefaultContainerSecurityContext(),
+					}},
+				},
+			},
+		},
+	}
+
+	ctrl.SetControllerReference(application, job, reconciler.Scheme)
+	return job
+}
+
+func relocationSecretEnvVar(key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: key,
+		ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: relocationSecretName},
+			Key:                  key,
+		}},
+	}
+}
+
+// Note: Pods can only reference secrets in their own namespace, so the passwords of the connection secrets of both
+// databases are copied into a secret in the namespace of the application
+func (reconciler *ApplicationReconciler) defineRelocationSecret(application *applicationsamplev1.Application,
+	sourcePassword []byte, targetPassword []byte) *corev1.Secret {
+
+	secret := &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: relocationSecretName, Namespace: application.Namespace},
+		Data: map[string][]byte{
+			relocationSourcePasswordKey: sourcePassword,
+			relocationTargetPasswordKey: targetPassword,
+		},
+		Type: corev1.SecretTypeOpaque,
+	}
+
+	ctrl.SetControllerReference(application, secret, reconciler.Scheme)
+	return secret
+}
+
+func (reconciler *ApplicationReconciler) readDatabasePassword(ctx context.Context, database *databasesamplev1alpha1.Database) ([]byte, error) {
+	log := log.FromContext(ctx)
+	name := database.Name + databaseConnectionSecretSuffix
+	secret := &corev1.Secret{}
+	err := reconciler.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, secret)
+	if err != nil {
+		log.Info("Failed to get connection secret " + name + " of database " + database.Name + ". Re-running reconcile.")
+		return nil, err
+	}
+	password, ok := secret.Data[databaseConnectionSecretPasswordKey]
+	if !ok {
+		return nil, fmt.Errorf("connection secret %s of database %s doesn't contain a password", name, database.Name)
+	}
+	return password, nil
+}
+
+// Note: The status contains the database which is currently used, the spec the database which is supposed to be used
+// Note: The new database has already been created by reconcileDatabase when this function is invoked
+func (reconciler *ApplicationReconciler) reconcileDatabaseRelocation(ctx context.Context, application *applicationsamplev1.Application) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	if application.Status.DatabaseName == "" || application.Status.DatabaseNamespace == "" {
+		application.Status.DatabaseName = application.Spec.Database.Name
+		application.Status.DatabaseNamespace = application.Spec.Database.Namespace
+		err := reconciler.Client.Status().Update(ctx, application)
+		if err != nil {
+			log.Info("Application resource status update failed.")
+		}
+		return ctrl.Result{}, err
+	}
+	if application.Status.DatabaseName == application.Spec.Database.Name &&
+		application.Status.DatabaseNamespace == application.Spec.Database.Namespace {
+		return ctrl.Result{}, nil
+	}
+
+	log.Info("Relocating database " + application.Status.DatabaseNamespace + "/" + application.Status.DatabaseName +
+		" to " + application.Spec.Database.Namespace + "/" + application.Spec.Database.Name)
+	err := reconciler.setConditionDatabaseRelocated(ctx, application, CONDITION_STATUS_FALSE, CONDITION_REASON_DATABASE_RELOCATION_IN_PROGRESS)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if application.Annotations[applicationsamplev1.CopyDatabaseDataAnnotation] == "true" {
+		result, err := reconciler.reconcileRelocationJob(ctx, application)
+		if err != nil || result.RequeueAfter > 0 {
+			return result, err
+		}
+	}
+
+	// Note: The deployment is switched to the new database via the status. The old database is deleted after the
+	// deployment has been rolled out, see reconcileDatabaseRelocationCompletion.
+	application.Status.PreviousDatabaseName = application.Status.DatabaseName
+	application.Status.PreviousDatabaseNamespace = application.Status.DatabaseNamespace
+	application.Status.DatabaseName = application.Spec.Database.Name
+	application.Status.DatabaseNamespace = application.Spec.Database.Namespace
+	err = reconciler.Client.Status().Update(ctx, application)
+	if err != nil {
+		log.Info("Application resource status update failed.")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// Note: The old database is only deleted after all pods use the new database and if no other application uses it
+func (reconciler *ApplicationReconciler) reconcileDatabaseRelocationCompletion(ctx context.Context, application *applicationsamplev1.Application) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	if application.Status.PreviousDatabaseName == "" || application.Status.PreviousDatabaseNamespace == "" {
+		return ctrl.Result{}, nil
+	}
+
+	deployment := &appsv1.Deployment{}
+	err := reconciler.Get(ctx, types.NamespacedName{Name: deploymentName, Namespace: application.Namespace}, deployment)
+	if err != nil {
+		log.Info("Failed to get deployment resource " + deploymentName + ". Re-running reconcile.")
+		return ctrl.Result{}, err
+	}
+	if !isRolledOut(deployment) {
+		log.Info("Deployment resource " + deploymentName + " has not been rolled out with the new database yet")
+		return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+	}
+
+	consumers, err := reconciler.getOtherDatabaseConsumers(ctx, application, application.Status.PreviousDatabaseName,
+		application.Status.PreviousDatabaseNamespace)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	reason := CONDITION_REASON_DATABASE_RELOCATION_SUCCEEDED
+	if len(consumers) > 0 {
+		log.Info("Database " + application.Status.PreviousDatabaseName + " is still used by " + strings.Join(consumers, ", ") + ". Keeping it")
+		reason = CONDITION_REASON_DATABASE_RELOCATION_SUCCEEDED_DATABASE_KEPT
+	} else {
+		err = reconciler.deleteIfExists(ctx, &databasesamplev1alpha1.Database{}, application.Status.PreviousDatabaseName,
+			application.Status.PreviousDatabaseNamespace)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	err = reconciler.deleteRelocationJob(ctx, application)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	err = reconciler.deleteIfExists(ctx, &corev1.Secret{}, relocationSecretName, application.Namespace)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	application.Status.PreviousDatabaseName = ""
+	application.Status.PreviousDatabaseNamespace = ""
+	err = reconciler.Client.Status().Update(ctx, application)
+	if err != nil {
+		log.Info("Application resource status update failed.")
+		return ctrl.Result{}, err
+	}
+	err = reconciler.setConditionDatabaseRelocated(ctx, application, CONDITION_STATUS_TRUE, reason)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Note: The annotations are removed so that the next change of the database requires a new explicit relocation
+	delete(application.Annotations, applicationsamplev1.RelocateDatabaseAnnotation)
+	delete(application.Annotations, applicationsamplev1.CopyDatabaseDataAnnotation)
+	err = reconciler.Update(ctx, application)
+	if err != nil {
+		log.Info("Failed to remove relocation annotations. Re-running reconcile.")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// Note: A deployment is rolled out when the controller has seen the latest spec and all pods are updated and available
+func isRolledOut(deployment *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	return deployment.Status.ObservedGeneration >= deployment.Generation &&
+		deployment.Status.UpdatedReplicas == replicas &&
+		deployment.Status.Replicas == replicas &&
+		deployment.Status.AvailableReplicas == replicas
+}
+
+// Note: Applications which use the database, are about to use it or still have to delete it are consumers
+func (reconciler *ApplicationReconciler) getOtherDatabaseConsumers(ctx context.Context, application *applicationsamplev1.Application,
+	name string, namespace string) ([]string, error) {
+
+	log := log.FromContext(ctx)
+	applications := &applicationsamplev1.ApplicationList{}
+	err := reconciler.List(ctx, applications)
+	if err != nil {
+		log.Info("Failed to list application resources. Re-running reconcile.")
+		return nil, err
+	}
+	consumers := []string{}
+	for _, other := range applications.Items {
+		if other.Namespace == application.Namespace && other.Name == application.Name {
+			continue
+		}
+		if (other.Spec.Database.Name == name && other.Spec.Database.Namespace == namespace) ||
+			(other.Status.DatabaseName == name && other.Status.DatabaseNamespace == namespace) ||
+			(other.Status.PreviousDatabaseName == name && other.Status.PreviousDatabaseNamespace == namespace) {
+			consumers = append(consumers, other.Namespace+"/"+other.Name)
+		}
+	}
+	return consumers, nil
+}
+
+func (reconciler *ApplicationReconciler) reconcileRelocationJob(ctx context.Context, application *applicationsamplev1.Application) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	job := &batchv1.Job{}
+	err := reconciler.Get(ctx, types.NamespacedName{Name: relocationJobName, Namespace: application.Namespace}, job)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			sourceDatabase := &databasesamplev1alpha1.Database{}
+			err = reconciler.Get(ctx, types.NamespacedName{Name: application.Status.DatabaseName, Namespace: application.Status.DatabaseNamespace}, sourceDatabase)
+			if err != nil {
+				log.Info("Failed to get database resource " + application.Status.DatabaseName + ". Re-running reconcile.")
+				return ctrl.Result{}, err
+			}
+			targetDatabase := &databasesamplev1alpha1.Database{}
+			err = reconciler.Get(ctx, types.NamespacedName{Name: application.Spec.Database.Name, Namespace: application.Spec.Database.Namespace}, targetDatabase)
+			if err != nil {
+				log.Info("Failed to get database resource " + application.Spec.Database.Name + ". Re-running reconcile.")
+				return ctrl.Result{}, err
+			}
+
+			sourcePassword, err := reconciler.readDatabasePassword(ctx, sourceDatabase)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			targetPassword, err := reconciler.readDatabasePassword(ctx, targetDatabase)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			err = reconciler.deleteIfExists(ctx, &corev1.Secret{}, relocationSecretName, application.Namespace)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			err = reconciler.Create(ctx, reconciler.defineRelocationSecret(application, sourcePassword, targetPassword))
+			if err != nil {
+				log.Info("Failed to create secret resource " + relocationSecretName + ". Re-running reconcile.")
+				return ctrl.Result{}, err
+			}
+
+			log.Info("Job resource " + relocationJobName + " not found. Creating job to copy database data")
+			err = reconciler.Create(ctx, reconciler.defineRelocationJob(application, sourceDatabase, targetDatabase))
+			if err != nil {
+				log.Info("Failed to create job resource. Re-running reconcile.")
+				return ctrl.Result{}, err
+			}
+			// Note: Delay the next loop run since copying data can take time
+			return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+		}
+		log.Info("Failed to get job resource " + relocationJobName + ". Re-running reconcile.")
+		return ctrl.Result{}, err
+	}
+
+	if job.Status.Succeeded > 0 {
+		return ctrl.Result{}, nil
+	}
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			// Note: The old database is kept. Deleting the job triggers another attempt
+			log.Info("Job resource " + relocationJobName + " failed. Database data has not been copied")
+			err = reconciler.setConditionDatabaseRelocated(ctx, application, CONDITION_STATUS_FALSE, CONDITION_REASON_DATABASE_RELOCATION_FAILED)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: time.Second * 60}, nil
+		}
+	}
+	return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+}
+
+// Note: Jobs are deleted in the background so that their pods are deleted too
+func (reconciler *ApplicationReconciler) deleteRelocationJob(ctx context.Context, application *applicationsamplev1.Application) error {
+	log := log.FromContext(ctx)
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: relocationJobName, Namespace: application.Namespace}}
+	err := reconciler.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !errors.IsNotFound(err) {
+		log.Info("Failed to delete job resource " + relocationJobName + ". Re-running reconcile.")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package applicationcontroller
+
+import (
+	"context"
+
+	applicationsamplev1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
+	"github.com/nheidloff/operator-sample-go/operator-application/utilities"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Note: Resources defined in the application take precedence over the namespace LimitRange which takes precedence over the operator configuration
+func (reconciler *ApplicationReconciler) defineResources(ctx context.Context, application *applicationsamplev1.Application) (corev1.ResourceRequirements, error) {
+	log := log.FromContext(ctx)
+	if application.Spec.Workload.Resources != nil {
+		return *application.Spec.Workload.Resources.DeepCopy(), nil
+	}
+
+	limitRanges := &corev1.LimitRangeList{}
+	err := reconciler.List(ctx, limitRanges, client.InNamespace(application.Namespace))
+	if err != nil {
+		log.Info("Failed to list limit range resources. Re-running reconcile.")
+		return corev1.ResourceRequirements{}, err
+	}
+	for _, limitRange := range limitRanges.Items {
+		for _, limit := range limitRange.Spec.Limits {
+			if limit.Type != corev1.LimitTypeContainer {
+				continue
+			}
+			if len(limit.DefaultRequest) > 0 || len(limit.Default) > 0 {
+				log.Info("Using default resources from limit range " + limitRange.Name)
+				return corev1.ResourceRequirements{
+					Requests: limit.DefaultRequest.DeepCopy(),
+					Limits:   limit.Default.DeepCopy(),
+				}, nil
+			}
+		}
+	}
+
+	configuredResources, err := utilities.GetConfiguredDefaultResources()
+	if err != nil {
+		return corev1.ResourceRequirements{}, err
+	}
+	if configuredResources != nil {
+		return *configuredResources, nil
+	}
+	return corev1.ResourceRequirements{}, nil
+}
+
+// Note: A pod is BestEffort if none of its containers define requests or limits
+func isBestEffort(podSpec *corev1.PodSpec) bool {
+	for _, container := range podSpec.Containers {
+		if len(container.Resources.Requests) > 0 || len(container.Resources.Limits) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Note: The API server sets missing requests to the limits. The operator applies the same defaulting before it compares
+// the resources of the deployment, otherwise the deployment would be updated in every reconciliation.
+func defaultResourceRequests(resources corev1.ResourceRequirements) corev1.ResourceRequirements {
+	defaulted := *resources.DeepCopy()
+	for name, limit := range defaulted.Limits {
+		if _, ok := defaulted.Requests[name]; ok {
+			continue
+		}
+		if defaulted.Requests == nil {
+			defaulted.Requests = corev1.ResourceList{}
+		}
+		defaulted.Requests[name] = limit.DeepCopy()
+	}
+	return defaulted
+}
@@ -0,0 +1,127 @@
+package applicationcontroller
+
+import (
+	"context"
+
+	applicationsamplev1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func (reconciler *ApplicationReconciler) defineRole(application *applicationsamplev1.Application) *rbacv1.Role {
+	labels := map[string]string{labelKey: labelValue}
+
+	role := &rbacv1.Role{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "Role"},
+		ObjectMeta: metav1.ObjectMeta{Name: roleName, Namespace: application.Namespace, Labels: labels},
+		Rules:      application.Spec.Workload.ServiceAccount.Permissions,
+	}
+
+	ctrl.SetControllerReference(application, role, reconciler.Scheme)
+	return role
+}
+
+func (reconciler *ApplicationReconciler) defineRoleBinding(application *applicationsamplev1.Application) *rbacv1.RoleBinding {
+	labels := map[string]string{labelKey: labelValue}
+
+	roleBinding := &rbacv1.RoleBinding{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "RoleBinding"},
+		ObjectMeta: metav1.ObjectMeta{Name: roleBindingName, Namespace: application.Namespace, Labels: labels},
+		Subjects: []rbacv1.Subject{{
+			Kind:      rbacv1.ServiceAccountKind,
+			Name:      serviceAccountName,
+			Namespace: application.Namespace,
+		}},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     roleName,
+		},
+	}
+
+	ctrl.SetControllerReference(application, roleBinding, reconciler.Scheme)
+	return roleBinding
+}
+
+// Note: The role and role binding only exist if permissions are declared in the application
+func (reconciler *ApplicationReconciler) reconcileRole(ctx context.Context, application *applicationsamplev1.Application) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	// Note: The webhook rejects these permissions. If webhooks are disabled, the role is not created either.
+	permissionsValid := true
+	if application.Spec.Workload.ServiceAccount != nil {
+		problems := applicationsamplev1.ValidatePermissions(field.NewPath("spec", "workload", "serviceAccount", "permissions"),
+			application.Spec.Workload.ServiceAccount.Permissions)
+		if len(problems) > 0 {
+			log.Info("Permissions of application " + application.Name + " are not granted: " + problems.ToAggregate().Error())
+			permissionsValid = false
+		}
+	}
+	if !permissionsValid || application.Spec.Workload.ServiceAccount == nil || len(application.Spec.Workload.ServiceAccount.Permissions) == 0 {
+		err := reconciler.deleteIfExists(ctx, &rbacv1.RoleBinding{}, roleBindingName, application.Namespace)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		err = reconciler.deleteIfExists(ctx, &rbacv1.Role{}, roleName, application.Namespace)
+		return ctrl.Result{}, err
+	}
+
+	role := &rbacv1.Role{}
+	roleDefinition := reconciler.defineRole(application)
+	err := reconciler.Get(ctx, types.NamespacedName{Name: roleName, Namespace: application.Namespace}, role)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("Role resource " + roleName + " not found. Creating or re-creating role")
+			err = reconciler.Create(ctx, roleDefinition)
+			if err != nil {
+				log.Info("Failed to create role resource. Re-running reconcile.")
+				return ctrl.Result{}, err
+			}
+		} else {
+			log.Info("Failed to get role resource " + roleName + ". Re-running reconcile.")
+			return ctrl.Result{}, err
+		}
+	} else {
+		if !equality.Semantic.DeepEqual(role.Rules, roleDefinition.Rules) {
+			role.Rules = roleDefinition.Rules
+			err = reconciler.Update(ctx, role)
+			if err != nil {
+				log.Info("Failed to update role resource. Re-running reconcile.")
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	roleBinding := &rbacv1.RoleBinding{}
+	roleBindingDefinition := reconciler.defineRoleBinding(application)
+	err = reconciler.Get(ctx, types.NamespacedName{Name: roleBindingName, Namespace: application.Namespace}, roleBinding)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("RoleBinding resource " + roleBindingName + " not found. Creating or re-creating role binding")
+			err = reconciler.Create(ctx, roleBindingDefinition)
+			if err != nil {
+				log.Info("Failed to create role binding resource. Re-running reconcile.")
+				return ctrl.Result{}, err
+			}
+		} else {
+			log.Info("Failed to get role binding resource " + roleBindingName + ". Re-running reconcile.")
+			return ctrl.Result{}, err
+		}
+	} else {
+		// Note: The role reference of role bindings is immutable, only the subjects are updated
+		if !equality.Semantic.DeepEqual(roleBinding.Subjects, roleBindingDefinition.Subjects) {
+			roleBinding.Subjects = roleBindingDefinition.Subjects
+			err = reconciler.Update(ctx, roleBinding)
+			if err != nil {
+				log.Info("Failed to update role binding resource. Re-running reconcile.")
+				return ctrl.Result{}, err
+			}
+		}
+	}
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,20 @@
+package applicationcontroller
+
+import (
+	applicationsamplev1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
+	"github.com/nheidloff/operator-sample-go/operator-application/utilities"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Note: Security contexts defined in the application replace the defaults which comply to the restricted Pod Security Standard
+func (reconciler *ApplicationReconciler) defineSecurityContexts(application *applicationsamplev1.Application) (*corev1.PodSecurityContext, *corev1.SecurityContext) {
+	podSecurityContext := utilities.DefaultPodSecurityContext()
+	if application.Spec.Workload.PodSecurityContext != nil {
+		podSecurityContext = application.Spec.Workload.PodSecurityContext.DeepCopy()
+	}
+	containerSecurityContext := utilities.DefaultContainerSecurityContext()
+	if application.Spec.Workload.SecurityContext != nil {
+		containerSecurityContext = application.Spec.Workload.SecurityContext.DeepCopy()
+	}
+	return podSecurityContext, containerSecurityContext
+}
@@ -0,0 +1,70 @@
+package applicationcontroller
+
+import (
+	"context"
+
+	applicationsamplev1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func (reconciler *ApplicationReconciler) defineServiceAccount(application *applicationsamplev1.Application) *corev1.ServiceAccount {
+	labels := map[string]string{labelKey: labelValue}
+	automountServiceAccountToken := false
+	imagePullSecrets := []corev1.LocalObjectReference{}
+	if application.Spec.Workload.ServiceAccount != nil {
+		if application.Spec.Workload.ServiceAccount.AutomountServiceAccountToken != nil {
+			automountServiceAccountToken = *application.Spec.Workload.ServiceAccount.AutomountServiceAccountToken
+		}
+		for _, imagePullSecret := range application.Spec.Workload.ServiceAccount.ImagePullSecrets {
+			imagePullSecrets = append(imagePullSecrets, corev1.LocalObjectReference{Name: imagePullSecret})
+		}
+	}
+
+	serviceAccount := &corev1.ServiceAccount{
+		TypeMeta:                     metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+		ObjectMeta:                   metav1.ObjectMeta{Name: serviceAccountName, Namespace: application.Namespace, Labels: labels},
+		ImagePullSecrets:             imagePullSecrets,
+		AutomountServiceAccountToken: &automountServiceAccountToken,
+	}
+
+	ctrl.SetControllerReference(application, serviceAccount, reconciler.Scheme)
+	return serviceAccount
+}
+
+func (reconciler *ApplicationReconciler) reconcileServiceAccount(ctx context.Context, application *applicationsamplev1.Application) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	serviceAccount := &corev1.ServiceAccount{}
+	serviceAccountDefinition := reconciler.defineServiceAccount(application)
+	err := reconciler.Get(ctx, types.NamespacedName{Name: serviceAccountName, Namespace: application.Namespace}, serviceAccount)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("ServiceAccount resource " + serviceAccountName + " not found. Creating or re-creating service account")
+			err = reconciler.Create(ctx, serviceAccountDefinition)
+			if err != nil {
+				log.Info("Failed to create service account resource. Re-running reconcile.")
+				return ctrl.Result{}, err
+			}
+		} else {
+			log.Info("Failed to get service account resource " + serviceAccountName + ". Re-running reconcile.")
+			return ctrl.Result{}, err
+		}
+	} else {
+		if !equality.Semantic.DeepEqual(serviceAccount.ImagePullSecrets, serviceAccountDefinition.ImagePullSecrets) ||
+			!equality.Semantic.DeepEqual(serviceAccount.AutomountServiceAccountToken, serviceAccountDefinition.AutomountServiceAccountToken) {
+			serviceAccount.ImagePullSecrets = serviceAccountDefinition.ImagePullSecrets
+			serviceAccount.AutomountServiceAccountToken = serviceAccountDefinition.AutomountServiceAccountToken
+			err = reconciler.Update(ctx, serviceAccount)
+			if err != nil {
+				log.Info("Failed to update service account resource. Re-running reconcile.")
+				return ctrl.Result{}, err
+			}
+		}
+	}
+	return ctrl.Result{}, nil
+}
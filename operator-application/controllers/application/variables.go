@@ -3,7 +3,7 @@ package applicationcontroller
 import (
 	"fmt"
 
-	applicationsamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1beta1"
+	applicationsamplev1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
 	"k8s.io/client-go/rest"
 )
 
@@ -15,35 +15,93 @@ var secretName string
 var deploymentName string
 var serviceName string
 var containerName string
+var serviceAccountName string
+var roleName string
+var roleBindingName string
+var ingressNetworkPolicyName string
+var egressNetworkPolicyName string
+var relocationJobName string
+var relocationSecretName string
 
 const image = "docker.io/nheidloff/simple-microservice:latest"
 const port int32 = 8081
 const nodePort int32 = 30548
 const labelKey = "app"
 const labelValue = "myapplication"
+const instanceLabelKey = "app.kubernetes.io/instance"
 const greetingMessage = "World"
 const secretGreetingMessageLabel = "GREETING_MESSAGE"
+const databaseNameEnvName = "DATABASE_NAME"
+const databaseNamespaceEnvName = "DATABASE_NAMESPACE"
+const namespaceNameLabelKey = "kubernetes.io/metadata.name"
+const dnsPort int32 = 53
+
+// Note: The amount of pods is defaulted by the webhook, this value is only used if the webhook is not running
+const defaultAmountPods int32 = 1
+
+// Note: Defaults of the probes use the Quarkus health endpoints
+const probeTypeHTTP = "HTTP"
+const probeTypeTCP = "TCP"
+const probeTypeExec = "Exec"
+const readinessProbePath = "/q/health/ready"
+const livenessProbePath = "/q/health/live"
+const startupProbePath = "/q/health/started"
+const readinessProbeInitialDelaySeconds int32 = 20
+const livenessProbeInitialDelaySeconds int32 = 40
+const startupProbeInitialDelaySeconds int32 = 0
+const probePeriodSeconds int32 = 10
+const probeTimeoutSeconds int32 = 1
+const probeSuccessThreshold int32 = 1
+const probeFailureThreshold int32 = 3
+
+// Note: The relocation job copies the data with the PostgreSQL client tools
+const relocationJobImage = "docker.io/library/postgres:14"
+const relocationJobScript = `PGPASSWORD="$SOURCE_DATABASE_PASSWORD" pg_dump --username="$SOURCE_DATABASE_USER" "$SOURCE_DATABASE_URL" | ` +
+	`PGPASSWORD="$TARGET_DATABASE_PASSWORD" psql --username="$TARGET_DATABASE_USER" "$TARGET_DATABASE_URL"`
+const relocationJobBackoffLimit int32 = 3
+
+var relocationJobUser int64 = 999
+
+// Note: The job reads the passwords from a secret, they are copied from the connection secrets of the databases which
+// the database operator writes as '<database>-connection'
+const relocationSourcePasswordKey = "SOURCE_DATABASE_PASSWORD"
+const relocationTargetPasswordKey = "TARGET_DATABASE_PASSWORD"
+const databaseConnectionSecretSuffix = "-connection"
+const databaseConnectionSecretPasswordKey = "password"
 
 // Note: For simplication purposes database properties are hardcoded
 const databaseUser string = "name"
-const databasePassword string = "password"
-const databaseUrl string = "url"
-const databaseCertificate string = "certificate"
+const databaseUrl string = "postgresql://database.database.svc:5432/database"
 
-func (reconciler *ApplicationReconciler) setGlobalVariables(application *applicationsamplev1beta1.Application) {
+func (reconciler *ApplicationReconciler) setGlobalVariables(application *applicationsamplev1.Application) {
 	secretName = application.Name + "-secret-greeting"
 	deploymentName = application.Name + "-deployment-microservice"
 	serviceName = application.Name + "-service-microservice"
 	containerName = application.Name + "-microservice"
+	serviceAccountName = application.Name + "-service-account-microservice"
+	roleName = application.Name + "-role-microservice"
+	roleBindingName = application.Name + "-rolebinding-microservice"
+	ingressNetworkPolicyName = application.Name + "-networkpolicy-ingress-microservice"
+	egressNetworkPolicyName = application.Name + "-networkpolicy-egress-microservice"
+	relocationJobName = application.Name + "-job-relocate-database"
+	relocationSecretName = application.Name + "-secret-relocate-database"
 	// TODO: Handle application.Spec.Version
 }
 
-func (reconciler *ApplicationReconciler) printVariables(application *applicationsamplev1beta1.Application) {
+func (reconciler *ApplicationReconciler) printVariables(application *applicationsamplev1.Application) {
 	fmt.Println("Custom Resource Values:")
 	fmt.Printf("- Name: %s\n", application.Name)
 	fmt.Printf("- Namespace: %s\n", application.Namespace)
 	fmt.Printf("- Version: %s\n", application.Spec.Version)
-	fmt.Printf("- AmountPods: %d\n", application.Spec.AmountPods)
-	fmt.Printf("- DatabaseName: %s\n", application.Spec.DatabaseName)
-	fmt.Printf("- DatabaseNamespace: %s\n", application.Spec.DatabaseNamespace)
+	if application.Spec.Workload.AmountPods != nil {
+		fmt.Printf("- AmountPods: %d\n", *application.Spec.Workload.AmountPods)
+	}
+	fmt.Printf("- DatabaseName: %s\n", application.Spec.Database.Name)
+	fmt.Printf("- DatabaseNamespace: %s\n", application.Spec.Database.Namespace)
+	if application.Status.DatabaseName != "" {
+		fmt.Printf("- Database in use: %s/%s\n", application.Status.DatabaseNamespace, application.Status.DatabaseName)
+	}
+	if application.Spec.Workload.Resources != nil {
+		fmt.Printf("- Resources: %v\n", *application.Spec.Workload.Resources)
+	}
 }
@@ -0,0 +1,191 @@
+package certificatescontroller
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func getServiceDNSNames(serviceName string, namespace string) []string {
+	return []string{
+		serviceName,
+		serviceName + "." + namespace,
+		serviceName + "." + namespace + ".svc",
+		serviceName + "." + namespace + ".svc.cluster.local",
+	}
+}
+
+func newCertificateAuthority(commonName string, now time.Time) ([]byte, []byte, error) {
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	return newCertificate(template, nil, nil)
+}
+
+// Note: Serving certificates never outlive the certificate authority which signed them
+func newServingCertificate(caCertPEM []byte, caKeyPEM []byte, dnsNames []string, now time.Time) ([]byte, []byte, error) {
+	ca, err := parseCertificate(caCertPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	caKey, err := parsePrivateKey(caKeyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	notAfter := now.Add(servingCertValidity)
+	if notAfter.After(ca.NotAfter) {
+		notAfter = ca.NotAfter
+	}
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: dnsNames[len(dnsNames)-2]},
+		DNSNames:    dnsNames,
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	return newCertificate(template, ca, caKey)
+}
+
+// Note: Self-signed certificates are created if no parent is passed
+func newCertificate(template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	template.SerialNumber = serialNumber
+	if parent == nil {
+		parent = template
+		parentKey = key
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+func parseCertificate(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no PEM encoded certificate found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func parsePrivateKey(keyPEM []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil || block.Type != "EC PRIVATE KEY" {
+		return nil, fmt.Errorf("no PEM encoded private key found")
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+func needsRotation(certificate *x509.Certificate, now time.Time) bool {
+	return now.Add(rotationThreshold).After(certificate.NotAfter)
+}
+
+func coversDNSNames(certificate *x509.Certificate, dnsNames []string) bool {
+	for _, dnsName := range dnsNames {
+		if certificate.VerifyHostname(dnsName) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Note: The certificate authority is rotated together with the serving certificate. The previous certificate
+// authority is kept in the CA bundle until it expires, so that replicas with old serving certificates keep working.
+// Returns true if the secret has been changed.
+func rotateCertificates(secret *corev1.Secret, caCommonName string, dnsNames []string, now time.Time) (bool, error) {
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	changed := false
+
+	ca, err := parseCertificate(secret.Data[caCertKey])
+	_, keyErr := parsePrivateKey(secret.Data[caKeyKey])
+	if err != nil || keyErr != nil || needsRotation(ca, now) {
+		if err == nil {
+			secret.Data[previousCACertKey] = secret.Data[caCertKey]
+		}
+		caCertPEM, caKeyPEM, err := newCertificateAuthority(caCommonName, now)
+		if err != nil {
+			return false, err
+		}
+		secret.Data[caCertKey] = caCertPEM
+		secret.Data[caKeyKey] = caKeyPEM
+		ca, err = parseCertificate(caCertPEM)
+		if err != nil {
+			return false, err
+		}
+		changed = true
+	}
+
+	previousCA, err := parseCertificate(secret.Data[previousCACertKey])
+	if _, exists := secret.Data[previousCACertKey]; exists && (err != nil || now.After(previousCA.NotAfter)) {
+		delete(secret.Data, previousCACertKey)
+		changed = true
+	}
+
+	servingCert, err := parseCertificate(secret.Data[corev1.TLSCertKey])
+	_, keyErr = parsePrivateKey(secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil || keyErr != nil || needsRotation(servingCert, now) ||
+		servingCert.CheckSignatureFrom(ca) != nil || !coversDNSNames(servingCert, dnsNames) {
+		certPEM, keyPEM, err := newServingCertificate(secret.Data[caCertKey], secret.Data[caKeyKey], dnsNames, now)
+		if err != nil {
+			return false, err
+		}
+		secret.Data[corev1.TLSCertKey] = certPEM
+		secret.Data[corev1.TLSPrivateKeyKey] = keyPEM
+		changed = true
+	}
+	return changed, nil
+}
+
+func getCABundle(secret *corev1.Secret) []byte {
+	caBundle := append([]byte{}, secret.Data[caCertKey]...)
+	return append(caBundle, secret.Data[previousCACertKey]...)
+}
+
+// Note: The next rotation is scheduled when the serving certificate or the certificate authority gets close to expiry
+func getNextRotation(secret *corev1.Secret, now time.Time) time.Duration {
+	next := time.Duration(0)
+	for _, key := range []string{caCertKey, corev1.TLSCertKey} {
+		certificate, err := parseCertificate(secret.Data[key])
+		if err != nil {
+			return 0
+		}
+		untilRotation := certificate.NotAfter.Add(-rotationThreshold).Sub(now)
+		if next == 0 || untilRotation < next {
+			next = untilRotation
+		}
+	}
+	if next < time.Minute {
+		next = time.Minute
+	}
+	return next
+}
@@ -0,0 +1,71 @@
+package certificatescontroller
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// Note: Replicas which aren't the leader only write the files of certificates which the leader has rotated
+func TestCertificateFilesAreWrittenAfterInjection(t *testing.T) {
+	ctx := context.Background()
+	names := NewNames("operator-", "tests.sample.third.party")
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: names.Secret, Namespace: "operator"}}
+	if _, err := rotateCertificates(secret, names.CertificateAuthority, getServiceDNSNames(names.Service, "operator"), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	configuration := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: names.ValidatingWebhookConfiguration},
+		Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: "vtest.kb.io"}},
+	}
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := apiextensionsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret, configuration).Build()
+	reconciler := &CertificateReconciler{Client: client, APIReader: client, Scheme: scheme, Namespace: "operator",
+		CertDir: t.TempDir(), Names: names}
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Name: names.Secret, Namespace: "operator"}}
+	certFile := filepath.Join(reconciler.CertDir, corev1.TLSCertKey)
+
+	result, err := reconciler.reconcileCertificateFiles(ctx, request)
+	if err != nil || result.RequeueAfter == 0 {
+		t.Fatalf("expected reconcile to wait for the injection, result: %v, error: %v", result, err)
+	}
+	if _, err := os.Stat(certFile); !os.IsNotExist(err) {
+		t.Fatalf("expected no certificate file before the injection, got %v", err)
+	}
+
+	configuration.Webhooks[0].ClientConfig.CABundle = getCABundle(secret)
+	if err := client.Update(ctx, configuration); err != nil {
+		t.Fatal(err)
+	}
+	result, err = reconciler.reconcileCertificateFiles(ctx, request)
+	if err != nil || result.RequeueAfter != 0 {
+		t.Fatalf("expected certificate files to be written, result: %v, error: %v", result, err)
+	}
+	written, err := os.ReadFile(certFile)
+	if err != nil || !bytes.Equal(written, secret.Data[corev1.TLSCertKey]) {
+		t.Fatalf("expected serving certificate in %s, error: %v", certFile, err)
+	}
+
+	if (certificateFilesController{}).NeedLeaderElection() {
+		t.Fatalf("expected certificate files to be written without leader election")
+	}
+}
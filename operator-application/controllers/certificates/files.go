@@ -0,0 +1,36 @@
+package certificatescontroller
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Note: The webhook server watches the files and reloads the certificate when they change
+func (reconciler *CertificateReconciler) writeCertificateFiles(ctx context.Context, secret *corev1.Secret) error {
+	log := log.FromContext(ctx)
+	err := os.MkdirAll(reconciler.CertDir, 0700)
+	if err != nil {
+		log.Info("Failed to create certificate directory " + reconciler.CertDir)
+		return err
+	}
+	// Note: The key is written first so that the webhook server only finds a matching pair once the certificate changes
+	for _, key := range []string{corev1.TLSPrivateKeyKey, corev1.TLSCertKey} {
+		path := filepath.Join(reconciler.CertDir, key)
+		existing, err := os.ReadFile(path)
+		if err == nil && bytes.Equal(existing, secret.Data[key]) {
+			continue
+		}
+		log.Info("Writing certificate file " + path)
+		err = os.WriteFile(path, secret.Data[key], 0600)
+		if err != nil {
+			log.Info("Failed to write certificate file " + path)
+			return err
+		}
+	}
+	return nil
+}
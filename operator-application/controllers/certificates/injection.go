@@ -0,0 +1,159 @@
+package certificatescontroller
+
+import (
+	"bytes"
+	"context"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func (reconciler *CertificateReconciler) injectCABundle(ctx context.Context, caBundle []byte) error {
+	err := reconciler.injectIntoMutatingWebhooks(ctx, caBundle)
+	if err != nil {
+		return err
+	}
+	err = reconciler.injectIntoValidatingWebhooks(ctx, caBundle)
+	if err != nil {
+		return err
+	}
+	return reconciler.injectIntoConversionWebhook(ctx, caBundle)
+}
+
+func (reconciler *CertificateReconciler) injectIntoMutatingWebhooks(ctx context.Context, caBundle []byte) error {
+	log := log.FromContext(ctx)
+	configuration := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	err := reconciler.APIReader.Get(ctx, types.NamespacedName{Name: reconciler.Names.MutatingWebhookConfiguration}, configuration)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("MutatingWebhookConfiguration resource " + reconciler.Names.MutatingWebhookConfiguration + " not found. Ignoring since webhooks might not be deployed")
+			return nil
+		}
+		log.Info("Failed to get MutatingWebhookConfiguration resource. Re-running reconcile.")
+		return err
+	}
+
+	patch := client.MergeFrom(configuration.DeepCopy())
+	changed := false
+	for i := range configuration.Webhooks {
+		if !bytes.Equal(configuration.Webhooks[i].ClientConfig.CABundle, caBundle) {
+			configuration.Webhooks[i].ClientConfig.CABundle = caBundle
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	log.Info("Injecting CA bundle into MutatingWebhookConfiguration resource " + reconciler.Names.MutatingWebhookConfiguration)
+	err = reconciler.Patch(ctx, configuration, patch)
+	if err != nil {
+		log.Info("Failed to inject CA bundle into MutatingWebhookConfiguration resource. Re-running reconcile.")
+	}
+	return err
+}
+
+func (reconciler *CertificateReconciler) injectIntoValidatingWebhooks(ctx context.Context, caBundle []byte) error {
+	log := log.FromContext(ctx)
+	configuration := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	err := reconciler.APIReader.Get(ctx, types.NamespacedName{Name: reconciler.Names.ValidatingWebhookConfiguration}, configuration)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("ValidatingWebhookConfiguration resource " + reconciler.Names.ValidatingWebhookConfiguration + " not found. Ignoring since webhooks might not be deployed")
+			return nil
+		}
+		log.Info("Failed to get ValidatingWebhookConfiguration resource. Re-running reconcile.")
+		return err
+	}
+
+	patch := client.MergeFrom(configuration.DeepCopy())
+	changed := false
+	for i := range configuration.Webhooks {
+		if !bytes.Equal(configuration.Webhooks[i].ClientConfig.CABundle, caBundle) {
+			configuration.Webhooks[i].ClientConfig.CABundle = caBundle
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	log.Info("Injecting CA bundle into ValidatingWebhookConfiguration resource " + reconciler.Names.ValidatingWebhookConfiguration)
+	err = reconciler.Patch(ctx, configuration, patch)
+	if err != nil {
+		log.Info("Failed to inject CA bundle into ValidatingWebhookConfiguration resource. Re-running reconcile.")
+	}
+	return err
+}
+
+// Note: The CRD only has a CA bundle if the conversion webhook is enabled in crd/kustomization.yaml
+func (reconciler *CertificateReconciler) injectIntoConversionWebhook(ctx context.Context, caBundle []byte) error {
+	log := log.FromContext(ctx)
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	err := reconciler.APIReader.Get(ctx, types.NamespacedName{Name: reconciler.Names.CustomResourceDefinition}, crd)
+	if err != nil {
+		log.Info("Failed to get CustomResourceDefinition resource. Re-running reconcile.")
+		return err
+	}
+
+	conversion := crd.Spec.Conversion
+	if conversion == nil || conversion.Strategy != apiextensionsv1.WebhookConverter ||
+		conversion.Webhook == nil || conversion.Webhook.ClientConfig == nil {
+		return nil
+	}
+	if bytes.Equal(conversion.Webhook.ClientConfig.CABundle, caBundle) {
+		return nil
+	}
+	patch := client.MergeFrom(crd.DeepCopy())
+	conversion.Webhook.ClientConfig.CABundle = caBundle
+	log.Info("Injecting CA bundle into conversion webhook of CustomResourceDefinition resource " + reconciler.Names.CustomResourceDefinition)
+	err = reconciler.Patch(ctx, crd, patch)
+	if err != nil {
+		log.Info("Failed to inject CA bundle into CustomResourceDefinition resource. Re-running reconcile.")
+	}
+	return err
+}
+
+// Note: Configurations which don't exist or have no CA bundle, e.g. CRDs without conversion webhook, are ignored
+func (reconciler *CertificateReconciler) isCABundleInjected(ctx context.Context, caBundle []byte) (bool, error) {
+	log := log.FromContext(ctx)
+	caBundles := [][]byte{}
+	mutatingConfiguration := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	err := reconciler.APIReader.Get(ctx, types.NamespacedName{Name: reconciler.Names.MutatingWebhookConfiguration}, mutatingConfiguration)
+	if err != nil && !errors.IsNotFound(err) {
+		log.Info("Failed to get MutatingWebhookConfiguration resource. Re-running reconcile.")
+		return false, err
+	}
+	for _, webhook := range mutatingConfiguration.Webhooks {
+		caBundles = append(caBundles, webhook.ClientConfig.CABundle)
+	}
+	validatingConfiguration := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	err = reconciler.APIReader.Get(ctx, types.NamespacedName{Name: reconciler.Names.ValidatingWebhookConfiguration}, validatingConfiguration)
+	if err != nil && !errors.IsNotFound(err) {
+		log.Info("Failed to get ValidatingWebhookConfiguration resource. Re-running reconcile.")
+		return false, err
+	}
+	for _, webhook := range validatingConfiguration.Webhooks {
+		caBundles = append(caBundles, webhook.ClientConfig.CABundle)
+	}
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	err = reconciler.APIReader.Get(ctx, types.NamespacedName{Name: reconciler.Names.CustomResourceDefinition}, crd)
+	if err != nil && !errors.IsNotFound(err) {
+		log.Info("Failed to get CustomResourceDefinition resource. Re-running reconcile.")
+		return false, err
+	}
+	conversion := crd.Spec.Conversion
+	if conversion != nil && conversion.Strategy == apiextensionsv1.WebhookConverter &&
+		conversion.Webhook != nil && conversion.Webhook.ClientConfig != nil {
+		caBundles = append(caBundles, conversion.Webhook.ClientConfig.CABundle)
+	}
+
+	for _, injected := range caBundles {
+		if !bytes.Equal(injected, caBundle) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,80 @@
+package migrationcontroller
+
+import (
+	"context"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// Note: The reconciler watches the CRD of applications. Since the CRD is reconciled when the manager starts,
+// the migration runs on startup if MigrateOnStartup is true, and on demand if the CRD is annotated.
+type StorageVersionMigrationReconciler struct {
+	client.Client
+	APIReader        client.Reader
+	Scheme           *runtime.Scheme
+	MigrateOnStartup bool
+}
+
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=application.sample.ibm.com,resources=applications,verbs=get;list;watch;update
+func (reconciler *StorageVersionMigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	err := reconciler.Get(ctx, req.NamespacedName, crd)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("CustomResourceDefinition resource not found. Ignoring since object must be deleted.")
+			return ctrl.Result{}, nil
+		}
+		log.Info("Failed to get CustomResourceDefinition resource. Re-running reconcile.")
+		return ctrl.Result{}, err
+	}
+
+	storageVersion := getStorageVersion(crd)
+	storedVersionsGauge.Set(float64(len(crd.Status.StoredVersions)))
+	requested := crd.Annotations[MigrateAnnotation] == "true"
+	if isMigrated(crd, storageVersion) {
+		if requested {
+			log.Info("All applications are stored in version " + storageVersion + ". Nothing to migrate")
+			return ctrl.Result{}, reconciler.removeAnnotation(ctx, crd)
+		}
+		return ctrl.Result{}, nil
+	}
+	if !requested && !reconciler.MigrateOnStartup {
+		log.Info("Applications are stored in old versions, but the migration has not been requested", "storedVersions", crd.Status.StoredVersions)
+		return ctrl.Result{}, nil
+	}
+
+	err = reconciler.migrateApplications(ctx, storageVersion)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	err = reconciler.trimStoredVersions(ctx, crd, storageVersion)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if requested {
+		return ctrl.Result{}, reconciler.removeAnnotation(ctx, crd)
+	}
+	return ctrl.Result{}, nil
+}
+
+func (reconciler *StorageVersionMigrationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	isApplicationsCRD := predicate.NewPredicateFuncs(func(object client.Object) bool {
+		return object.GetName() == applicationsCRDName
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("storageversionmigration").
+		For(&apiextensionsv1.CustomResourceDefinition{}, builder.WithPredicates(isApplicationsCRD)).
+		Complete(reconciler)
+}
@@ -0,0 +1,112 @@
+package migrationcontroller
+
+import (
+	"context"
+	"fmt"
+
+	applicationsamplev1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func getStorageVersion(crd *apiextensionsv1.CustomResourceDefinition) string {
+	for _, version := range crd.Spec.Versions {
+		if version.Storage {
+			return version.Name
+		}
+	}
+	return ""
+}
+
+func isMigrated(crd *apiextensionsv1.CustomResourceDefinition, storageVersion string) bool {
+	storedVersions := crd.Status.StoredVersions
+	return len(storedVersions) == 1 && storedVersions[0] == storageVersion
+}
+
+// Note: Updates without changes make the API server write the objects in the current storage version
+// Note: The applications are read via the API reader page by page to avoid caching all of them
+func (reconciler *StorageVersionMigrationReconciler) migrateApplications(ctx context.Context, storageVersion string) error {
+	log := log.FromContext(ctx)
+	log.Info("Migrating applications to storage version " + storageVersion)
+
+	migrated := 0
+	failed := 0
+	continueToken := ""
+	for {
+		applications := &applicationsamplev1.ApplicationList{}
+		err := reconciler.APIReader.List(ctx, applications, client.Limit(migrationPageSize), client.Continue(continueToken))
+		if err != nil {
+			log.Info("Failed to list application resources. Re-running reconcile.")
+			return err
+		}
+		for i := range applications.Items {
+			application := &applications.Items[i]
+			err = reconciler.migrateApplication(ctx, application)
+			if err != nil && !errors.IsNotFound(err) {
+				log.Info("Failed to migrate application "+application.Namespace+"/"+application.Name, "error", err.Error())
+				migratedApplicationsCounter.WithLabelValues(migrationResultFailed).Inc()
+				failed++
+				continue
+			}
+			migratedApplicationsCounter.WithLabelValues(migrationResultMigrated).Inc()
+			migrated++
+			if migrated%migrationLogInterval == 0 {
+				log.Info(fmt.Sprintf("Migrated %d applications", migrated))
+			}
+		}
+		continueToken = applications.Continue
+		if continueToken == "" {
+			break
+		}
+	}
+
+	log.Info(fmt.Sprintf("Migration finished. Migrated: %d, failed: %d", migrated, failed))
+	if failed > 0 {
+		return fmt.Errorf("%d applications could not be migrated to storage version %s", failed, storageVersion)
+	}
+	return nil
+}
+
+// Note: Conflicts don't mean that the application has been written in the storage version, e.g. if only its status
+// has changed in between. The application is read again and the update is retried.
+func (reconciler *StorageVersionMigrationReconciler) migrateApplication(ctx context.Context, application *applicationsamplev1.Application) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		err := reconciler.Update(ctx, application)
+		if errors.IsConflict(err) {
+			if getErr := reconciler.APIReader.Get(ctx, client.ObjectKeyFromObject(application), application); getErr != nil {
+				return getErr
+			}
+		}
+		return err
+	})
+}
+
+// Note: Only after all objects have been rewritten, old versions can be removed from the stored versions
+func (reconciler *StorageVersionMigrationReconciler) trimStoredVersions(ctx context.Context,
+	crd *apiextensionsv1.CustomResourceDefinition, storageVersion string) error {
+
+	log := log.FromContext(ctx)
+	log.Info("Trimming stored versions", "storedVersions", crd.Status.StoredVersions, "storageVersion", storageVersion)
+	crd.Status.StoredVersions = []string{storageVersion}
+	err := reconciler.Status().Update(ctx, crd)
+	if err != nil {
+		log.Info("Failed to update stored versions of CustomResourceDefinition resource. Re-running reconcile.")
+		return err
+	}
+	storedVersionsGauge.Set(1)
+	return nil
+}
+
+func (reconciler *StorageVersionMigrationReconciler) removeAnnotation(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition) error {
+	log := log.FromContext(ctx)
+	patch := client.MergeFrom(crd.DeepCopy())
+	delete(crd.Annotations, MigrateAnnotation)
+	err := reconciler.Patch(ctx, crd, patch)
+	if err != nil {
+		log.Info("Failed to remove annotation from CustomResourceDefinition resource. Re-running reconcile.")
+	}
+	return err
+}
@@ -0,0 +1,36 @@
+package migrationcontroller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const applicationsCRDName = "applications.application.sample.ibm.com"
+
+// Note: Annotating the CRD with this annotation triggers a migration on demand
+const MigrateAnnotation = "application.sample.ibm.com/migrate-storage-version"
+
+const migrationPageSize int64 = 100
+const migrationLogInterval = 50
+
+const migrationResultMigrated = "migrated"
+const migrationResultFailed = "failed"
+
+var migratedApplicationsCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "application_storage_migration_applications_total",
+		Help: "Number of applications which have been rewritten in the storage version",
+	},
+	[]string{"result"},
+)
+
+var storedVersionsGauge = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "application_storage_migration_stored_versions",
+		Help: "Number of versions in which applications are stored in etcd, old versions can be removed if it is 1",
+	},
+)
+
+func init() {
+	metrics.Registry.MustRegister(migratedApplicationsCounter, storedVersionsGauge)
+}
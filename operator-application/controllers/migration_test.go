@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	applicationsamplev1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
+	migrationcontroller "github.com/nheidloff/operator-sample-go/operator-application/controllers/migration"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const testApplicationsCRDName = "applications.application.sample.ibm.com"
+
+// Note: The first update of an application is preceded by a change of the application, so that the update of the
+// migration conflicts and needs to be retried
+type conflictingTestClient struct {
+	client.Client
+	updates   map[string]int
+	conflicts map[string]bool
+}
+
+func (c *conflictingTestClient) Update(ctx context.Context, object client.Object, opts ...client.UpdateOption) error {
+	application, ok := object.(*applicationsamplev1.Application)
+	if !ok {
+		return c.Client.Update(ctx, object, opts...)
+	}
+	c.updates[application.Name]++
+	if !c.conflicts[application.Name] {
+		c.conflicts[application.Name] = true
+		changed := &applicationsamplev1.Application{}
+		Expect(c.Client.Get(ctx, client.ObjectKeyFromObject(application), changed)).To(Succeed())
+		changed.Labels = map[string]string{"migration-test": "changed"}
+		Expect(c.Client.Update(ctx, changed)).To(Succeed())
+	}
+	return c.Client.Update(ctx, object, opts...)
+}
+
+func getTestApplicationsCRD() *apiextensionsv1.CustomResourceDefinition {
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	Expect(k8sClient.Get(ctx, types.NamespacedName{Name: testApplicationsCRDName}, crd)).To(Succeed())
+	return crd
+}
+
+var _ = Describe("Storage version migration", func() {
+	It("rewrites the applications, trims the stored versions and removes the annotation", func() {
+		createTestNamespace("migration")
+
+		applications := []*applicationsamplev1.Application{}
+		for _, name := range []string{"migration-first", "migration-second"} {
+			application := &applicationsamplev1.Application{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "migration"},
+				Spec: applicationsamplev1.ApplicationSpec{
+					Database: applicationsamplev1.ApplicationDatabase{Name: name, Namespace: "migration"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, application)).To(Succeed())
+			defer k8sClient.Delete(ctx, application)
+			applications = append(applications, application)
+		}
+
+		By("storing applications in old versions")
+		crd := getTestApplicationsCRD()
+		crd.Status.StoredVersions = []string{"v1alpha1", "v1beta1", "v1"}
+		Expect(k8sClient.Status().Update(ctx, crd)).To(Succeed())
+		crd = getTestApplicationsCRD()
+		metav1.SetMetaDataAnnotation(&crd.ObjectMeta, migrationcontroller.MigrateAnnotation, "true")
+		Expect(k8sClient.Update(ctx, crd)).To(Succeed())
+
+		By("migrating on demand")
+		testClient := &conflictingTestClient{Client: k8sClient, updates: map[string]int{}, conflicts: map[string]bool{}}
+		reconciler := &migrationcontroller.StorageVersionMigrationReconciler{Client: testClient, APIReader: k8sClient,
+			Scheme: scheme.Scheme}
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: testApplicationsCRDName}})
+		Expect(err).NotTo(HaveOccurred())
+
+		// Note: Updates of the application controller can cause further conflicts
+		for _, application := range applications {
+			Expect(testClient.updates[application.Name]).To(BeNumerically(">=", 2))
+			Expect(getTestApplication(application.Name, application.Namespace).Labels).To(HaveKeyWithValue("migration-test", "changed"))
+		}
+		crd = getTestApplicationsCRD()
+		Expect(crd.Status.StoredVersions).To(Equal([]string{"v1"}))
+		Expect(crd.Annotations).NotTo(HaveKey(migrationcontroller.MigrateAnnotation))
+
+		By("not migrating again without changes of the stored versions")
+		updates := map[string]int{}
+		for name, count := range testClient.updates {
+			updates[name] = count
+		}
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: testApplicationsCRDName}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(testClient.updates).To(Equal(updates))
+	})
+})
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	applicationsamplev1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func getTestPodLabels(application *applicationsamplev1.Application) func() (map[string]string, error) {
+	return func() (map[string]string, error) {
+		deployment := &appsv1.Deployment{}
+		name := application.Name + "-deployment-microservice"
+		err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: application.Namespace}, deployment)
+		return deployment.Spec.Template.Labels, err
+	}
+}
+
+var _ = Describe("Network policies", func() {
+	It("only select the pods of their application", func() {
+		createTestNamespace("network")
+		createTestNamespace("network-database")
+
+		applications := []*applicationsamplev1.Application{}
+		for _, name := range []string{"network-first", "network-second"} {
+			application := &applicationsamplev1.Application{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "network"},
+				Spec: applicationsamplev1.ApplicationSpec{
+					Database: applicationsamplev1.ApplicationDatabase{Name: name, Namespace: "network-database"},
+					Exposure: applicationsamplev1.ApplicationExposure{Network: &applicationsamplev1.ApplicationNetwork{}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, application)).To(Succeed())
+			defer k8sClient.Delete(ctx, application)
+			applications = append(applications, application)
+		}
+
+		podLabels := []map[string]string{}
+		for _, application := range applications {
+			Eventually(getTestPodLabels(application), testTimeout).Should(HaveKeyWithValue("app.kubernetes.io/instance", application.Name))
+			labels, _ := getTestPodLabels(application)()
+			podLabels = append(podLabels, labels)
+		}
+
+		for i, application := range applications {
+			for _, suffix := range []string{"-networkpolicy-ingress-microservice", "-networkpolicy-egress-microservice"} {
+				networkPolicy := &networkingv1.NetworkPolicy{}
+				Eventually(func() error {
+					return k8sClient.Get(ctx, types.NamespacedName{Name: application.Name + suffix, Namespace: application.Namespace}, networkPolicy)
+				}, testTimeout).Should(Succeed())
+				selector, err := metav1.LabelSelectorAsSelector(&networkPolicy.Spec.PodSelector)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(selector.Matches(labels.Set(podLabels[i]))).To(BeTrue())
+				Expect(selector.Matches(labels.Set(podLabels[1-i]))).To(BeFalse())
+			}
+		}
+	})
+})
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	applicationsamplev1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
+	applicationcontroller "github.com/nheidloff/operator-sample-go/operator-application/controllers/application"
+	databasesamplev1alpha1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const testTimeout = "20s"
+
+func createTestNamespace(name string) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	err := k8sClient.Create(ctx, namespace)
+	if !errors.IsAlreadyExists(err) {
+		Expect(err).NotTo(HaveOccurred())
+	}
+}
+
+func getTestApplication(name string, namespace string) *applicationsamplev1.Application {
+	application := &applicationsamplev1.Application{}
+	Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, application)).To(Succeed())
+	return application
+}
+
+func getEgressNamespaces(application *applicationsamplev1.Application) func() []string {
+	return func() []string {
+		networkPolicy := &networkingv1.NetworkPolicy{}
+		name := application.Name + "-networkpolicy-egress-microservice"
+		err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: application.Namespace}, networkPolicy)
+		if err != nil || len(networkPolicy.Spec.Egress) == 0 {
+			return nil
+		}
+		namespaces := []string{}
+		for _, peer := range networkPolicy.Spec.Egress[0].To {
+			namespaces = append(namespaces, peer.NamespaceSelector.MatchLabels["kubernetes.io/metadata.name"])
+		}
+		return namespaces
+	}
+}
+
+// Note: envtest doesn't run the deployment controller, so the rollout is reported by the spec
+func markTestDeploymentRolledOut(application *applicationsamplev1.Application) {
+	deployment := &appsv1.Deployment{}
+	name := application.Name + "-deployment-microservice"
+	Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: application.Namespace}, deployment)).To(Succeed())
+	deployment.Status.ObservedGeneration = deployment.Generation
+	deployment.Status.Replicas = *deployment.Spec.Replicas
+	deployment.Status.UpdatedReplicas = *deployment.Spec.Replicas
+	deployment.Status.AvailableReplicas = *deployment.Spec.Replicas
+	Expect(k8sClient.Status().Update(ctx, deployment)).To(Succeed())
+}
+
+var _ = Describe("Database relocation", func() {
+	It("switches the microservice to the new database and deletes the old one after the rollout", func() {
+		createTestNamespace("relocation")
+		createTestNamespace("relocation-database-old")
+		createTestNamespace("relocation-database-new")
+
+		application := &applicationsamplev1.Application{
+			ObjectMeta: metav1.ObjectMeta{Name: "relocation", Namespace: "relocation"},
+			Spec: applicationsamplev1.ApplicationSpec{
+				Database: applicationsamplev1.ApplicationDatabase{Name: "database", Namespace: "relocation-database-old"},
+				Exposure: applicationsamplev1.ApplicationExposure{Network: &applicationsamplev1.ApplicationNetwork{}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, application)).To(Succeed())
+		defer k8sClient.Delete(ctx, application)
+
+		By("using the database of the spec")
+		Eventually(getEgressNamespaces(application), testTimeout).Should(Equal([]string{"relocation-database-old"}))
+		application = getTestApplication(application.Name, application.Namespace)
+		Expect(application.Status.DatabaseNamespace).To(Equal("relocation-database-old"))
+
+		By("relocating the database")
+		application.Annotations = map[string]string{applicationsamplev1.RelocateDatabaseAnnotation: "true"}
+		application.Spec.Database.Namespace = "relocation-database-new"
+		Expect(k8sClient.Update(ctx, application)).To(Succeed())
+
+		// Note: Pods which haven't been rolled out yet still use the old database
+		Eventually(getEgressNamespaces(application), testTimeout).Should(Equal([]string{"relocation-database-new", "relocation-database-old"}))
+		application = getTestApplication(application.Name, application.Namespace)
+		Expect(application.Status.DatabaseNamespace).To(Equal("relocation-database-new"))
+		Expect(application.Status.PreviousDatabaseNamespace).To(Equal("relocation-database-old"))
+		condition := meta.FindStatusCondition(application.Status.Conditions, applicationcontroller.CONDITION_TYPE_DATABASE_RELOCATED)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Reason).To(Equal(applicationcontroller.CONDITION_REASON_DATABASE_RELOCATION_IN_PROGRESS))
+		database := &databasesamplev1alpha1.Database{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "database", Namespace: "relocation-database-new"}, database)).To(Succeed())
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "database", Namespace: "relocation-database-old"}, database)).To(Succeed())
+
+		By("completing the relocation after the rollout")
+		markTestDeploymentRolledOut(application)
+		Eventually(getEgressNamespaces(application), testTimeout).Should(Equal([]string{"relocation-database-new"}))
+		Eventually(func() string {
+			application = getTestApplication(application.Name, application.Namespace)
+			condition := meta.FindStatusCondition(application.Status.Conditions, applicationcontroller.CONDITION_TYPE_DATABASE_RELOCATED)
+			if condition == nil {
+				return ""
+			}
+			return condition.Reason
+		}, testTimeout).Should(Equal(applicationcontroller.CONDITION_REASON_DATABASE_RELOCATION_SUCCEEDED))
+		Expect(application.Status.PreviousDatabaseNamespace).To(BeEmpty())
+		Eventually(func() map[string]string {
+			return getTestApplication(application.Name, application.Namespace).Annotations
+		}, testTimeout).ShouldNot(HaveKey(applicationsamplev1.RelocateDatabaseAnnotation))
+		err := k8sClient.Get(ctx, types.NamespacedName{Name: "database", Namespace: "relocation-database-old"}, database)
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+	})
+})
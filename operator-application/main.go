@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
@@ -16,9 +18,13 @@ import (
 
 	databasesamplev1alpha1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1alpha1"
 
+	applicationsamplev1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1"
 	applicationsamplev1alpha1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1alpha1"
 	applicationsamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-application/api/v1beta1"
 	applicationcontroller "github.com/nheidloff/operator-sample-go/operator-application/controllers/application"
+	certificatescontroller "github.com/nheidloff/operator-sample-go/operator-application/controllers/certificates"
+	migrationcontroller "github.com/nheidloff/operator-sample-go/operator-application/controllers/migration"
+	"github.com/nheidloff/operator-sample-go/operator-application/utilities"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -29,11 +35,13 @@ var (
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
 
 	utilruntime.Must(databasesamplev1alpha1.AddToScheme(scheme))
 
 	utilruntime.Must(applicationsamplev1alpha1.AddToScheme(scheme))
 	utilruntime.Must(applicationsamplev1beta1.AddToScheme(scheme))
+	utilruntime.Must(applicationsamplev1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -41,11 +49,19 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var migrateStorageVersion bool
+	var manageWebhookCertificates bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&migrateStorageVersion, "migrate-storage-version", true,
+		"Rewrite all applications in the current storage version on startup if they are stored in old versions. "+
+			"Without this flag the migration only runs if the CRD is annotated.")
+	flag.BoolVar(&manageWebhookCertificates, "manage-webhook-certificates", false,
+		"Generate and rotate the certificates of the webhooks and inject the CA bundle. "+
+			"Enabling this will remove the dependency on cert-manager.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -74,11 +90,40 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "Application")
 		os.Exit(1)
 	}
+	if err = (&migrationcontroller.StorageVersionMigrationReconciler{
+		Client:           mgr.GetClient(),
+		APIReader:        mgr.GetAPIReader(),
+		Scheme:           mgr.GetScheme(),
+		MigrateOnStartup: migrateStorageVersion,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "StorageVersionMigration")
+		os.Exit(1)
+	}
 	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
-		if err = (&applicationsamplev1beta1.Application{}).SetupWebhookWithManager(mgr); err != nil {
+		if err = (&applicationsamplev1.Application{}).SetupWebhookWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "Application")
 			os.Exit(1)
 		}
+		applicationsamplev1alpha1.SetupDeprecationWebhookWithManager(mgr)
+		if manageWebhookCertificates {
+			certificateReconciler := &certificatescontroller.CertificateReconciler{
+				Client:    mgr.GetClient(),
+				APIReader: mgr.GetAPIReader(),
+				Scheme:    mgr.GetScheme(),
+				Namespace: utilities.GetOperatorNamespace(),
+				CertDir:   certificatescontroller.DefaultCertDir,
+				Names:     certificatescontroller.NewNames("operator-application-", "applications.application.sample.ibm.com"),
+			}
+			// Note: The webhook server requires the certificate files when it starts
+			if err = certificateReconciler.SetupCertificates(context.Background()); err != nil {
+				setupLog.Error(err, "unable to set up webhook certificates")
+				os.Exit(1)
+			}
+			if err = certificateReconciler.SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create controller", "controller", "WebhookCertificates")
+				os.Exit(1)
+			}
+		}
 	}
 	//+kubebuilder:scaffold:builder
 
@@ -0,0 +1,123 @@
+package policies
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"sigs.k8s.io/yaml"
+)
+
+// Note: Expressions get the object, the old object on updates (otherwise null) and the namespace of the object.
+// The namespace is called namespaceObject since namespace is a reserved word in CEL.
+const VariableObject = "object"
+const VariableOldObject = "oldObject"
+const VariableNamespace = "namespaceObject"
+
+// Note: Limits the evaluation time of expressions, e.g. of comprehensions over large lists
+const costLimit uint64 = 1000000
+
+// Rule is a policy rule which is defined by platform admins, for example:
+//
+//	expression: object.spec.workload.amountPods <= 3 || namespaceObject.metadata.labels.environment == "prod"
+//	message: Only production namespaces may run more than three pods
+//	field: spec.workload.amountPods
+type Rule struct {
+	Name       string `json:"-"`
+	Expression string `json:"expression"`
+	Message    string `json:"message,omitempty"`
+	Field      string `json:"field,omitempty"`
+	program    cel.Program
+}
+
+// Violation is a rule which an object doesn't comply with
+type Violation struct {
+	Rule    string
+	Field   string
+	Message string
+}
+
+// ParseRules compiles the rules of a config map. Every key is the name of a rule, every value contains the rule in YAML.
+// Rules which cannot be compiled are returned as errors and skipped.
+func ParseRules(data map[string]string) ([]*Rule, []error) {
+	environment, err := cel.NewEnv(
+		cel.Variable(VariableObject, cel.DynType),
+		cel.Variable(VariableOldObject, cel.DynType),
+		cel.Variable(VariableNamespace, cel.DynType),
+	)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rules := []*Rule{}
+	errs := []error{}
+	for _, name := range names {
+		rule := &Rule{}
+		err := yaml.UnmarshalStrict([]byte(data[name]), rule)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s is invalid: %v", name, err))
+			continue
+		}
+		rule.Name = name
+		if strings.TrimSpace(rule.Expression) == "" {
+			errs = append(errs, fmt.Errorf("rule %s has no expression", name))
+			continue
+		}
+		ast, issues := environment.Compile(rule.Expression)
+		if issues != nil && issues.Err() != nil {
+			errs = append(errs, fmt.Errorf("rule %s cannot be compiled: %v", name, issues.Err()))
+			continue
+		}
+		if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+			errs = append(errs, fmt.Errorf("rule %s must return a bool, not %s", name, ast.OutputType()))
+			continue
+		}
+		rule.program, err = environment.Program(ast, cel.CostLimit(costLimit))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s cannot be compiled: %v", name, err))
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, errs
+}
+
+// Evaluate returns the rules which the object violates. Pass nil for the old object on creation.
+// Note: Rules which cannot be evaluated, e.g. because a field is missing, are violations too
+func Evaluate(rules []*Rule, object map[string]interface{}, oldObject map[string]interface{}, namespace map[string]interface{}) []Violation {
+	activation := map[string]interface{}{
+		VariableObject:    object,
+		VariableOldObject: types.NullValue,
+		VariableNamespace: namespace,
+	}
+	if oldObject != nil {
+		activation[VariableOldObject] = oldObject
+	}
+
+	violations := []Violation{}
+	for _, rule := range rules {
+		result, _, err := rule.program.Eval(activation)
+		if err != nil {
+			violations = append(violations, Violation{Rule: rule.Name, Field: rule.Field,
+				Message: fmt.Sprintf("policy %s could not be evaluated: %v", rule.Name, err)})
+			continue
+		}
+		if allowed, ok := result.Value().(bool); ok && allowed {
+			continue
+		}
+		message := rule.Message
+		if message == "" {
+			message = "violates policy " + rule.Name + ": " + rule.Expression
+		}
+		violations = append(violations, Violation{Rule: rule.Name, Field: rule.Field, Message: message})
+	}
+	return violations
+}
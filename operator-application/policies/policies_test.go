@@ -0,0 +1,84 @@
+package policies
+
+import (
+	"testing"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, errs := ParseRules(map[string]string{
+		"max-pods":       "expression: object.spec.workload.amountPods <= 3\nmessage: At most three pods",
+		"syntax-error":   "expression: object.spec.(",
+		"not-a-bool":     "expression: '\"text\"'",
+		"no-expression":  "message: Missing expression",
+		"unknown-fields": "expression: 'true'\nseverity: high",
+	})
+	if len(rules) != 1 || rules[0].Name != "max-pods" {
+		t.Fatalf("expected only rule max-pods to be compiled, got %v", rules)
+	}
+	if len(errs) != 4 {
+		t.Fatalf("expected four invalid rules, got %v", errs)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	rules, errs := ParseRules(map[string]string{
+		"max-pods": `
+expression: object.spec.workload.amountPods <= 3 || namespaceObject.metadata.labels.environment == "prod"
+message: Only production namespaces may run more than three pods
+field: spec.workload.amountPods`,
+		"required-labels": `
+expression: has(object.metadata.labels) && "team" in object.metadata.labels`,
+		"immutable-title": `
+expression: oldObject == null || object.spec.title == oldObject.spec.title
+message: The title cannot be changed`,
+	})
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	application := func(amountPods int64, title string, labels map[string]interface{}) map[string]interface{} {
+		metadata := map[string]interface{}{"name": "application"}
+		if labels != nil {
+			metadata["labels"] = labels
+		}
+		return map[string]interface{}{
+			"metadata": metadata,
+			"spec": map[string]interface{}{
+				"title":    title,
+				"workload": map[string]interface{}{"amountPods": amountPods},
+			},
+		}
+	}
+	namespace := func(environment string) map[string]interface{} {
+		return map[string]interface{}{"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{"environment": environment},
+		}}
+	}
+	team := map[string]interface{}{"team": "a"}
+
+	tests := []struct {
+		name      string
+		object    map[string]interface{}
+		oldObject map[string]interface{}
+		namespace map[string]interface{}
+		expected  []string
+	}{
+		{"compliant", application(3, "title", team), nil, namespace("dev"), []string{}},
+		{"too many pods", application(4, "title", team), nil, namespace("dev"), []string{"max-pods"}},
+		{"too many pods in prod", application(4, "title", team), nil, namespace("prod"), []string{}},
+		{"missing labels", application(1, "title", nil), nil, namespace("dev"), []string{"required-labels"}},
+		{"changed title", application(1, "new", team), application(1, "old", team), namespace("dev"), []string{"immutable-title"}},
+		{"missing namespace labels", application(4, "title", team), nil, map[string]interface{}{}, []string{"max-pods"}},
+	}
+	for _, test := range tests {
+		violations := Evaluate(rules, test.object, test.oldObject, test.namespace)
+		if len(violations) != len(test.expected) {
+			t.Fatalf("%s: expected violations %v, got %v", test.name, test.expected, violations)
+		}
+		for i, violation := range violations {
+			if violation.Rule != test.expected[i] || violation.Message == "" {
+				t.Fatalf("%s: expected violations %v, got %v", test.name, test.expected, violations)
+			}
+		}
+	}
+}
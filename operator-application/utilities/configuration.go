@@ -0,0 +1,183 @@
+package utilities
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Note: The operator configuration is read from environment variables of the manager container
+const ConfigurationDefaultCpuRequest = "APPLICATION_DEFAULT_CPU_REQUEST"
+const ConfigurationDefaultMemoryRequest = "APPLICATION_DEFAULT_MEMORY_REQUEST"
+const ConfigurationDefaultCpuLimit = "APPLICATION_DEFAULT_CPU_LIMIT"
+const ConfigurationDefaultMemoryLimit = "APPLICATION_DEFAULT_MEMORY_LIMIT"
+const ConfigurationMaxAmountPods = "APPLICATION_MAX_AMOUNT_PODS"
+const ConfigurationAllowedSchemaUrlSchemes = "APPLICATION_ALLOWED_SCHEMA_URL_SCHEMES"
+const ConfigurationDefaultAmountPods = "APPLICATION_DEFAULT_AMOUNT_PODS"
+const ConfigurationDefaultDatabaseName = "APPLICATION_DEFAULT_DATABASE_NAME"
+const ConfigurationDefaultDatabaseNamespace = "APPLICATION_DEFAULT_DATABASE_NAMESPACE"
+const ConfigurationNamespaceDefaults = "APPLICATION_NAMESPACE_DEFAULTS"
+const ConfigurationPolicyConfigMap = "APPLICATION_POLICY_CONFIGMAP"
+const ConfigurationOperatorNamespace = "POD_NAMESPACE"
+
+const defaultAllowedSchemaUrlSchemes = "https"
+const defaultAmountPods int32 = 1
+const defaultDatabaseName = "database"
+const defaultDatabaseNamespace = "database"
+const defaultPolicyConfigMap = "operator-application-policies"
+const defaultOperatorNamespace = "operator-application-system"
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// GetConfiguredDefaultResources returns the default resources from the operator configuration or nil if none are configured
+func GetConfiguredDefaultResources() (*corev1.ResourceRequirements, error) {
+	requests, err := getConfiguredResourceList(ConfigurationDefaultCpuRequest, ConfigurationDefaultMemoryRequest)
+	if err != nil {
+		return nil, err
+	}
+	limits, err := getConfiguredResourceList(ConfigurationDefaultCpuLimit, ConfigurationDefaultMemoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	if len(requests) == 0 && len(limits) == 0 {
+		return nil, nil
+	}
+	return &corev1.ResourceRequirements{Requests: requests, Limits: limits}, nil
+}
+
+func getConfiguredResourceList(cpuVariable string, memoryVariable string) (corev1.ResourceList, error) {
+	resources := corev1.ResourceList{}
+	variables := map[corev1.ResourceName]string{
+		corev1.ResourceCPU:    cpuVariable,
+		corev1.ResourceMemory: memoryVariable,
+	}
+	for resourceName, variable := range variables {
+		value := os.Getenv(variable)
+		if value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid operator configuration %s: %v", variable, err)
+		}
+		resources[resourceName] = quantity
+	}
+	return resources, nil
+}
+
+// GetConfiguredMaxAmountPods returns the maximal amount of pods per application or 0 if there is no limit
+func GetConfiguredMaxAmountPods() (int32, error) {
+	value := os.Getenv(ConfigurationMaxAmountPods)
+	if value == "" {
+		return 0, nil
+	}
+	return ParseAmountPods(ConfigurationMaxAmountPods, value)
+}
+
+// GetConfiguredAllowedSchemaUrlSchemes returns the URL schemes which may be used for schema URLs
+func GetConfiguredAllowedSchemaUrlSchemes() []string {
+	value := os.Getenv(ConfigurationAllowedSchemaUrlSchemes)
+	if value == "" {
+		value = defaultAllowedSchemaUrlSchemes
+	}
+	schemes := []string{}
+	for _, scheme := range strings.Split(value, ",") {
+		scheme = strings.ToLower(strings.TrimSpace(scheme))
+		if scheme != "" {
+			schemes = append(schemes, scheme)
+		}
+	}
+	return schemes
+}
+
+// GetConfiguredDefaultAmountPods returns the amount of pods of applications which don't define it
+func GetConfiguredDefaultAmountPods() (int32, error) {
+	value := os.Getenv(ConfigurationDefaultAmountPods)
+	if value == "" {
+		return defaultAmountPods, nil
+	}
+	return ParseAmountPods(ConfigurationDefaultAmountPods, value)
+}
+
+// ParseAmountPods parses a non-negative amount of pods from the configuration source with the given name
+func ParseAmountPods(source string, value string) (int32, error) {
+	amountPods, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || amountPods < 0 {
+		return 0, fmt.Errorf("Invalid operator configuration %s: %s", source, value)
+	}
+	return int32(amountPods), nil
+}
+
+// GetConfiguredDefaultDatabaseName returns the database name of applications which don't define it
+func GetConfiguredDefaultDatabaseName() string {
+	return getConfiguredString(ConfigurationDefaultDatabaseName, defaultDatabaseName)
+}
+
+// GetConfiguredDefaultDatabaseNamespace returns the database namespace of applications which don't define it
+func GetConfiguredDefaultDatabaseNamespace() string {
+	return getConfiguredString(ConfigurationDefaultDatabaseNamespace, defaultDatabaseNamespace)
+}
+
+// NamespaceDefaults are the defaults of applications in namespaces whose labels match the selector, e.g. env=prod
+type NamespaceDefaults struct {
+	NamespaceSelector string `json:"namespaceSelector"`
+	AmountPods        *int32 `json:"amountPods,omitempty"`
+	DatabaseName      string `json:"databaseName,omitempty"`
+	DatabaseNamespace string `json:"databaseNamespace,omitempty"`
+}
+
+// GetConfiguredNamespaceDefaults returns the first defaults whose selector matches the labels of the namespace or nil
+// Note: The configuration is a JSON list, e.g. [{"namespaceSelector":"env=prod","amountPods":3}]
+func GetConfiguredNamespaceDefaults(namespaceLabels map[string]string) (*NamespaceDefaults, error) {
+	value := strings.TrimSpace(os.Getenv(ConfigurationNamespaceDefaults))
+	if value == "" {
+		return nil, nil
+	}
+	namespaceDefaults := []NamespaceDefaults{}
+	err := json.Unmarshal([]byte(value), &namespaceDefaults)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid operator configuration %s: %v", ConfigurationNamespaceDefaults, err)
+	}
+	for i := range namespaceDefaults {
+		selector, err := labels.Parse(namespaceDefaults[i].NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid operator configuration %s: %v", ConfigurationNamespaceDefaults, err)
+		}
+		if namespaceDefaults[i].AmountPods != nil && *namespaceDefaults[i].AmountPods < 0 {
+			return nil, fmt.Errorf("Invalid operator configuration %s: amountPods must not be negative", ConfigurationNamespaceDefaults)
+		}
+		if selector.Matches(labels.Set(namespaceLabels)) {
+			return &namespaceDefaults[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// GetConfiguredPolicyConfigMapName returns the name of the config map in the operator namespace which contains policy rules
+func GetConfiguredPolicyConfigMapName() string {
+	return getConfiguredString(ConfigurationPolicyConfigMap, defaultPolicyConfigMap)
+}
+
+// GetOperatorNamespace returns the namespace the operator runs in
+func GetOperatorNamespace() string {
+	if namespace := strings.TrimSpace(os.Getenv(ConfigurationOperatorNamespace)); namespace != "" {
+		return namespace
+	}
+	if namespace, err := os.ReadFile(serviceAccountNamespaceFile); err == nil {
+		return strings.TrimSpace(string(namespace))
+	}
+	return defaultOperatorNamespace
+}
+
+func getConfiguredString(variable string, defaultValue string) string {
+	value := strings.TrimSpace(os.Getenv(variable))
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,189 @@
+package utilities
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+const PodSecurityEnforceLabelName = "pod-security.kubernetes.io/enforce"
+const PodSecurityLevelPrivileged = "privileged"
+const PodSecurityLevelBaseline = "baseline"
+const PodSecurityLevelRestricted = "restricted"
+
+// Note: Capabilities which may be added according to the baseline Pod Security Standard
+var baselineCapabilities = map[corev1.Capability]bool{
+	"AUDIT_WRITE": true, "CHOWN": true, "DAC_OVERRIDE": true, "FOWNER": true, "FSETID": true, "KILL": true, "MKNOD": true,
+	"NET_BIND_SERVICE": true, "SETFCAP": true, "SETGID": true, "SETPCAP": true, "SETUID": true, "SYS_CHROOT": true,
+}
+
+// Note: Sysctls which may be set according to the baseline Pod Security Standard
+var baselineSysctls = map[string]bool{
+	"kernel.shm_rmid_forced": true, "net.ipv4.ip_local_port_range": true, "net.ipv4.ip_unprivileged_port_start": true,
+	"net.ipv4.tcp_syncookies": true, "net.ipv4.ping_group_range": true,
+}
+
+// Note: SELinux types which may be set according to the baseline Pod Security Standard, user and role must not be set
+var baselineSELinuxTypes = map[string]bool{"": true, "container_t": true, "container_init_t": true, "container_kvm_t": true}
+
+// DefaultPodSecurityContext returns a pod security context which complies to the restricted Pod Security Standard
+func DefaultPodSecurityContext() *corev1.PodSecurityContext {
+	runAsNonRoot := true
+	return &corev1.PodSecurityContext{
+		RunAsNonRoot:   &runAsNonRoot,
+		SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}
+}
+
+// DefaultContainerSecurityContext returns a container security context which complies to the restricted Pod Security Standard
+func DefaultContainerSecurityContext() *corev1.SecurityContext {
+	allowPrivilegeEscalation := false
+	privileged := false
+	runAsNonRoot := true
+	return &corev1.SecurityContext{
+		AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+		Privileged:               &privileged,
+		RunAsNonRoot:             &runAsNonRoot,
+		Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+		SeccompProfile:           &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}
+}
+
+// Note: Context is either podSecurityContext or securityContext, Field the name of the setting in this context
+type PodSecurityViolation struct {
+	Context string
+	Field   string
+	Message string
+}
+
+const podSecurityContext = "podSecurityContext"
+const containerSecurityContext = "securityContext"
+
+// CheckPodSecurity returns the violations of the given Pod Security Standard level
+// Note: Only the settings which can be defined in the application are checked
+func CheckPodSecurity(level string, podContext *corev1.PodSecurityContext, containerContext *corev1.SecurityContext) []PodSecurityViolation {
+	violations := []PodSecurityViolation{}
+	if level != PodSecurityLevelBaseline && level != PodSecurityLevelRestricted {
+		return violations
+	}
+	if podContext == nil {
+		podContext = &corev1.PodSecurityContext{}
+	}
+	if containerContext == nil {
+		containerContext = &corev1.SecurityContext{}
+	}
+	addViolation := func(context string, field string, message string) {
+		violations = append(violations, PodSecurityViolation{Context: context, Field: field, Message: message})
+	}
+
+	// Baseline
+	if containerContext.Privileged != nil && *containerContext.Privileged {
+		addViolation(containerSecurityContext, "privileged", "privileged containers are not allowed")
+	}
+	if containerContext.Capabilities != nil {
+		for _, capability := range containerContext.Capabilities.Add {
+			if !baselineCapabilities[capability] {
+				addViolation(containerSecurityContext, "capabilities", "capability "+string(capability)+" must not be added")
+			}
+		}
+	}
+	if isSeccompProfileType(podContext.SeccompProfile, corev1.SeccompProfileTypeUnconfined) {
+		addViolation(podSecurityContext, "seccompProfile", "seccomp profile must not be Unconfined")
+	}
+	if isSeccompProfileType(containerContext.SeccompProfile, corev1.SeccompProfileTypeUnconfined) {
+		addViolation(containerSecurityContext, "seccompProfile", "seccomp profile must not be Unconfined")
+	}
+	if containerContext.ProcMount != nil && *containerContext.ProcMount != corev1.DefaultProcMount {
+		addViolation(containerSecurityContext, "procMount", "procMount must be Default")
+	}
+	for _, sysctl := range podContext.Sysctls {
+		if !baselineSysctls[sysctl.Name] {
+			addViolation(podSecurityContext, "sysctls", "sysctl "+sysctl.Name+" must not be set")
+		}
+	}
+	for _, message := range checkSELinuxOptions(podContext.SELinuxOptions) {
+		addViolation(podSecurityContext, "seLinuxOptions", message)
+	}
+	for _, message := range checkSELinuxOptions(containerContext.SELinuxOptions) {
+		addViolation(containerSecurityContext, "seLinuxOptions", message)
+	}
+	if podContext.WindowsOptions != nil && isTrue(podContext.WindowsOptions.HostProcess) {
+		addViolation(podSecurityContext, "windowsOptions", "hostProcess must not be true")
+	}
+	if containerContext.WindowsOptions != nil && isTrue(containerContext.WindowsOptions.HostProcess) {
+		addViolation(containerSecurityContext, "windowsOptions", "hostProcess must not be true")
+	}
+	if level == PodSecurityLevelBaseline {
+		return violations
+	}
+
+	// Restricted
+	if containerContext.AllowPrivilegeEscalation == nil || *containerContext.AllowPrivilegeEscalation {
+		addViolation(containerSecurityContext, "allowPrivilegeEscalation", "allowPrivilegeEscalation must be false")
+	}
+	// Note: The setting of the container takes precedence, the one of the pod only applies if the container has none
+	if !isTrue(containerContext.RunAsNonRoot) && !(containerContext.RunAsNonRoot == nil && isTrue(podContext.RunAsNonRoot)) {
+		if containerContext.RunAsNonRoot == nil && podContext.RunAsNonRoot != nil {
+			addViolation(podSecurityContext, "runAsNonRoot", "runAsNonRoot must be true")
+		} else {
+			addViolation(containerSecurityContext, "runAsNonRoot", "runAsNonRoot must be true")
+		}
+	}
+	if podContext.RunAsUser != nil && *podContext.RunAsUser == 0 {
+		addViolation(podSecurityContext, "runAsUser", "runAsUser must not be 0")
+	}
+	if containerContext.RunAsUser != nil && *containerContext.RunAsUser == 0 {
+		addViolation(containerSecurityContext, "runAsUser", "runAsUser must not be 0")
+	}
+	seccompProfile := containerContext.SeccompProfile
+	seccompContext := containerSecurityContext
+	if seccompProfile == nil && podContext.SeccompProfile != nil {
+		seccompProfile = podContext.SeccompProfile
+		seccompContext = podSecurityContext
+	}
+	if !isSeccompProfileType(seccompProfile, corev1.SeccompProfileTypeRuntimeDefault) &&
+		!isSeccompProfileType(seccompProfile, corev1.SeccompProfileTypeLocalhost) {
+		addViolation(seccompContext, "seccompProfile", "seccomp profile must be RuntimeDefault or Localhost")
+	}
+	dropsAll := false
+	if containerContext.Capabilities != nil {
+		for _, capability := range containerContext.Capabilities.Drop {
+			if capability == "ALL" {
+				dropsAll = true
+			}
+		}
+		for _, capability := range containerContext.Capabilities.Add {
+			if capability != "NET_BIND_SERVICE" {
+				addViolation(containerSecurityContext, "capabilities", "only capability NET_BIND_SERVICE may be added")
+				break
+			}
+		}
+	}
+	if !dropsAll {
+		addViolation(containerSecurityContext, "capabilities", "capabilities must drop ALL")
+	}
+	return violations
+}
+
+func checkSELinuxOptions(options *corev1.SELinuxOptions) []string {
+	messages := []string{}
+	if options == nil {
+		return messages
+	}
+	if !baselineSELinuxTypes[options.Type] {
+		messages = append(messages, "SELinux type "+options.Type+" must not be set")
+	}
+	if options.User != "" {
+		messages = append(messages, "SELinux user must not be set")
+	}
+	if options.Role != "" {
+		messages = append(messages, "SELinux role must not be set")
+	}
+	return messages
+}
+
+func isTrue(value *bool) bool {
+	return value != nil && *value
+}
+
+func isSeccompProfileType(profile *corev1.SeccompProfile, profileType corev1.SeccompProfileType) bool {
+	return profile != nil && profile.Type == profileType
+}
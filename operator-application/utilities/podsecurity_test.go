@@ -0,0 +1,82 @@
+package utilities
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCheckPodSecurity(t *testing.T) {
+	hostProcess := true
+	tests := []struct {
+		name             string
+		podContext       func(*corev1.PodSecurityContext)
+		containerContext func(*corev1.SecurityContext)
+		context          string
+		field            string
+	}{
+		{name: "defaults"},
+		{name: "safe sysctl", podContext: func(context *corev1.PodSecurityContext) {
+			context.Sysctls = []corev1.Sysctl{{Name: "net.ipv4.tcp_syncookies", Value: "1"}}
+		}},
+		{name: "unsafe sysctl", podContext: func(context *corev1.PodSecurityContext) {
+			context.Sysctls = []corev1.Sysctl{{Name: "kernel.msgmax", Value: "65536"}}
+		}, context: podSecurityContext, field: "sysctls"},
+		{name: "container SELinux type", containerContext: func(context *corev1.SecurityContext) {
+			context.SELinuxOptions = &corev1.SELinuxOptions{Type: "container_init_t"}
+		}},
+		{name: "custom pod SELinux type", podContext: func(context *corev1.PodSecurityContext) {
+			context.SELinuxOptions = &corev1.SELinuxOptions{Type: "spc_t"}
+		}, context: podSecurityContext, field: "seLinuxOptions"},
+		{name: "custom container SELinux type", containerContext: func(context *corev1.SecurityContext) {
+			context.SELinuxOptions = &corev1.SELinuxOptions{Type: "spc_t"}
+		}, context: containerSecurityContext, field: "seLinuxOptions"},
+		{name: "pod SELinux user", podContext: func(context *corev1.PodSecurityContext) {
+			context.SELinuxOptions = &corev1.SELinuxOptions{User: "system_u"}
+		}, context: podSecurityContext, field: "seLinuxOptions"},
+		{name: "container SELinux user", containerContext: func(context *corev1.SecurityContext) {
+			context.SELinuxOptions = &corev1.SELinuxOptions{User: "system_u"}
+		}, context: containerSecurityContext, field: "seLinuxOptions"},
+		{name: "pod SELinux role", podContext: func(context *corev1.PodSecurityContext) {
+			context.SELinuxOptions = &corev1.SELinuxOptions{Role: "system_r"}
+		}, context: podSecurityContext, field: "seLinuxOptions"},
+		{name: "container SELinux role", containerContext: func(context *corev1.SecurityContext) {
+			context.SELinuxOptions = &corev1.SELinuxOptions{Role: "system_r"}
+		}, context: containerSecurityContext, field: "seLinuxOptions"},
+		{name: "pod host process", podContext: func(context *corev1.PodSecurityContext) {
+			context.WindowsOptions = &corev1.WindowsSecurityContextOptions{HostProcess: &hostProcess}
+		}, context: podSecurityContext, field: "windowsOptions"},
+		{name: "container host process", containerContext: func(context *corev1.SecurityContext) {
+			context.WindowsOptions = &corev1.WindowsSecurityContextOptions{HostProcess: &hostProcess}
+		}, context: containerSecurityContext, field: "windowsOptions"},
+	}
+
+	for _, level := range []string{PodSecurityLevelBaseline, PodSecurityLevelRestricted} {
+		for _, test := range tests {
+			podContext := DefaultPodSecurityContext()
+			if test.podContext != nil {
+				test.podContext(podContext)
+			}
+			containerContext := DefaultContainerSecurityContext()
+			if test.containerContext != nil {
+				test.containerContext(containerContext)
+			}
+			violations := CheckPodSecurity(level, podContext, containerContext)
+			if test.field == "" {
+				if len(violations) > 0 {
+					t.Errorf("%s, %s: expected no violations, got %v", level, test.name, violations)
+				}
+				continue
+			}
+			if len(violations) != 1 || violations[0].Context != test.context || violations[0].Field != test.field {
+				t.Errorf("%s, %s: expected a violation of %s.%s, got %v", level, test.name, test.context, test.field, violations)
+			}
+		}
+	}
+
+	if violations := CheckPodSecurity(PodSecurityLevelPrivileged, &corev1.PodSecurityContext{
+		Sysctls: []corev1.Sysctl{{Name: "kernel.msgmax", Value: "65536"}},
+	}, nil); len(violations) > 0 {
+		t.Errorf("expected no violations of the privileged level, got %v", violations)
+	}
+}
@@ -0,0 +1,126 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+
+	"github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var databaselog = logf.Log.WithName("database-resource")
+
+// Note: Fields of the hub version which don't exist in v1alpha1 are stored in this annotation when converting to
+// v1alpha1, so that they are not lost when the database is written back via v1alpha1
+const HubFieldsAnnotation = "database.sample.third.party/hub-fields"
+
+type hubFields struct {
+	Engine            string                       `json:"engine,omitempty"`
+	Version           string                       `json:"version,omitempty"`
+	StorageSize       *resource.Quantity           `json:"storageSize,omitempty"`
+	PasswordSecretRef *corev1.SecretKeySelector    `json:"passwordSecretRef"`
+	TLSSecretRef      *corev1.LocalObjectReference `json:"tlsSecretRef"`
+}
+
+// convert this database to the hub version (v1beta1)
+func (src *Database) ConvertTo(dstRaw conversion.Hub) error {
+	databaselog.Info("Calling ConvertTo")
+	dst := dstRaw.(*v1beta1.Database)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+
+	fields := hubFields{}
+	stashed, hasFields := dst.Annotations[HubFieldsAnnotation]
+	if hasFields {
+		err := json.Unmarshal([]byte(stashed), &fields)
+		if err != nil {
+			return err
+		}
+		delete(dst.Annotations, HubFieldsAnnotation)
+		if len(dst.Annotations) == 0 {
+			dst.Annotations = nil
+		}
+	}
+
+	// Note: Databases which have been created via v1alpha1 use PostgreSQL
+	dst.Spec.Engine = v1beta1.EnginePostgreSQL
+	if fields.Engine != "" {
+		dst.Spec.Engine = fields.Engine
+	}
+	dst.Spec.Version = fields.Version
+	dst.Spec.StorageSize = fields.StorageSize
+	dst.Spec.User = src.Spec.User
+	dst.Spec.Url = src.Spec.Url
+	dst.Spec.PasswordSecretRef = fields.PasswordSecretRef
+	dst.Spec.TLSSecretRef = fields.TLSSecretRef
+
+	// Note: The conversion doesn't read or write secrets. Inline values are passed to the operator in an annotation,
+	// which moves them into the generated secrets, see InlineCredentialsAnnotation.
+	inlineCredentials := v1beta1.InlineCredentials{Password: src.Spec.Password, Certificate: src.Spec.Certificate}
+	if inlineCredentials.Password != "" {
+		dst.Spec.PasswordSecretRef = nil
+	}
+	if inlineCredentials.Certificate != "" {
+		dst.Spec.TLSSecretRef = &corev1.LocalObjectReference{Name: v1beta1.GetGeneratedTLSSecretName(dst)}
+	}
+	if inlineCredentials != (v1beta1.InlineCredentials{}) {
+		stashed, err := json.Marshal(inlineCredentials)
+		if err != nil {
+			return err
+		}
+		if dst.Annotations == nil {
+			dst.Annotations = map[string]string{}
+		}
+		dst.Annotations[v1beta1.InlineCredentialsAnnotation] = string(stashed)
+	}
+
+	dst.Status.Conditions = src.DeepCopy().Status.Conditions
+	dst.Status.ObservedGeneration = src.Status.ObservedGeneration
+	if src.Status.Endpoint != nil {
+		dst.Status.Endpoint = &v1beta1.DatabaseEndpoint{Host: src.Status.Endpoint.Host, Port: src.Status.Endpoint.Port}
+	}
+	dst.Status.Version = src.Status.Version
+	dst.Status.ConnectionSecret = src.DeepCopy().Status.ConnectionSecret
+	dst.Status.Binding = src.DeepCopy().Status.Binding
+	return nil
+}
+
+// convert from the hub version (v1beta1) to this version
+func (dst *Database) ConvertFrom(srcRaw conversion.Hub) error {
+	databaselog.Info("Calling ConvertFrom")
+	src := srcRaw.(*v1beta1.Database)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+
+	fields, err := json.Marshal(hubFields{
+		Engine:            src.Spec.Engine,
+		Version:           src.Spec.Version,
+		StorageSize:       src.Spec.StorageSize,
+		PasswordSecretRef: src.Spec.PasswordSecretRef,
+		TLSSecretRef:      src.Spec.TLSSecretRef,
+	})
+	if err != nil {
+		return err
+	}
+	if dst.Annotations == nil {
+		dst.Annotations = map[string]string{}
+	}
+	dst.Annotations[HubFieldsAnnotation] = string(fields)
+
+	// Note: Credentials are only returned via v1beta1, v1alpha1 clients get empty values
+	dst.Spec.User = src.Spec.User
+	dst.Spec.Url = src.Spec.Url
+	dst.Spec.Password = ""
+	dst.Spec.Certificate = ""
+
+	dst.Status.Conditions = src.DeepCopy().Status.Conditions
+	dst.Status.ObservedGeneration = src.Status.ObservedGeneration
+	dst.Status.Endpoint = nil
+	if src.Status.Endpoint != nil {
+		dst.Status.Endpoint = &DatabaseEndpoint{Host: src.Status.Endpoint.Host, Port: src.Status.Endpoint.Port}
+	}
+	dst.Status.Version = src.Status.Version
+	dst.Status.ConnectionSecret = src.DeepCopy().Status.ConnectionSecret
+	dst.Status.Binding = src.DeepCopy().Status.Binding
+	return nil
+}
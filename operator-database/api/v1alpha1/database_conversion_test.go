@@ -0,0 +1,108 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestInlineCredentialsArePassedInAnnotation(t *testing.T) {
+	spoke := &Database{
+		ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "database"},
+		Spec:       DatabaseSpec{User: "name", Password: "password", Url: "url", Certificate: "certificate"},
+	}
+
+	hub := &v1beta1.Database{}
+	if err := spoke.ConvertTo(hub); err != nil {
+		t.Fatalf("ConvertTo failed: %v", err)
+	}
+	if hub.Spec.Engine != v1beta1.EnginePostgreSQL {
+		t.Fatalf("expected engine %s, got %s", v1beta1.EnginePostgreSQL, hub.Spec.Engine)
+	}
+	if hub.Spec.PasswordSecretRef != nil || hub.Spec.TLSSecretRef == nil || hub.Spec.TLSSecretRef.Name != "database-tls" {
+		t.Fatalf("expected references to generated secrets, got %+v", hub.Spec)
+	}
+	inlineCredentials := v1beta1.InlineCredentials{}
+	if err := json.Unmarshal([]byte(hub.Annotations[v1beta1.InlineCredentialsAnnotation]), &inlineCredentials); err != nil {
+		t.Fatalf("expected inline credentials in annotation: %v", err)
+	}
+	if inlineCredentials.Password != "password" || inlineCredentials.Certificate != "certificate" {
+		t.Fatalf("expected inline credentials in annotation, got %+v", inlineCredentials)
+	}
+
+	// Note: Credentials are not returned via v1alpha1
+	converted := &Database{}
+	if err := converted.ConvertFrom(hub); err != nil {
+		t.Fatalf("ConvertFrom failed: %v", err)
+	}
+	if converted.Spec.Password != "" || converted.Spec.Certificate != "" {
+		t.Fatalf("expected no credentials, got %+v", converted.Spec)
+	}
+}
+
+func TestHubFieldsSurviveRoundTrip(t *testing.T) {
+	for _, passwordSecretRef := range []*corev1.SecretKeySelector{
+		nil,
+		{LocalObjectReference: corev1.LocalObjectReference{Name: "custom"}, Key: "key"},
+	} {
+		storageSize := resource.MustParse("5Gi")
+		original := &v1beta1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "database"},
+			Spec: v1beta1.DatabaseSpec{Engine: v1beta1.EngineMySQL, Version: "8.0", StorageSize: &storageSize, User: "name",
+				PasswordSecretRef: passwordSecretRef, Url: "url", TLSSecretRef: &corev1.LocalObjectReference{Name: "tls"}},
+		}
+		spoke := &Database{}
+		if err := spoke.ConvertFrom(original.DeepCopy()); err != nil {
+			t.Fatalf("ConvertFrom failed: %v", err)
+		}
+		hub := &v1beta1.Database{}
+		if err := spoke.ConvertTo(hub); err != nil {
+			t.Fatalf("ConvertTo failed: %v", err)
+		}
+		if !equality.Semantic.DeepEqual(original.ObjectMeta, hub.ObjectMeta) || !equality.Semantic.DeepEqual(original.Spec, hub.Spec) {
+			t.Fatalf("v1beta1 -> v1alpha1 -> v1beta1 is not lossless: expected %+v, got %+v", original, hub)
+		}
+	}
+}
+
+func TestPlaintextPasswordsAreRejected(t *testing.T) {
+	database := &Database{Spec: DatabaseSpec{User: "name"}}
+	if err := database.ValidateCreate(); err != nil {
+		t.Fatalf("expected database without password to be accepted: %v", err)
+	}
+	database.Spec.Password = "password"
+	if err := database.ValidateCreate(); err == nil {
+		t.Fatal("expected plaintext password to be rejected on create")
+	}
+	if err := database.ValidateUpdate(database.DeepCopy()); err != nil {
+		t.Fatalf("expected unchanged password to be accepted: %v", err)
+	}
+	old := database.DeepCopy()
+	old.Spec.Password = "old"
+	if err := database.ValidateUpdate(old); err == nil {
+		t.Fatal("expected changed plaintext password to be rejected on update")
+	}
+}
+
+func TestUrlAndCertificateAreValidated(t *testing.T) {
+	database := &Database{Spec: DatabaseSpec{User: "name", Url: "url", Certificate: "certificate"}}
+	err := database.ValidateCreate()
+	if err == nil || !strings.Contains(err.Error(), "spec.url") || !strings.Contains(err.Error(), "spec.certificate") {
+		t.Fatalf("expected invalid url and certificate to be rejected on create, got %v", err)
+	}
+	if err := database.ValidateUpdate(database.DeepCopy()); err != nil {
+		t.Fatalf("expected unchanged url and certificate to be accepted: %v", err)
+	}
+	old := database.DeepCopy()
+	database.Spec.Url = "postgresql://database.database.svc:5432/database"
+	database.Spec.Certificate = ""
+	if err := database.ValidateUpdate(old); err != nil {
+		t.Fatalf("expected valid url without certificate to be accepted: %v", err)
+	}
+}
@@ -1,21 +1,50 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type DatabaseSpec struct {
-	User        string `json:"user,omitempty"`
+	//+kubebuilder:validation:Pattern=`^[a-z_][a-z0-9_]{0,31}$`
+	User        string `json:"user"`
 	Password    string `json:"password,omitempty"`
 	Url         string `json:"url,omitempty"`
 	Certificate string `json:"certificate,omitempty"`
 }
 
 type DatabaseStatus struct {
+	// Provisioning, Ready and Degraded
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Generation of the spec which the status has been computed for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Endpoint of the service which clients connect to
+	Endpoint *DatabaseEndpoint `json:"endpoint,omitempty"`
+
+	// Version of the database engine, e.g. 14
+	Version string `json:"version,omitempty"`
+
+	// Secret which contains the credentials to connect to the database
+	ConnectionSecret *corev1.LocalObjectReference `json:"connectionSecret,omitempty"`
+
+	// Secret which follows the Service Binding specification for provisioned services
+	Binding *corev1.LocalObjectReference `json:"binding,omitempty"`
+}
+
+type DatabaseEndpoint struct {
+	Host string `json:"host"`
+	Port int32  `json:"port"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Host",type=string,JSONPath=`.status.endpoint.host`
+//+kubebuilder:printcolumn:name="Port",type=integer,JSONPath=`.status.endpoint.port`
+//+kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.version`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 type Database struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -33,6 +62,14 @@ type DatabaseList struct {
 	Items           []Database `json:"items"`
 }
 
+func (database *Database) GetConditions() []metav1.Condition {
+	return database.Status.Conditions
+}
+
+func (database *Database) SetConditions(conditions []metav1.Condition) {
+	database.Status.Conditions = conditions
+}
+
 func init() {
 	SchemeBuilder.Register(&Database{}, &DatabaseList{})
 }
@@ -0,0 +1,103 @@
+package v1alpha1
+
+import (
+	"github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+const PlaintextPasswordMessage = "plaintext passwords are not accepted anymore, use database.sample.third.party/v1beta1 and spec.passwordSecretRef instead"
+
+func (r *Database) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// Note: Only requests which use v1alpha1 are validated. With the default match policy the API server would convert
+// v1beta1 requests to v1alpha1 and validate them twice.
+//+kubebuilder:webhook:path=/validate-database-sample-third-party-v1alpha1-database,mutating=false,failurePolicy=fail,matchPolicy=Exact,sideEffects=None,groups=database.sample.third.party,resources=databases,verbs=create;update,versions=v1alpha1,name=vdatabase.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &Database{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Database) ValidateCreate() error {
+	databaselog.Info("validate create", "name", r.Name)
+
+	errorList := v1beta1.ValidateInlineCredentialsAnnotation(r.Annotations, nil)
+	if r.Spec.Password != "" {
+		errorList = append(errorList, r.plaintextPasswordError())
+	}
+	errorList = append(errorList, r.validateCertificate(nil)...)
+	hub, err := r.toHub()
+	if err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+	errorList = append(errorList, hub.ValidateFields()...)
+	return r.toError(errorList)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+// Note: Passwords are not returned by v1alpha1, so only databases which were stored with a plaintext password have one.
+// The changes are validated like in v1beta1 after the conversion, since the engine, the version and the storage size
+// can be changed via the annotation with the fields of the hub version.
+func (r *Database) ValidateUpdate(old runtime.Object) error {
+	databaselog.Info("validate update", "name", r.Name)
+
+	oldDatabase, ok := old.(*Database)
+	if !ok {
+		return r.ValidateCreate()
+	}
+	errorList := v1beta1.ValidateInlineCredentialsAnnotation(r.Annotations, oldDatabase.Annotations)
+	if r.Spec.Password != "" && r.Spec.Password != oldDatabase.Spec.Password {
+		errorList = append(errorList, r.plaintextPasswordError())
+	}
+	errorList = append(errorList, r.validateCertificate(oldDatabase)...)
+	hub, err := r.toHub()
+	if err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+	oldHub, err := oldDatabase.toHub()
+	if err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+	errorList = append(errorList, hub.ValidateUpdatedFields(oldHub)...)
+	return r.toError(errorList)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Database) ValidateDelete() error {
+	return nil
+}
+
+func (r *Database) plaintextPasswordError() *field.Error {
+	return field.Forbidden(field.NewPath("spec", "password"), PlaintextPasswordMessage)
+}
+
+// Note: The certificate is only validated if it has changed
+func (r *Database) validateCertificate(oldDatabase *Database) field.ErrorList {
+	var errorList field.ErrorList
+	if r.Spec.Certificate != "" && (oldDatabase == nil || r.Spec.Certificate != oldDatabase.Spec.Certificate) {
+		err := v1beta1.ParseCACertificates([]byte(r.Spec.Certificate))
+		if err != nil {
+			errorList = append(errorList, field.Invalid(field.NewPath("spec", "certificate"), "<value omitted>", err.Error()))
+		}
+	}
+	return errorList
+}
+
+func (r *Database) toHub() (*v1beta1.Database, error) {
+	hub := &v1beta1.Database{}
+	err := r.ConvertTo(hub)
+	return hub, err
+}
+
+func (r *Database) toError(errorList field.ErrorList) error {
+	if len(errorList) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Database").GroupKind(), r.Name, errorList)
+}
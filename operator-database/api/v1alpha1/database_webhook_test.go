@@ -0,0 +1,81 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUpdatesOfHubFieldsAreValidatedLikeInV1beta1(t *testing.T) {
+	storageSize := resource.MustParse("1Gi")
+	hub := &v1beta1.Database{
+		ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "database"},
+		Spec: v1beta1.DatabaseSpec{
+			Engine:      v1beta1.EngineMySQL,
+			Version:     "8.0",
+			StorageSize: &storageSize,
+			User:        "name",
+			Url:         "mysql://database.database.svc:3306/database",
+		},
+	}
+	oldDatabase := &Database{}
+	if err := oldDatabase.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		hubFields     string
+		user          string
+		expectedError string
+	}{
+		{`{"engine":"mysql","version":"8.4","storageSize":"1Gi"}`, "name", ""},
+		{`{"engine":"postgresql","version":"8.0","storageSize":"1Gi"}`, "name", "spec.engine"},
+		{`{"engine":"mysql","version":"8.0","storageSize":"5Gi"}`, "name", "spec.storageSize"},
+		{`{"engine":"mysql","version":"5.7","storageSize":"1Gi"}`, "name", "spec.version"},
+		{`{"engine":"mysql","version":"8.0","storageSize":"1Gi"}`, v1beta1.MySQLAdminUser, "spec.user"},
+	} {
+		database := oldDatabase.DeepCopy()
+		database.Annotations[HubFieldsAnnotation] = test.hubFields
+		database.Spec.User = test.user
+		err := database.ValidateUpdate(oldDatabase)
+		if test.expectedError == "" && err != nil {
+			t.Errorf("%s: expected update to be accepted, got %v", test.hubFields, err)
+		}
+		if test.expectedError != "" && (err == nil || !strings.Contains(err.Error(), test.expectedError)) {
+			t.Errorf("%s: expected error for %s, got %v", test.hubFields, test.expectedError, err)
+		}
+	}
+}
+
+func TestInlineCredentialsAnnotationIsOnlyAcceptedFromTheConversion(t *testing.T) {
+	legacy := &Database{
+		ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "database"},
+		Spec:       DatabaseSpec{User: "name", Url: "postgresql://database.database.svc:5432/database", Password: "secret"},
+	}
+	oldHub, err := legacy.toHub()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := oldHub.DeepCopy()
+	hub.Spec.User = "other"
+	if errorList := v1beta1.ValidateInlineCredentialsAnnotation(hub.Annotations, oldHub.Annotations); len(errorList) > 0 {
+		t.Errorf("expected updates of converted databases to be accepted, got %v", errorList)
+	}
+
+	hub.Annotations[v1beta1.InlineCredentialsAnnotation] = `{"password":"other"}`
+	if errorList := v1beta1.ValidateInlineCredentialsAnnotation(hub.Annotations, oldHub.Annotations); len(errorList) == 0 {
+		t.Errorf("expected changes of the annotation to be rejected")
+	}
+
+	database := &Database{}
+	if err := database.ConvertFrom(oldHub); err != nil {
+		t.Fatal(err)
+	}
+	database.Annotations[v1beta1.InlineCredentialsAnnotation] = `{"password":"other"}`
+	if err := database.ValidateCreate(); err == nil || !strings.Contains(err.Error(), v1beta1.InlineCredentialsAnnotation) {
+		t.Errorf("expected the annotation to be rejected via v1alpha1, got %v", err)
+	}
+}
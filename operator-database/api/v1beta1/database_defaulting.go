@@ -0,0 +1,20 @@
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Note: Defaults are only applied to fields which are not set, so explicit values always win. The version depends on
+// the engine, so the engine is defaulted first.
+func (r *Database) applyDefaults() {
+	if r.Spec.Engine == "" {
+		r.Spec.Engine = EnginePostgreSQL
+	}
+	if r.Spec.Version == "" {
+		r.Spec.Version = GetDefaultVersion(r.Spec.Engine)
+	}
+	if r.Spec.StorageSize == nil {
+		storageSize := resource.MustParse(DefaultStorageSize)
+		r.Spec.StorageSize = &storageSize
+	}
+}
@@ -0,0 +1,219 @@
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Note: Credentials and TLS material are not part of the spec, but referenced secrets
+type DatabaseSpec struct {
+	// Database engine which runs the database. The engine cannot be changed after the database has been provisioned.
+	//+kubebuilder:validation:Enum=postgresql;mysql
+	//+kubebuilder:default=postgresql
+	Engine string `json:"engine,omitempty"`
+
+	// Version of the engine, e.g. '14' or '14.10' for PostgreSQL and '8.0' for MySQL. If not set, the default version
+	// of the engine is used. Versions can be upgraded, but not downgraded.
+	//+kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+){0,2}$`
+	Version string `json:"version,omitempty"`
+
+	// Size of the volume which stores the data, defaults to 1Gi. The storage size cannot be changed after the database
+	// has been provisioned.
+	StorageSize *resource.Quantity `json:"storageSize,omitempty"`
+
+	// Name of the user which owns the database. The admin users of the engines 'postgres' and 'root' as well as names
+	// with the prefixes 'pg_' and 'mysql.' are reserved.
+	//+kubebuilder:validation:Pattern=`^[a-z_][a-z0-9_]{0,31}$`
+	User string `json:"user"`
+
+	// Secret key which contains the password of the user. If not set, a password is generated and stored in
+	// the secret '<name>-credentials' under the key 'password'.
+	PasswordSecretRef *corev1.SecretKeySelector `json:"passwordSecretRef,omitempty"`
+
+	Url string `json:"url,omitempty"`
+
+	// Secret which contains the CA certificate under the key 'ca.crt'
+	TLSSecretRef *corev1.LocalObjectReference `json:"tlsSecretRef,omitempty"`
+}
+
+type DatabaseStatus struct {
+	// Provisioning, Ready, Degraded, Upgrading, UpgradeFailed and Reachable
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Generation of the spec which the status has been computed for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Endpoint of the service which clients connect to
+	Endpoint *DatabaseEndpoint `json:"endpoint,omitempty"`
+
+	// Version of the database engine, e.g. 14
+	Version string `json:"version,omitempty"`
+
+	// Secret which contains the credentials to connect to the database
+	ConnectionSecret *corev1.LocalObjectReference `json:"connectionSecret,omitempty"`
+
+	// Secret which follows the Service Binding specification for provisioned services
+	Binding *corev1.LocalObjectReference `json:"binding,omitempty"`
+
+	// Upgrade of the engine version which is in progress
+	Upgrade *DatabaseUpgrade `json:"upgrade,omitempty"`
+
+	// Result of the last connectivity probe, see condition Reachable
+	Connectivity *DatabaseConnectivity `json:"connectivity,omitempty"`
+}
+
+// Note: Upgrades go through the phases BackingUp, then Restarting for upgrades within a major version or Replacing and
+// Restoring for major upgrades
+type DatabaseUpgrade struct {
+	FromVersion string `json:"fromVersion"`
+	ToVersion   string `json:"toVersion"`
+
+	// RollingRestart or DumpRestore
+	Strategy string `json:"strategy"`
+
+	Phase string `json:"phase"`
+
+	// DatabaseBackup which has been created before the upgrade
+	BackupName string `json:"backupName"`
+
+	// Persistent volume of the previous data directory of major upgrades which is retained until the dump has been restored
+	PreviousVolumeName string `json:"previousVolumeName,omitempty"`
+}
+
+// Note: The operator probes the endpoint like a client. It connects, completes the TLS handshake if the database has a
+// CA certificate and runs SELECT 1 as the user of the spec.
+type DatabaseConnectivity struct {
+	LastProbeTime metav1.Time `json:"lastProbeTime"`
+
+	LastSuccessTime *metav1.Time `json:"lastSuccessTime,omitempty"`
+
+	// Duration of the last successful probe from connecting until the result of the query has been received
+	Latency *metav1.Duration `json:"latency,omitempty"`
+}
+
+type DatabaseEndpoint struct {
+	Host string `json:"host"`
+	Port int32  `json:"port"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Reachable",type=string,JSONPath=`.status.conditions[?(@.type=="Reachable")].status`
+//+kubebuilder:printcolumn:name="Host",type=string,JSONPath=`.status.endpoint.host`
+//+kubebuilder:printcolumn:name="Port",type=integer,JSONPath=`.status.endpoint.port`
+//+kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.version`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+type Database struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseSpec   `json:"spec,omitempty"`
+	Status DatabaseStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+type DatabaseList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Database `json:"items"`
+}
+
+func (database *Database) GetConditions() []metav1.Condition {
+	return database.Status.Conditions
+}
+
+func (database *Database) SetConditions(conditions []metav1.Condition) {
+	database.Status.Conditions = conditions
+}
+
+const EnginePostgreSQL = "postgresql"
+const EngineMySQL = "mysql"
+
+// Note: Users with these names are created by the engines and are managed by the operator
+const PostgreSQLAdminUser = "postgres"
+const MySQLAdminUser = "root"
+
+var reservedUsernamePrefixes = []string{"pg_", "mysql."}
+
+// GetEngine returns the engine of the database, databases which have been created without engine use PostgreSQL
+func (database *Database) GetEngine() string {
+	if database.Spec.Engine == "" {
+		return EnginePostgreSQL
+	}
+	return database.Spec.Engine
+}
+
+// Note: Images are tagged with the versions
+const PostgreSQLDefaultVersion = "14"
+const MySQLDefaultVersion = "8.0"
+
+// GetDefaultVersion returns the version which is used for databases of the engine which don't define a version
+func GetDefaultVersion(engine string) string {
+	if engine == EngineMySQL {
+		return MySQLDefaultVersion
+	}
+	return PostgreSQLDefaultVersion
+}
+
+const DefaultStorageSize = "1Gi"
+
+// GetStorageSize returns the storage size of the database, databases which have been created without size use 1Gi
+func (database *Database) GetStorageSize() resource.Quantity {
+	if database.Spec.StorageSize == nil {
+		return resource.MustParse(DefaultStorageSize)
+	}
+	return *database.Spec.StorageSize
+}
+
+const UpgradeStrategyRollingRestart = "RollingRestart"
+const UpgradeStrategyDumpRestore = "DumpRestore"
+
+const UpgradePhaseBackingUp = "BackingUp"
+const UpgradePhaseRestarting = "Restarting"
+const UpgradePhaseReplacing = "Replacing"
+const UpgradePhaseRestoring = "Restoring"
+
+const PasswordSecretKey = "password"
+const CACertificateSecretKey = "ca.crt"
+
+// Note: Secrets which the operator generates are labeled with the name of the database
+const GeneratedSecretLabel = "database.sample.third.party/database"
+
+// Note: Plaintext credentials of databases which are written via v1alpha1 are stored in this annotation by the
+// conversion. The operator moves them into the generated secrets and removes the annotation.
+const InlineCredentialsAnnotation = "database.sample.third.party/inline-credentials"
+
+type InlineCredentials struct {
+	Password    string `json:"password,omitempty"`
+	Certificate string `json:"certificate,omitempty"`
+}
+
+// GetGeneratedPasswordSecretName returns the name of the secret with the generated password
+func GetGeneratedPasswordSecretName(database *Database) string {
+	return database.Name + "-credentials"
+}
+
+// GetGeneratedTLSSecretName returns the name of the secret with the CA certificate of databases created via v1alpha1
+func GetGeneratedTLSSecretName(database *Database) string {
+	return database.Name + "-tls"
+}
+
+// GetPasswordSecretRef returns the referenced password secret or the secret with the generated password
+func (database *Database) GetPasswordSecretRef() corev1.SecretKeySelector {
+	if database.Spec.PasswordSecretRef != nil {
+		return *database.Spec.PasswordSecretRef
+	}
+	return corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: GetGeneratedPasswordSecretName(database)},
+		Key:                  PasswordSecretKey,
+	}
+}
+
+func init() {
+	SchemeBuilder.Register(&Database{}, &DatabaseList{})
+}
@@ -0,0 +1,220 @@
+package v1beta1
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Note: All problems are returned at once so that users don't have to fix them one by one
+func (r *Database) validateDatabase() error {
+	errorList := ValidateInlineCredentialsAnnotation(r.Annotations, nil)
+	return r.toError(append(errorList, r.ValidateFields()...))
+}
+
+func (r *Database) validateDatabaseUpdate(oldDatabase *Database) error {
+	errorList := ValidateInlineCredentialsAnnotation(r.Annotations, oldDatabase.Annotations)
+	return r.toError(append(errorList, r.ValidateUpdatedFields(oldDatabase)...))
+}
+
+func (r *Database) toError(errorList field.ErrorList) error {
+	if len(errorList) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Database").GroupKind(), r.Name, errorList)
+}
+
+// ValidateFields checks the fields of new databases. It is also used by the webhook of v1alpha1.
+func (r *Database) ValidateFields() field.ErrorList {
+	var errorList field.ErrorList
+	specPath := field.NewPath("spec")
+	errorList = append(errorList, ValidateUsername(specPath.Child("user"), r.Spec.User)...)
+	errorList = append(errorList, ValidateUrl(specPath.Child("url"), r.Spec.Url)...)
+	errorList = append(errorList, r.validateStorageSize()...)
+	errorList = append(errorList, r.validateTLSSecret()...)
+	return errorList
+}
+
+// ValidateUpdatedFields checks the changes of databases. It is also used by the webhook of v1alpha1, since fields which
+// don't exist in v1alpha1 can be changed via the annotation of the conversion.
+// Note: The user, the URL and the certificate are only validated if they have changed, so that databases which have been
+// created before the validation existed can still be updated, e.g. when the operator adds finalizers
+func (r *Database) ValidateUpdatedFields(oldDatabase *Database) field.ErrorList {
+	var errorList field.ErrorList
+	specPath := field.NewPath("spec")
+	if r.Spec.User != oldDatabase.Spec.User {
+		errorList = append(errorList, ValidateUsername(specPath.Child("user"), r.Spec.User)...)
+	}
+	if r.Spec.Url != oldDatabase.Spec.Url {
+		errorList = append(errorList, ValidateUrl(specPath.Child("url"), r.Spec.Url)...)
+	}
+	errorList = append(errorList, r.validateStorageSize()...)
+	errorList = append(errorList, r.validateImmutableFields(oldDatabase)...)
+	errorList = append(errorList, r.validateVersionUpgrade(oldDatabase)...)
+	if getTLSSecretName(r) != getTLSSecretName(oldDatabase) {
+		errorList = append(errorList, r.validateTLSSecret()...)
+	}
+	return errorList
+}
+
+// ValidateInlineCredentialsAnnotation rejects inline credentials which are passed in the annotation by clients
+// Note: Only the conversion of v1alpha1 sets the annotation. Databases which were stored with inline credentials keep it
+// until the operator has moved them into the generated secrets, so updates which don't change the value are accepted.
+func ValidateInlineCredentialsAnnotation(annotations map[string]string, oldAnnotations map[string]string) field.ErrorList {
+	value, ok := annotations[InlineCredentialsAnnotation]
+	if !ok {
+		return nil
+	}
+	if oldValue, ok := oldAnnotations[InlineCredentialsAnnotation]; ok && oldValue == value {
+		return nil
+	}
+	return field.ErrorList{field.Forbidden(field.NewPath("metadata", "annotations").Key(InlineCredentialsAnnotation),
+		"is set by the conversion of v1alpha1 only, use spec.passwordSecretRef and spec.tlsSecretRef instead")}
+}
+
+// ValidateUsername checks that the user is set and is neither an admin user of the engines nor reserved by them
+// Note: The admin users of both engines are rejected, since the engine can only be changed by restoring a backup
+func ValidateUsername(path *field.Path, username string) field.ErrorList {
+	var errorList field.ErrorList
+	if username == "" {
+		return append(errorList, field.Required(path, "the user which owns the database must be set"))
+	}
+	if username == PostgreSQLAdminUser || username == MySQLAdminUser {
+		return append(errorList, field.Forbidden(path, "the admin user "+username+" of the engine cannot be used"))
+	}
+	for _, prefix := range reservedUsernamePrefixes {
+		if strings.HasPrefix(username, prefix) {
+			return append(errorList, field.Forbidden(path, "names with the prefix "+prefix+" are reserved by the engine"))
+		}
+	}
+	return errorList
+}
+
+// ValidateUrl checks that the URL is absolute, e.g. postgresql://database.database.svc:5432/database
+func ValidateUrl(path *field.Path, databaseUrl string) field.ErrorList {
+	var errorList field.ErrorList
+	if databaseUrl == "" {
+		return errorList
+	}
+	parsedUrl, err := url.Parse(databaseUrl)
+	if err != nil || parsedUrl.Scheme == "" || parsedUrl.Host == "" {
+		errorList = append(errorList, field.Invalid(path, databaseUrl, "must be an absolute URL, e.g. postgresql://host:5432/database"))
+	}
+	return errorList
+}
+
+// ParseCACertificates checks that the certificate contains only PEM encoded X.509 certificates
+func ParseCACertificates(certificate []byte) error {
+	rest := certificate
+	amountCertificates := 0
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return fmt.Errorf("contains a PEM block of type %s instead of CERTIFICATE", block.Type)
+		}
+		_, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("contains a certificate which cannot be parsed: %v", err)
+		}
+		amountCertificates++
+	}
+	if amountCertificates == 0 {
+		return fmt.Errorf("must contain at least one PEM encoded certificate")
+	}
+	return nil
+}
+
+func (r *Database) validateStorageSize() field.ErrorList {
+	var errorList field.ErrorList
+	if r.Spec.StorageSize != nil && r.Spec.StorageSize.Sign() <= 0 {
+		errorList = append(errorList, field.Invalid(field.NewPath("spec", "storageSize"), r.Spec.StorageSize.String(), "must be greater than zero"))
+	}
+	return errorList
+}
+
+// Note: The data volume is created with the engine and the size of the first version of the spec. Databases which
+// have been created without engine or size use the defaults, so setting the defaults explicitly is not a change.
+func (r *Database) validateImmutableFields(oldDatabase *Database) field.ErrorList {
+	var errorList field.ErrorList
+	specPath := field.NewPath("spec")
+	if r.GetEngine() != oldDatabase.GetEngine() {
+		errorList = append(errorList, field.Forbidden(specPath.Child("engine"),
+			"cannot be changed from "+oldDatabase.GetEngine()+" to "+r.GetEngine()+", create a new database and restore a backup instead"))
+	}
+	oldStorageSize := oldDatabase.GetStorageSize()
+	storageSize := r.GetStorageSize()
+	if storageSize.Cmp(oldStorageSize) != 0 {
+		errorList = append(errorList, field.Forbidden(specPath.Child("storageSize"),
+			"cannot be changed from "+oldStorageSize.String()+" to "+storageSize.String()))
+	}
+	return errorList
+}
+
+// Note: Versions can be set back to the running version, so that upgrades which haven't touched the data can be canceled
+func (r *Database) validateVersionUpgrade(oldDatabase *Database) field.ErrorList {
+	var errorList field.ErrorList
+	if r.Spec.Version == "" {
+		return errorList
+	}
+	path := field.NewPath("spec", "version")
+	_, err := ParseVersion(r.Spec.Version)
+	if err != nil {
+		return append(errorList, field.Invalid(path, r.Spec.Version, err.Error()))
+	}
+	if r.Spec.Version == oldDatabase.Status.Version {
+		return errorList
+	}
+	for _, version := range []string{oldDatabase.Spec.Version, oldDatabase.Status.Version} {
+		comparison, err := CompareVersions(r.Spec.Version, version)
+		if err == nil && comparison < 0 {
+			return append(errorList, field.Forbidden(path, "version cannot be downgraded from "+version+" to "+r.Spec.Version))
+		}
+	}
+	return errorList
+}
+
+// Note: Secrets which don't exist yet are not validated, since they can be created after the database
+func (r *Database) validateTLSSecret() field.ErrorList {
+	var errorList field.ErrorList
+	secretName := getTLSSecretName(r)
+	if secretName == "" || webhookReader == nil {
+		return errorList
+	}
+	path := field.NewPath("spec", "tlsSecretRef", "name")
+	secret := &corev1.Secret{}
+	err := webhookReader.Get(context.Background(), types.NamespacedName{Name: secretName, Namespace: r.Namespace}, secret)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			errorList = append(errorList, field.InternalError(path, err))
+		}
+		return errorList
+	}
+	certificate, ok := secret.Data[CACertificateSecretKey]
+	if !ok {
+		return append(errorList, field.Invalid(path, secretName, "secret has no key "+CACertificateSecretKey))
+	}
+	err = ParseCACertificates(certificate)
+	if err != nil {
+		errorList = append(errorList, field.Invalid(path, secretName, "key "+CACertificateSecretKey+" "+err.Error()))
+	}
+	return errorList
+}
+
+func getTLSSecretName(database *Database) string {
+	if database.Spec.TLSSecretRef == nil {
+		return ""
+	}
+	return database.Spec.TLSSecretRef.Name
+}
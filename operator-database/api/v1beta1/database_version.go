@@ -0,0 +1,49 @@
+package v1beta1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseVersion returns the numeric components of versions like '14', '14.10' or '8.0.36'
+func ParseVersion(version string) ([]int, error) {
+	components := []int{}
+	for _, component := range strings.Split(version, ".") {
+		number, err := strconv.Atoi(component)
+		if err != nil || number < 0 {
+			return nil, fmt.Errorf("version %s is not valid", version)
+		}
+		components = append(components, number)
+	}
+	return components, nil
+}
+
+// CompareVersions returns -1 if a is lower than b, 0 if both are equal and 1 if a is higher than b.
+// Note: Missing components are compared as 0, e.g. '8' and '8.0' are equal.
+func CompareVersions(a string, b string) (int, error) {
+	componentsA, err := ParseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	componentsB, err := ParseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < len(componentsA) || i < len(componentsB); i++ {
+		componentA, componentB := 0, 0
+		if i < len(componentsA) {
+			componentA = componentsA[i]
+		}
+		if i < len(componentsB) {
+			componentB = componentsB[i]
+		}
+		if componentA < componentB {
+			return -1, nil
+		}
+		if componentA > componentB {
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,48 @@
+package v1beta1
+
+import (
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	for _, test := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"14", "14", 0},
+		{"8", "8.0", 0},
+		{"14", "15", -1},
+		{"14.10", "14.9", 1},
+		{"8.0.36", "8.4", -1},
+	} {
+		comparison, err := CompareVersions(test.a, test.b)
+		if err != nil || comparison != test.expected {
+			t.Errorf("CompareVersions(%s, %s): expected %d, got %d (%v)", test.a, test.b, test.expected, comparison, err)
+		}
+	}
+	if _, err := CompareVersions("latest", "14"); err == nil {
+		t.Error("expected non-numeric versions to be rejected")
+	}
+}
+
+func TestDowngradesAreRejected(t *testing.T) {
+	old := &Database{Spec: DatabaseSpec{Version: "15"}, Status: DatabaseStatus{Version: "14"}}
+	for _, test := range []struct {
+		version string
+		valid   bool
+	}{
+		{"", true},
+		{"15", true},
+		{"16", true},
+		{"14", true},
+		{"14.10", false},
+		{"13", false},
+	} {
+		database := old.DeepCopy()
+		database.Spec.Version = test.version
+		err := database.ValidateUpdate(old)
+		if (err == nil) != test.valid {
+			t.Errorf("version %q: expected valid=%v, got error %v", test.version, test.valid, err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+var databaselog = logf.Log.WithName("database-resource")
+
+// Note: The webhook functions don't get a client passed in, so the reader of the manager is stored
+var webhookReader client.Reader
+
+func (r *Database) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	webhookReader = mgr.GetAPIReader()
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// Note: Only requests which use v1beta1 are defaulted. Databases which are created via v1alpha1 get the same values
+// from the getters of the types, e.g. GetEngine and GetStorageSize.
+//+kubebuilder:webhook:path=/mutate-database-sample-third-party-v1beta1-database,mutating=true,failurePolicy=fail,matchPolicy=Exact,sideEffects=None,groups=database.sample.third.party,resources=databases,verbs=create;update,versions=v1beta1,name=mdatabase.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &Database{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *Database) Default() {
+	databaselog.Info("default", "name", r.Name)
+	r.applyDefaults()
+}
+
+//+kubebuilder:webhook:path=/validate-database-sample-third-party-v1beta1-database,mutating=false,failurePolicy=fail,matchPolicy=Exact,sideEffects=None,groups=database.sample.third.party,resources=databases,verbs=create;update,versions=v1beta1,name=vdatabase.v1beta1.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &Database{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Database) ValidateCreate() error {
+	databaselog.Info("validate create", "name", r.Name)
+
+	return r.validateDatabase()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Database) ValidateUpdate(old runtime.Object) error {
+	databaselog.Info("validate update", "name", r.Name)
+
+	oldDatabase, ok := old.(*Database)
+	if !ok {
+		return r.validateDatabase()
+	}
+	return r.validateDatabaseUpdate(oldDatabase)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Database) ValidateDelete() error {
+	return nil
+}
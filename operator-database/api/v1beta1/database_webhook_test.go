@@ -0,0 +1,198 @@
+package v1beta1
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func validDatabase(name string) *Database {
+	return &Database{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: DatabaseSpec{
+			User: "name",
+			Url:  "postgresql://" + name + ".default.svc:5432/database",
+		},
+	}
+}
+
+func tlsSecret(name string, certificate []byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Data:       map[string][]byte{CACertificateSecretKey: certificate},
+	}
+}
+
+func generateCACertificate() []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "database-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate})
+}
+
+var _ = Describe("Database defaulting webhook", func() {
+	table.DescribeTable("defaults the engine, the version and the storage size",
+		func(name string, engine string, expectedVersion string) {
+			database := validDatabase(name)
+			database.Spec.Engine = engine
+			Expect(k8sClient.Create(ctx, database)).To(Succeed())
+			defer k8sClient.Delete(ctx, database)
+
+			created := &Database{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(database), created)).To(Succeed())
+			Expect(created.GetEngine()).To(Equal(database.GetEngine()))
+			Expect(created.Spec.Version).To(Equal(expectedVersion))
+			Expect(created.Spec.StorageSize).NotTo(BeNil())
+			Expect(created.Spec.StorageSize.String()).To(Equal(DefaultStorageSize))
+		},
+		table.Entry("without engine", "default-engine", "", PostgreSQLDefaultVersion),
+		table.Entry("mysql", "default-mysql", EngineMySQL, MySQLDefaultVersion),
+	)
+
+	It("keeps explicit values", func() {
+		database := validDatabase("explicit")
+		storageSize := resource.MustParse("5Gi")
+		database.Spec.Version = "15"
+		database.Spec.StorageSize = &storageSize
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		defer k8sClient.Delete(ctx, database)
+
+		created := &Database{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(database), created)).To(Succeed())
+		Expect(created.Spec.Version).To(Equal("15"))
+		Expect(created.Spec.StorageSize.String()).To(Equal("5Gi"))
+	})
+})
+
+var _ = Describe("Database validating webhook", func() {
+	table.DescribeTable("validates databases on creation",
+		func(name string, mutate func(*Database), expectedErrors []string) {
+			database := validDatabase(name)
+			mutate(database)
+
+			err := k8sClient.Create(ctx, database)
+			if len(expectedErrors) == 0 {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(k8sClient.Delete(ctx, database)).To(Succeed())
+				return
+			}
+			Expect(err).To(HaveOccurred())
+			for _, expectedError := range expectedErrors {
+				Expect(err.Error()).To(ContainSubstring(expectedError))
+			}
+		},
+		table.Entry("valid database", "valid", func(database *Database) {}, nil),
+		table.Entry("admin user", "admin-user", func(database *Database) {
+			database.Spec.User = PostgreSQLAdminUser
+		}, []string{"spec.user", "admin user"}),
+		table.Entry("reserved user", "reserved-user", func(database *Database) {
+			database.Spec.User = "pg_monitor"
+		}, []string{"spec.user", "reserved"}),
+		table.Entry("relative url", "relative-url", func(database *Database) {
+			database.Spec.Url = "database:5432"
+		}, []string{"spec.url", "must be an absolute URL"}),
+		table.Entry("zero storage size", "zero-storage", func(database *Database) {
+			storageSize := resource.MustParse("0")
+			database.Spec.StorageSize = &storageSize
+		}, []string{"spec.storageSize", "must be greater than zero"}),
+		table.Entry("all problems at once", "all-problems", func(database *Database) {
+			storageSize := resource.MustParse("-1Gi")
+			database.Spec.Url = "url"
+			database.Spec.StorageSize = &storageSize
+		}, []string{"spec.url", "spec.storageSize"}),
+		table.Entry("tls secret which doesn't exist yet", "missing-tls", func(database *Database) {
+			database.Spec.TLSSecretRef = &corev1.LocalObjectReference{Name: "missing-tls"}
+		}, nil),
+		table.Entry("inline credentials annotation", "inline-credentials", func(database *Database) {
+			database.Annotations = map[string]string{InlineCredentialsAnnotation: `{"password":"secret"}`}
+		}, []string{InlineCredentialsAnnotation, "conversion of v1alpha1"}),
+	)
+
+	It("parses the CA certificate of the tls secret", func() {
+		valid := tlsSecret("valid-tls", generateCACertificate())
+		invalid := tlsSecret("invalid-tls", []byte("certificate"))
+		for _, secret := range []*corev1.Secret{valid, invalid} {
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+			defer k8sClient.Delete(ctx, secret)
+		}
+
+		database := validDatabase("invalid-tls")
+		database.Spec.TLSSecretRef = &corev1.LocalObjectReference{Name: invalid.Name}
+		err := k8sClient.Create(ctx, database)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.tlsSecretRef.name"))
+
+		database.Spec.TLSSecretRef.Name = valid.Name
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, database)).To(Succeed())
+	})
+
+	table.DescribeTable("validates databases on update",
+		func(name string, mutate func(*Database), expectedErrors []string) {
+			database := validDatabase(name)
+			Expect(k8sClient.Create(ctx, database)).To(Succeed())
+			defer k8sClient.Delete(ctx, database)
+
+			mutate(database)
+			err := k8sClient.Update(ctx, database)
+			if len(expectedErrors) == 0 {
+				Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			Expect(err).To(HaveOccurred())
+			for _, expectedError := range expectedErrors {
+				Expect(err.Error()).To(ContainSubstring(expectedError))
+			}
+		},
+		table.Entry("user changed", "update-user", func(database *Database) {
+			database.Spec.User = "other"
+		}, nil),
+		table.Entry("user changed to the admin user", "update-admin-user", func(database *Database) {
+			database.Spec.User = MySQLAdminUser
+		}, []string{"spec.user", "admin user"}),
+		table.Entry("version upgraded", "update-version", func(database *Database) {
+			database.Spec.Version = "15"
+		}, nil),
+		table.Entry("version downgraded", "downgrade-version", func(database *Database) {
+			database.Spec.Version = "13"
+		}, []string{"spec.version", "cannot be downgraded"}),
+		table.Entry("engine changed", "update-engine", func(database *Database) {
+			database.Spec.Engine = EngineMySQL
+			database.Spec.Version = MySQLDefaultVersion
+		}, []string{"spec.engine", "cannot be changed"}),
+		table.Entry("inline credentials annotation added", "update-inline-credentials", func(database *Database) {
+			database.Annotations = map[string]string{InlineCredentialsAnnotation: `{"password":"secret"}`}
+		}, []string{InlineCredentialsAnnotation, "conversion of v1alpha1"}),
+		table.Entry("storage size changed", "update-storage", func(database *Database) {
+			storageSize := resource.MustParse("2Gi")
+			database.Spec.StorageSize = &storageSize
+		}, []string{"spec.storageSize", "cannot be changed from 1Gi to 2Gi"}),
+	)
+})
@@ -0,0 +1,108 @@
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type DatabaseBackupSpec struct {
+	// Name of the database in the same namespace which is backed up
+	DatabaseName string `json:"databaseName"`
+
+	// Target which the dumps are written to
+	Target BackupTarget `json:"target"`
+
+	// Cron schedule, e.g. '0 2 * * *'. If set, a cron job creates backups regularly, otherwise one backup is created.
+	Schedule string `json:"schedule,omitempty"`
+
+	// Retention policy of the dumps of this backup
+	Retention *BackupRetention `json:"retention,omitempty"`
+}
+
+// Note: Exactly one target needs to be set
+type BackupTarget struct {
+	// Persistent volume claim in the same namespace. Dumps are stored in the directory '<backup name>'.
+	PersistentVolumeClaim *PersistentVolumeClaimTarget `json:"persistentVolumeClaim,omitempty"`
+
+	// S3-compatible object storage, e.g. MinIO. Dumps are stored under '<prefix><backup name>/'.
+	S3 *S3Target `json:"s3,omitempty"`
+}
+
+type PersistentVolumeClaimTarget struct {
+	ClaimName string `json:"claimName"`
+}
+
+type S3Target struct {
+	// URL of the endpoint, e.g. http://minio.minio.svc:9000
+	Endpoint string `json:"endpoint"`
+
+	Bucket string `json:"bucket"`
+
+	Prefix string `json:"prefix,omitempty"`
+
+	// Secret which contains the keys 'AWS_ACCESS_KEY_ID' and 'AWS_SECRET_ACCESS_KEY'
+	CredentialsSecretRef corev1.LocalObjectReference `json:"credentialsSecretRef"`
+}
+
+type BackupRetention struct {
+	// Number of dumps which are kept, older dumps are deleted after every backup
+	//+kubebuilder:validation:Minimum=1
+	KeepLast int32 `json:"keepLast"`
+}
+
+type DatabaseBackupStatus struct {
+	// Progressing, Succeeded and Scheduled
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Engine of the database, restores need to use the same engine
+	Engine string `json:"engine,omitempty"`
+
+	// Last dump which has been written successfully
+	LastBackup *BackupFile `json:"lastBackup,omitempty"`
+}
+
+type BackupFile struct {
+	// Name of the dump in the directory or under the prefix of the backup
+	Name string `json:"name"`
+
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Database",type=string,JSONPath=`.spec.databaseName`
+//+kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+//+kubebuilder:printcolumn:name="Succeeded",type=string,JSONPath=`.status.conditions[?(@.type=="Succeeded")].status`
+//+kubebuilder:printcolumn:name="Last Backup",type=string,JSONPath=`.status.lastBackup.name`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+type DatabaseBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseBackupSpec   `json:"spec,omitempty"`
+	Status DatabaseBackupStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+type DatabaseBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DatabaseBackup `json:"items"`
+}
+
+func (backup *DatabaseBackup) GetConditions() []metav1.Condition {
+	return backup.Status.Conditions
+}
+
+func (backup *DatabaseBackup) SetConditions(conditions []metav1.Condition) {
+	backup.Status.Conditions = conditions
+}
+
+const S3AccessKeyIDSecretKey = "AWS_ACCESS_KEY_ID"
+const S3SecretAccessKeySecretKey = "AWS_SECRET_ACCESS_KEY"
+
+func init() {
+	SchemeBuilder.Register(&DatabaseBackup{}, &DatabaseBackupList{})
+}
@@ -0,0 +1,64 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type DatabaseRestoreSpec struct {
+	// Name of the backup in the same namespace which is restored
+	BackupName string `json:"backupName"`
+
+	// Name of the dump of the backup. If not set, the last dump is restored.
+	BackupFile string `json:"backupFile,omitempty"`
+
+	// Name of the database in the same namespace which the dump is restored into
+	DatabaseName string `json:"databaseName"`
+
+	// Spec of the database which is created if it doesn't exist. If not set, the database needs to exist.
+	DatabaseTemplate *DatabaseSpec `json:"databaseTemplate,omitempty"`
+}
+
+type DatabaseRestoreStatus struct {
+	// Progressing and Succeeded
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Dump which is restored
+	BackupFile string `json:"backupFile,omitempty"`
+
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Backup",type=string,JSONPath=`.spec.backupName`
+//+kubebuilder:printcolumn:name="Database",type=string,JSONPath=`.spec.databaseName`
+//+kubebuilder:printcolumn:name="Succeeded",type=string,JSONPath=`.status.conditions[?(@.type=="Succeeded")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+type DatabaseRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseRestoreSpec   `json:"spec,omitempty"`
+	Status DatabaseRestoreStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+type DatabaseRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DatabaseRestore `json:"items"`
+}
+
+func (restore *DatabaseRestore) GetConditions() []metav1.Condition {
+	return restore.Status.Conditions
+}
+
+func (restore *DatabaseRestore) SetConditions(conditions []metav1.Condition) {
+	restore.Status.Conditions = conditions
+}
+
+func init() {
+	SchemeBuilder.Register(&DatabaseRestore{}, &DatabaseRestoreList{})
+}
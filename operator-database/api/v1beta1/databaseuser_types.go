@@ -0,0 +1,87 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Privilege on the tables of the database
+//+kubebuilder:validation:Enum=ALL;SELECT;INSERT;UPDATE;DELETE
+type Privilege string
+
+const PrivilegeAll Privilege = "ALL"
+const PrivilegeSelect Privilege = "SELECT"
+const PrivilegeInsert Privilege = "INSERT"
+const PrivilegeUpdate Privilege = "UPDATE"
+const PrivilegeDelete Privilege = "DELETE"
+
+type DatabaseUserSpec struct {
+	// Name of the database in the same namespace which the user is created in
+	DatabaseName string `json:"databaseName"`
+
+	// Name of the user in the database. The name cannot be changed.
+	//+kubebuilder:validation:Pattern=`^[a-z_][a-z0-9_]{0,31}$`
+	Username string `json:"username"`
+
+	// Privileges which are granted on all tables of the database
+	//+kubebuilder:validation:MinItems=1
+	Privileges []Privilege `json:"privileges"`
+
+	// Secret in the same namespace which the operator writes the credentials and connection details to
+	SecretName string `json:"secretName"`
+}
+
+type DatabaseUserStatus struct {
+	// Ready
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Generation of the spec which the status has been computed for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Name of the user which has been created in the database
+	Username string `json:"username,omitempty"`
+
+	// Value of the annotation 'database.sample.third.party/rotate-password' which has been handled last
+	PasswordRotation string `json:"passwordRotation,omitempty"`
+
+	// Time when the password has been generated
+	PasswordUpdateTime *metav1.Time `json:"passwordUpdateTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Database",type=string,JSONPath=`.spec.databaseName`
+//+kubebuilder:printcolumn:name="Username",type=string,JSONPath=`.spec.username`
+//+kubebuilder:printcolumn:name="Secret",type=string,JSONPath=`.spec.secretName`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+type DatabaseUser struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseUserSpec   `json:"spec,omitempty"`
+	Status DatabaseUserStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+type DatabaseUserList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DatabaseUser `json:"items"`
+}
+
+func (user *DatabaseUser) GetConditions() []metav1.Condition {
+	return user.Status.Conditions
+}
+
+func (user *DatabaseUser) SetConditions(conditions []metav1.Condition) {
+	user.Status.Conditions = conditions
+}
+
+// Note: The password is rotated when the value of this annotation changes, e.g. to the current time
+const RotatePasswordAnnotation = "database.sample.third.party/rotate-password"
+
+func init() {
+	SchemeBuilder.Register(&DatabaseUser{}, &DatabaseUserList{})
+}
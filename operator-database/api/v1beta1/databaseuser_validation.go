@@ -0,0 +1,39 @@
+package v1beta1
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Note: The database may not exist yet, then the owner is checked by the operator before the user is created
+func (r *DatabaseUser) validateDatabaseUser() error {
+	var database *Database
+	if webhookReader != nil {
+		database = &Database{}
+		err := webhookReader.Get(context.Background(), types.NamespacedName{Name: r.Spec.DatabaseName, Namespace: r.Namespace}, database)
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return apierrors.NewInternalError(err)
+			}
+			database = nil
+		}
+	}
+	errorList := ValidateDatabaseUsername(field.NewPath("spec", "username"), r.Spec.Username, database)
+	if len(errorList) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("DatabaseUser").GroupKind(), r.Name, errorList)
+}
+
+// ValidateDatabaseUsername checks that the user is neither an admin user of the engines, reserved by them nor the user
+// which owns the database. The owner is not checked if the database is nil.
+func ValidateDatabaseUsername(path *field.Path, username string, database *Database) field.ErrorList {
+	errorList := ValidateUsername(path, username)
+	if len(errorList) == 0 && database != nil && username == database.Spec.User {
+		errorList = append(errorList, field.Forbidden(path, "the user "+username+" owns the database "+database.Name+" and cannot be managed by a database user"))
+	}
+	return errorList
+}
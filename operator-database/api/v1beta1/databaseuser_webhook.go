@@ -0,0 +1,44 @@
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+var databaseuserlog = logf.Log.WithName("databaseuser-resource")
+
+func (r *DatabaseUser) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	webhookReader = mgr.GetAPIReader()
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-database-sample-third-party-v1beta1-databaseuser,mutating=false,failurePolicy=fail,matchPolicy=Exact,sideEffects=None,groups=database.sample.third.party,resources=databaseusers,verbs=create;update,versions=v1beta1,name=vdatabaseuser.v1beta1.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &DatabaseUser{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *DatabaseUser) ValidateCreate() error {
+	databaseuserlog.Info("validate create", "name", r.Name)
+
+	return r.validateDatabaseUser()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *DatabaseUser) ValidateUpdate(old runtime.Object) error {
+	databaseuserlog.Info("validate update", "name", r.Name)
+
+	oldUser, ok := old.(*DatabaseUser)
+	if ok && oldUser.Spec.Username == r.Spec.Username && oldUser.Spec.DatabaseName == r.Spec.DatabaseName {
+		return nil
+	}
+	return r.validateDatabaseUser()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *DatabaseUser) ValidateDelete() error {
+	return nil
+}
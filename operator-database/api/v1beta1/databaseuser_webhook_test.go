@@ -0,0 +1,51 @@
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func validDatabaseUser(name string) *DatabaseUser {
+	return &DatabaseUser{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: DatabaseUserSpec{
+			DatabaseName: "owned",
+			Username:     "reader",
+			Privileges:   []Privilege{PrivilegeSelect},
+			SecretName:   name + "-connection",
+		},
+	}
+}
+
+var _ = Describe("DatabaseUser validating webhook", func() {
+	table.DescribeTable("validates database users on creation",
+		func(name string, username string, expectedErrors []string) {
+			database := validDatabase("owned")
+			Expect(k8sClient.Create(ctx, database)).To(Succeed())
+			defer k8sClient.Delete(ctx, database)
+
+			user := validDatabaseUser(name)
+			user.Spec.Username = username
+			err := k8sClient.Create(ctx, user)
+			if len(expectedErrors) == 0 {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(k8sClient.Delete(ctx, user)).To(Succeed())
+				return
+			}
+			Expect(err).To(HaveOccurred())
+			for _, expectedError := range expectedErrors {
+				Expect(err.Error()).To(ContainSubstring(expectedError))
+			}
+		},
+		table.Entry("valid user", "valid-user", "reader", nil),
+		table.Entry("admin user", "admin-user", MySQLAdminUser, []string{"spec.username", "admin user"}),
+		table.Entry("reserved user", "reserved-user", "pg_read_all_data", []string{"spec.username", "reserved"}),
+		table.Entry("owner of the database", "owner-user", "name", []string{"spec.username", "owns the database owned"}),
+	)
+})
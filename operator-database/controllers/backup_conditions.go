@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Note: Progressing is True while a backup or restore job is running
+const CONDITION_TYPE_PROGRESSING = "Progressing"
+const CONDITION_REASON_JOB_RUNNING = "JobRunning"
+const CONDITION_MESSAGE_BACKUP_RUNNING = "Backup job is running"
+const CONDITION_MESSAGE_RESTORE_RUNNING = "Restore job is running"
+const CONDITION_REASON_JOB_NOT_RUNNING = "JobNotRunning"
+const CONDITION_MESSAGE_JOB_NOT_RUNNING = "No job is running"
+const CONDITION_REASON_WAITING_FOR_DATABASE = "WaitingForDatabase"
+const CONDITION_MESSAGE_WAITING_FOR_DATABASE = "Database doesn't accept connections yet"
+const CONDITION_REASON_WAITING_FOR_BACKUP = "WaitingForBackup"
+const CONDITION_MESSAGE_WAITING_FOR_BACKUP = "Backup has no dump yet"
+
+// Note: Succeeded is True if the last backup or the restore succeeded and False if it failed after all retries
+const CONDITION_TYPE_SUCCEEDED = "Succeeded"
+const CONDITION_REASON_BACKUP_SUCCEEDED = "BackupSucceeded"
+const CONDITION_REASON_BACKUP_FAILED = "BackupFailed"
+const CONDITION_MESSAGE_BACKUP_FAILED = "Backup job failed, see the logs of the job"
+const CONDITION_REASON_RESTORE_SUCCEEDED = "RestoreSucceeded"
+const CONDITION_MESSAGE_RESTORE_SUCCEEDED = "Dump has been restored"
+const CONDITION_REASON_RESTORE_FAILED = "RestoreFailed"
+const CONDITION_MESSAGE_RESTORE_FAILED = "Restore job failed, see the logs of the job"
+const CONDITION_REASON_NO_RESULT = "NoResult"
+const CONDITION_MESSAGE_NO_RESULT = "No job has finished yet"
+const CONDITION_REASON_ENGINE_MISMATCH = "EngineMismatch"
+
+// Note: Scheduled is True if backups are created regularly by a cron job
+const CONDITION_TYPE_SCHEDULED = "Scheduled"
+const CONDITION_REASON_SCHEDULED = "CronJobCreated"
+const CONDITION_MESSAGE_SCHEDULED = "Backups are created by a cron job"
+
+// Note: Errors which stop jobs from being created are reported in the condition Progressing
+const CONDITION_REASON_RECONCILE_FAILED = "ReconcileFailed"
+
+func setJobCondition(conditions *[]metav1.Condition, generation int64,
+	typeName string, status metav1.ConditionStatus, reason string, message string) {
+
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               typeName,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: generation,
+	})
+}
@@ -0,0 +1,262 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// Note: Cron jobs are updated when the generation of the backup differs from the one in this annotation
+const backupGenerationAnnotation = "database.sample.third.party/backup-generation"
+
+type DatabaseBackupReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Note: Providers of the engines, see NewProviders. If not set, the providers of all supported engines are used.
+	Providers map[string]Provider
+}
+
+//+kubebuilder:rbac:groups=database.sample.third.party,resources=databasebackups,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=database.sample.third.party,resources=databasebackups/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=database.sample.third.party,resources=databasebackups/finalizers,verbs=update
+//+kubebuilder:rbac:groups=database.sample.third.party,resources=databases,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
+func (r *DatabaseBackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	log.Info("Reconcile started")
+
+	backup := &databasesamplev1beta1.DatabaseBackup{}
+	err := r.Get(ctx, req.NamespacedName, backup)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("DatabaseBackup resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		log.Info("Failed to get database backup resource. Re-running reconcile.")
+		return ctrl.Result{}, err
+	}
+
+	original := backup.DeepCopy()
+	err = r.reconcileBackupJobs(ctx, backup)
+	jobsErr := r.setBackupStatus(ctx, backup, err)
+	statusErr := r.updateBackupStatus(ctx, original, backup)
+	for _, e := range []error{err, jobsErr, statusErr} {
+		if e != nil {
+			return ctrl.Result{}, e
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+func validateBackupTarget(backup *databasesamplev1beta1.DatabaseBackup) error {
+	target := backup.Spec.Target
+	if (target.PersistentVolumeClaim == nil) == (target.S3 == nil) {
+		return fmt.Errorf("exactly one target, persistentVolumeClaim or s3, needs to be set")
+	}
+	return nil
+}
+
+func (r *DatabaseBackupReconciler) reconcileBackupJobs(ctx context.Context, backup *databasesamplev1beta1.DatabaseBackup) error {
+	err := validateBackupTarget(backup)
+	if err != nil {
+		return err
+	}
+	database := &databasesamplev1beta1.Database{}
+	err = r.Get(ctx, types.NamespacedName{Name: backup.Spec.DatabaseName, Namespace: backup.Namespace}, database)
+	if err != nil {
+		return fmt.Errorf("database %s cannot be read: %v", backup.Spec.DatabaseName, err)
+	}
+	provider, err := getProvider(r.Providers, database)
+	if err != nil {
+		return err
+	}
+	if backup.Status.Engine != "" && backup.Status.Engine != database.GetEngine() {
+		return fmt.Errorf("dumps of engine %s cannot be mixed with dumps of engine %s", database.GetEngine(), backup.Status.Engine)
+	}
+	backup.Status.Engine = database.GetEngine()
+
+	hooks := provider.BackupHooks(database)
+	if backup.Spec.Schedule == "" {
+		return r.reconcileBackupJob(ctx, backup, hooks)
+	}
+	return r.reconcileBackupCronJob(ctx, backup, hooks)
+}
+
+// Note: Backups without schedule create one dump. To create another dump, the backup needs to be re-created.
+func (r *DatabaseBackupReconciler) reconcileBackupJob(ctx context.Context, backup *databasesamplev1beta1.DatabaseBackup, hooks *BackupHooks) error {
+	log := log.FromContext(ctx)
+	jobName := getBackupJobName(backup)
+	job := &batchv1.Job{}
+	err := r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: backup.Namespace}, job)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("Job resource " + jobName + " not found. Creating or re-creating job")
+			err = r.Create(ctx, r.defineBackupJob(backup, hooks))
+			if err != nil {
+				log.Info("Failed to create job resource. Re-running reconcile.")
+				return err
+			}
+			return nil
+		}
+		log.Info("Failed to get job resource " + jobName + ". Re-running reconcile.")
+		return err
+	}
+	return nil
+}
+
+func (r *DatabaseBackupReconciler) reconcileBackupCronJob(ctx context.Context, backup *databasesamplev1beta1.DatabaseBackup, hooks *BackupHooks) error {
+	log := log.FromContext(ctx)
+	cronJobName := getBackupJobName(backup)
+	definition := r.defineBackupCronJob(backup, hooks)
+	definition.Annotations = map[string]string{backupGenerationAnnotation: strconv.FormatInt(backup.Generation, 10)}
+	cronJob := &batchv1.CronJob{}
+	err := r.Get(ctx, types.NamespacedName{Name: cronJobName, Namespace: backup.Namespace}, cronJob)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("CronJob resource " + cronJobName + " not found. Creating or re-creating cron job")
+			err = r.Create(ctx, definition)
+			if err != nil {
+				log.Info("Failed to create cron job resource. Re-running reconcile.")
+				return err
+			}
+			return nil
+		}
+		log.Info("Failed to get cron job resource " + cronJobName + ". Re-running reconcile.")
+		return err
+	}
+
+	if cronJob.Annotations[backupGenerationAnnotation] != definition.Annotations[backupGenerationAnnotation] {
+		log.Info("CronJob resource " + cronJobName + " is out of date. Updating cron job")
+		cronJob.Annotations = definition.Annotations
+		cronJob.Spec = definition.Spec
+		err = r.Update(ctx, cronJob)
+		if err != nil {
+			log.Info("Failed to update cron job resource. Re-running reconcile.")
+			return err
+		}
+	}
+	return nil
+}
+
+// Note: The status is computed from the jobs of the backup, i.e. the job or the jobs created by the cron job
+func (r *DatabaseBackupReconciler) setBackupStatus(ctx context.Context, backup *databasesamplev1beta1.DatabaseBackup, reconcileErr error) error {
+	jobs := &batchv1.JobList{}
+	err := r.List(ctx, jobs, client.InNamespace(backup.Namespace), client.MatchingLabels{backupLabel: backup.Name})
+	if err != nil {
+		return err
+	}
+	sort.Slice(jobs.Items, func(i, j int) bool {
+		return jobs.Items[j].CreationTimestamp.Before(&jobs.Items[i].CreationTimestamp)
+	})
+
+	// Note: Jobs are sorted by creation, newest first. Dumps of deleted jobs remain the last backup.
+	running := false
+	var lastFinished *batchv1.Job
+	var lastSucceeded *batchv1.Job
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		finished, succeeded := getJobResult(job)
+		if !finished {
+			running = true
+			continue
+		}
+		if lastFinished == nil {
+			lastFinished = job
+		}
+		if succeeded && lastSucceeded == nil {
+			lastSucceeded = job
+		}
+	}
+	if lastSucceeded != nil {
+		backup.Status.LastBackup = &databasesamplev1beta1.BackupFile{
+			Name:           lastSucceeded.Name + backupFileSuffix,
+			CompletionTime: lastSucceeded.Status.CompletionTime,
+		}
+	}
+
+	conditions := &backup.Status.Conditions
+	switch {
+	case reconcileErr != nil:
+		setJobCondition(conditions, backup.Generation, CONDITION_TYPE_PROGRESSING, CONDITION_STATUS_FALSE,
+			CONDITION_REASON_RECONCILE_FAILED, reconcileErr.Error())
+	case running:
+		setJobCondition(conditions, backup.Generation, CONDITION_TYPE_PROGRESSING, CONDITION_STATUS_TRUE,
+			CONDITION_REASON_JOB_RUNNING, CONDITION_MESSAGE_BACKUP_RUNNING)
+	default:
+		setJobCondition(conditions, backup.Generation, CONDITION_TYPE_PROGRESSING, CONDITION_STATUS_FALSE,
+			CONDITION_REASON_JOB_NOT_RUNNING, CONDITION_MESSAGE_JOB_NOT_RUNNING)
+	}
+
+	switch {
+	case lastFinished == nil:
+		setJobCondition(conditions, backup.Generation, CONDITION_TYPE_SUCCEEDED, "Unknown",
+			CONDITION_REASON_NO_RESULT, CONDITION_MESSAGE_NO_RESULT)
+	case lastFinished == lastSucceeded:
+		setJobCondition(conditions, backup.Generation, CONDITION_TYPE_SUCCEEDED, CONDITION_STATUS_TRUE,
+			CONDITION_REASON_BACKUP_SUCCEEDED, "Dump "+lastFinished.Name+backupFileSuffix+" has been written")
+	default:
+		setJobCondition(conditions, backup.Generation, CONDITION_TYPE_SUCCEEDED, CONDITION_STATUS_FALSE,
+			CONDITION_REASON_BACKUP_FAILED, CONDITION_MESSAGE_BACKUP_FAILED)
+	}
+
+	if backup.Spec.Schedule == "" {
+		meta.RemoveStatusCondition(conditions, CONDITION_TYPE_SCHEDULED)
+	} else if reconcileErr == nil {
+		setJobCondition(conditions, backup.Generation, CONDITION_TYPE_SCHEDULED, CONDITION_STATUS_TRUE,
+			CONDITION_REASON_SCHEDULED, CONDITION_MESSAGE_SCHEDULED)
+	}
+	return nil
+}
+
+// Note: The status is only updated if it has changed to avoid unnecessary reconciliations
+func (r *DatabaseBackupReconciler) updateBackupStatus(ctx context.Context, original *databasesamplev1beta1.DatabaseBackup,
+	backup *databasesamplev1beta1.DatabaseBackup) error {
+
+	log := log.FromContext(ctx)
+	if equality.Semantic.DeepEqual(original.Status, backup.Status) {
+		return nil
+	}
+	err := r.Status().Update(ctx, backup)
+	if err != nil {
+		log.Info("DatabaseBackup resource status update failed.")
+	}
+	return err
+}
+
+// Note: Jobs created by cron jobs are not owned by backups, so jobs are mapped to backups via their label
+func (r *DatabaseBackupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Providers == nil {
+		r.Providers = NewProviders(mgr.GetClient(), mgr.GetScheme())
+	}
+	toBackup := handler.EnqueueRequestsFromMapFunc(func(object client.Object) []reconcile.Request {
+		name, ok := object.GetLabels()[backupLabel]
+		if !ok {
+			return nil
+		}
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name, Namespace: object.GetNamespace()}}}
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&databasesamplev1beta1.DatabaseBackup{}).
+		Owns(&batchv1.CronJob{}).
+		Watches(&source.Kind{Type: &batchv1.Job{}}, toBackup).
+		Complete(r)
+}
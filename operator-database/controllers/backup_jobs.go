@@ -0,0 +1,255 @@
+package controllers
+
+import (
+	"strconv"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const backupLabel = "database.sample.third.party/backup"
+const restoreLabel = "database.sample.third.party/restore"
+const backupVolumeName = "backup"
+const backupMountPath = "/backup"
+const backupFileSuffix = ".dump"
+const backupJobBackoffLimit int32 = 2
+const backupJobsHistoryLimit int32 = 3
+
+// Note: The MinIO client works with all S3-compatible object storages
+const s3ClientImage = "docker.io/minio/mc:RELEASE.2024-11-21T17-21-54Z"
+const s3ClientAlias = "backup"
+
+// Note: The alias of the MinIO client is written into its configuration in HOME, so that credentials don't need to be
+// URL-safe
+const s3ClientSetupScript = `set -e
+mc alias set ` + s3ClientAlias + ` "$S3_ENDPOINT" "$AWS_ACCESS_KEY_ID" "$AWS_SECRET_ACCESS_KEY" > /dev/null
+`
+
+// Note: Dumps are named after the jobs which write them. The names of jobs created by cron jobs end with the
+// scheduled time, so that sorting by name sorts the dumps by time.
+const backupFileEnvValue = backupMountPath + "/$(JOB_NAME)" + backupFileSuffix
+
+var backupJobUser int64 = 999
+
+func getBackupJobName(backup *databasesamplev1beta1.DatabaseBackup) string {
+	return backup.Name + "-backup"
+}
+
+func getRestoreJobName(restore *databasesamplev1beta1.DatabaseRestore) string {
+	return restore.Name + "-restore"
+}
+
+// Note: The dumps of a backup are stored in the directory '<backup name>' of the volume claim. For S3 the dumps are
+// written to an empty dir and uploaded.
+func getBackupVolume(backup *databasesamplev1beta1.DatabaseBackup, readOnly bool) (corev1.Volume, corev1.VolumeMount) {
+	volume := corev1.Volume{Name: backupVolumeName}
+	volumeMount := corev1.VolumeMount{Name: backupVolumeName, MountPath: backupMountPath}
+	if backup.Spec.Target.PersistentVolumeClaim != nil {
+		volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: backup.Spec.Target.PersistentVolumeClaim.ClaimName,
+			ReadOnly:  readOnly,
+		}
+		volumeMount.SubPath = backup.Name
+		volumeMount.ReadOnly = readOnly
+	} else {
+		volume.EmptyDir = &corev1.EmptyDirVolumeSource{}
+	}
+	return volume, volumeMount
+}
+
+func getS3Env(backup *databasesamplev1beta1.DatabaseBackup) []corev1.EnvVar {
+	s3 := backup.Spec.Target.S3
+	return []corev1.EnvVar{
+		{Name: "HOME", Value: "/tmp"},
+		{Name: "S3_ENDPOINT", Value: s3.Endpoint},
+		{Name: "S3_PATH", Value: s3.Bucket + "/" + s3.Prefix + backup.Name},
+		secretEnvVar(databasesamplev1beta1.S3AccessKeyIDSecretKey, s3.CredentialsSecretRef.Name, databasesamplev1beta1.S3AccessKeyIDSecretKey),
+		secretEnvVar(databasesamplev1beta1.S3SecretAccessKeySecretKey, s3.CredentialsSecretRef.Name, databasesamplev1beta1.S3SecretAccessKeySecretKey),
+	}
+}
+
+// Note: After the dump has been written, it is uploaded (S3) and older dumps are deleted (retention)
+func getBackupFinalizeScript(backup *databasesamplev1beta1.DatabaseBackup) string {
+	listCommand := `ls -1 "` + backupMountPath + `"`
+	deleteCommand := `rm -f "` + backupMountPath + `/$dump"`
+	script := "set -e\n"
+	if backup.Spec.Target.S3 != nil {
+		listCommand = `mc ls "` + s3ClientAlias + `/$S3_PATH/" | awk '{print $NF}'`
+		deleteCommand = `mc rm "` + s3ClientAlias + `/$S3_PATH/$dump"`
+		script = s3ClientSetupScript +
+			`mc cp "$BACKUP_FILE" "` + s3ClientAlias + `/$S3_PATH/$(basename "$BACKUP_FILE")"` + "\n"
+	}
+	if backup.Spec.Retention != nil {
+		keep := strconv.Itoa(int(backup.Spec.Retention.KeepLast) + 1)
+		script += listCommand + ` | grep '\` + backupFileSuffix + `$' | sort -r | tail -n +` + keep + ` | while read -r dump; do
+  echo "Deleting dump $dump"
+  ` + deleteCommand + `
+done
+`
+	}
+	return script + `echo "Backup $(basename "$BACKUP_FILE") written"` + "\n"
+}
+
+// Note: The containers of jobs run with the restricted Pod Security Standard
+func getJobPodSpec(initContainers []corev1.Container, container corev1.Container, volumes []corev1.Volume) corev1.PodSpec {
+	runAsNonRoot := true
+	allowPrivilegeEscalation := false
+	securityContext := &corev1.SecurityContext{
+		AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+		Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+	}
+	for i := range initContainers {
+		initContainers[i].SecurityContext = securityContext
+	}
+	container.SecurityContext = securityContext
+	return corev1.PodSpec{
+		RestartPolicy: corev1.RestartPolicyNever,
+		SecurityContext: &corev1.PodSecurityContext{
+			RunAsUser:      &backupJobUser,
+			RunAsGroup:     &backupJobUser,
+			FSGroup:        &backupJobUser,
+			RunAsNonRoot:   &runAsNonRoot,
+			SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+		},
+		InitContainers: initContainers,
+		Containers:     []corev1.Container{container},
+		Volumes:        volumes,
+	}
+}
+
+// Note: The dump is written by the engine in an init container, the finalize container uploads and prunes dumps
+func defineBackupJobTemplate(backup *databasesamplev1beta1.DatabaseBackup, hooks *BackupHooks) batchv1.JobTemplateSpec {
+	labels := map[string]string{backupLabel: backup.Name}
+	backoffLimit := backupJobBackoffLimit
+	volume, volumeMount := getBackupVolume(backup, false)
+	backupEnv := []corev1.EnvVar{
+		{Name: "JOB_NAME", ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.labels['job-name']"},
+		}},
+		{Name: BackupFileEnvName, Value: backupFileEnvValue},
+	}
+
+	dumpContainer := corev1.Container{
+		Name:         "dump",
+		Image:        hooks.Image,
+		Command:      hooks.BackupCommand,
+		Env:          append(append([]corev1.EnvVar{}, hooks.Env...), backupEnv...),
+		VolumeMounts: []corev1.VolumeMount{volumeMount},
+	}
+	finalizeContainer := corev1.Container{
+		Name:         "finalize",
+		Image:        hooks.Image,
+		Command:      []string{"sh", "-c", getBackupFinalizeScript(backup)},
+		Env:          backupEnv,
+		VolumeMounts: []corev1.VolumeMount{volumeMount},
+	}
+	if backup.Spec.Target.S3 != nil {
+		finalizeContainer.Image = s3ClientImage
+		finalizeContainer.Env = append(getS3Env(backup), backupEnv...)
+	}
+
+	return batchv1.JobTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: labels},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       getJobPodSpec([]corev1.Container{dumpContainer}, finalizeContainer, []corev1.Volume{volume}),
+			},
+		},
+	}
+}
+
+func (r *DatabaseBackupReconciler) defineBackupJob(backup *databasesamplev1beta1.DatabaseBackup, hooks *BackupHooks) *batchv1.Job {
+	template := defineBackupJobTemplate(backup, hooks)
+	job := &batchv1.Job{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+		ObjectMeta: metav1.ObjectMeta{Name: getBackupJobName(backup), Namespace: backup.Namespace, Labels: template.Labels},
+		Spec:       template.Spec,
+	}
+
+	ctrl.SetControllerReference(backup, job, r.Scheme)
+	return job
+}
+
+// Note: Backups of the same database don't run concurrently
+func (r *DatabaseBackupReconciler) defineBackupCronJob(backup *databasesamplev1beta1.DatabaseBackup, hooks *BackupHooks) *batchv1.CronJob {
+	historyLimit := backupJobsHistoryLimit
+	template := defineBackupJobTemplate(backup, hooks)
+	cronJob := &batchv1.CronJob{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "CronJob"},
+		ObjectMeta: metav1.ObjectMeta{Name: getBackupJobName(backup), Namespace: backup.Namespace, Labels: template.Labels},
+		Spec: batchv1.CronJobSpec{
+			Schedule:                   backup.Spec.Schedule,
+			ConcurrencyPolicy:          batchv1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: &historyLimit,
+			FailedJobsHistoryLimit:     &historyLimit,
+			JobTemplate:                template,
+		},
+	}
+
+	ctrl.SetControllerReference(backup, cronJob, r.Scheme)
+	return cronJob
+}
+
+// Note: For S3 the dump is downloaded in an init container. The engine of the target database restores the dump.
+func (r *DatabaseRestoreReconciler) defineRestoreJob(restore *databasesamplev1beta1.DatabaseRestore,
+	backup *databasesamplev1beta1.DatabaseBackup, backupFile string, hooks *BackupHooks) *batchv1.Job {
+
+	labels := map[string]string{restoreLabel: restore.Name}
+	backoffLimit := backupJobBackoffLimit
+	volume, volumeMount := getBackupVolume(backup, backup.Spec.Target.PersistentVolumeClaim != nil)
+	backupFileEnv := corev1.EnvVar{Name: BackupFileEnvName, Value: backupMountPath + "/" + backupFile}
+
+	initContainers := []corev1.Container{}
+	if backup.Spec.Target.S3 != nil {
+		initContainers = append(initContainers, corev1.Container{
+			Name:         "download",
+			Image:        s3ClientImage,
+			Command:      []string{"sh", "-c", s3ClientSetupScript + `mc cp "` + s3ClientAlias + `/$S3_PATH/$(basename "$BACKUP_FILE")" "$BACKUP_FILE"`},
+			Env:          append(getS3Env(backup), backupFileEnv),
+			VolumeMounts: []corev1.VolumeMount{volumeMount},
+		})
+	}
+	restoreContainer := corev1.Container{
+		Name:         "restore",
+		Image:        hooks.Image,
+		Command:      hooks.RestoreCommand,
+		Env:          append(append([]corev1.EnvVar{}, hooks.Env...), backupFileEnv),
+		VolumeMounts: []corev1.VolumeMount{volumeMount},
+	}
+
+	job := &batchv1.Job{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+		ObjectMeta: metav1.ObjectMeta{Name: getRestoreJobName(restore), Namespace: restore.Namespace, Labels: labels},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       getJobPodSpec(initContainers, restoreContainer, []corev1.Volume{volume}),
+			},
+		},
+	}
+
+	ctrl.SetControllerReference(restore, job, r.Scheme)
+	return job
+}
+
+// Note: Jobs have either succeeded, failed after all retries or are still running
+func getJobResult(job *batchv1.Job) (finished bool, succeeded bool) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return true, true
+		case batchv1.JobFailed:
+			return true, false
+		}
+	}
+	return false, false
+}
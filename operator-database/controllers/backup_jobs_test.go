@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func newTestBackup(name string, target databasesamplev1beta1.BackupTarget) *databasesamplev1beta1.DatabaseBackup {
+	return &databasesamplev1beta1.DatabaseBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       databasesamplev1beta1.DatabaseBackupSpec{DatabaseName: "database", Target: target},
+	}
+}
+
+var _ = Describe("Database backup jobs", func() {
+	claimTarget := &databasesamplev1beta1.PersistentVolumeClaimTarget{ClaimName: "backups"}
+	s3Target := &databasesamplev1beta1.S3Target{
+		Endpoint:             "http://minio.minio.svc:9000",
+		Bucket:               "backups",
+		CredentialsSecretRef: corev1.LocalObjectReference{Name: "minio-credentials"},
+	}
+	var hooks *BackupHooks
+
+	BeforeEach(func() {
+		hooks = NewFakeProvider().BackupHooks(newTestDatabase("database", databasesamplev1beta1.EnginePostgreSQL))
+	})
+
+	table.DescribeTable("need exactly one target",
+		func(target databasesamplev1beta1.BackupTarget, valid bool) {
+			err := validateBackupTarget(newTestBackup("nightly", target))
+			Expect(err == nil).To(Equal(valid))
+		},
+		table.Entry("without target", databasesamplev1beta1.BackupTarget{}, false),
+		table.Entry("claim", databasesamplev1beta1.BackupTarget{PersistentVolumeClaim: claimTarget}, true),
+		table.Entry("s3", databasesamplev1beta1.BackupTarget{S3: s3Target}, true),
+		table.Entry("claim and s3", databasesamplev1beta1.BackupTarget{PersistentVolumeClaim: claimTarget, S3: s3Target}, false),
+	)
+
+	It("write the dumps of schedules into the claim and keep the last ones", func() {
+		backup := newTestBackup("nightly", databasesamplev1beta1.BackupTarget{PersistentVolumeClaim: claimTarget})
+		backup.Spec.Schedule = "0 2 * * *"
+		backup.Spec.Retention = &databasesamplev1beta1.BackupRetention{KeepLast: 7}
+
+		reconciler := &DatabaseBackupReconciler{Scheme: scheme.Scheme}
+		cronJob := reconciler.defineBackupCronJob(backup, hooks)
+		Expect(cronJob.Spec.Schedule).To(Equal(backup.Spec.Schedule))
+		Expect(metav1.GetControllerOf(cronJob)).NotTo(BeNil())
+		Expect(cronJob.Spec.JobTemplate.Labels).To(HaveKeyWithValue(backupLabel, backup.Name))
+		pod := cronJob.Spec.JobTemplate.Spec.Template
+		Expect(pod.Labels).To(HaveKeyWithValue(backupLabel, backup.Name))
+		volume := pod.Spec.Volumes[0]
+		Expect(volume.PersistentVolumeClaim).NotTo(BeNil())
+		Expect(volume.PersistentVolumeClaim.ClaimName).To(Equal("backups"))
+		dump := pod.Spec.InitContainers[0]
+		Expect(dump.VolumeMounts[0].SubPath).To(Equal(backup.Name))
+		Expect(dump.Env).To(ContainElement(corev1.EnvVar{Name: BackupFileEnvName, Value: backupMountPath + "/$(JOB_NAME)" + backupFileSuffix}))
+		script := pod.Spec.Containers[0].Command[2]
+		Expect(script).To(ContainSubstring("tail -n +8"))
+		Expect(script).To(ContainSubstring(`rm -f "/backup/$dump"`))
+	})
+
+	It("upload the dumps to S3", func() {
+		target := s3Target.DeepCopy()
+		target.Prefix = "databases/"
+		backup := newTestBackup("nightly", databasesamplev1beta1.BackupTarget{S3: target})
+
+		reconciler := &DatabaseBackupReconciler{Scheme: scheme.Scheme}
+		job := reconciler.defineBackupJob(backup, hooks)
+		Expect(job.Name).To(Equal("nightly-backup"))
+		Expect(job.Spec.Template.Spec.Volumes[0].EmptyDir).NotTo(BeNil())
+		upload := job.Spec.Template.Spec.Containers[0]
+		Expect(upload.Image).To(Equal(s3ClientImage))
+		Expect(upload.Env).To(ContainElement(corev1.EnvVar{Name: "S3_PATH", Value: "backups/databases/nightly"}))
+		// Note: Without retention no dumps are deleted
+		Expect(upload.Command[2]).NotTo(ContainSubstring("mc rm"))
+	})
+
+	It("restore the dumps into other databases", func() {
+		backup := newTestBackup("nightly", databasesamplev1beta1.BackupTarget{S3: s3Target})
+		restore := &databasesamplev1beta1.DatabaseRestore{
+			ObjectMeta: metav1.ObjectMeta{Name: "copy", Namespace: "default"},
+			Spec:       databasesamplev1beta1.DatabaseRestoreSpec{BackupName: backup.Name, DatabaseName: "target"},
+		}
+		target := newTestDatabase("target", databasesamplev1beta1.EnginePostgreSQL)
+		reconciler := &DatabaseRestoreReconciler{Scheme: scheme.Scheme}
+
+		for databaseEnvName, provider := range map[string]Provider{"PGDATABASE": &postgreSQLProvider{}, "DATABASE_NAME": &mySQLProvider{}} {
+			By(databaseEnvName)
+			hooks := provider.BackupHooks(target)
+			Expect(hooks.BackupCommand[2]).NotTo(ContainSubstring("--databases"))
+			Expect(hooks.BackupCommand[2]).NotTo(ContainSubstring("--add-drop-database"))
+			job := reconciler.defineRestoreJob(restore, backup, "nightly-1.sql", hooks)
+			pod := job.Spec.Template.Spec
+			Expect(pod.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: databaseEnvName, Value: "target"}))
+			Expect(pod.InitContainers[0].Command[2]).To(ContainSubstring("mc alias set"))
+			for _, container := range append(pod.InitContainers, pod.Containers...) {
+				securityContext := container.SecurityContext
+				Expect(securityContext).NotTo(BeNil())
+				Expect(*securityContext.AllowPrivilegeEscalation).To(BeFalse())
+				Expect(securityContext.Capabilities.Drop).To(Equal([]corev1.Capability{"ALL"}))
+			}
+			Expect(pod.SecurityContext.SeccompProfile).NotTo(BeNil())
+			Expect(pod.SecurityContext.SeccompProfile.Type).To(Equal(corev1.SeccompProfileTypeRuntimeDefault))
+		}
+	})
+})
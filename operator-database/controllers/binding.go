@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func readSecretKey(ctx context.Context, reader client.Reader, namespace string, name string, key string) ([]byte, error) {
+	secret := &corev1.Secret{}
+	err := reader.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, secret)
+	if err != nil {
+		return nil, fmt.Errorf("secret %s cannot be read: %v", name, err)
+	}
+	value, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("secret %s doesn't contain the key %s", name, key)
+	}
+	return value, nil
+}
+
+// Note: nil is returned for databases without TLS secret
+func readCACertificate(ctx context.Context, reader client.Reader, database *databasesamplev1beta1.Database) ([]byte, error) {
+	if database.Spec.TLSSecretRef == nil {
+		return nil, nil
+	}
+	return readSecretKey(ctx, reader, database.Namespace, database.Spec.TLSSecretRef.Name, databasesamplev1beta1.CACertificateSecretKey)
+}
+
+// Note: The keys follow the well-known entries of the Service Binding specification. The type is the engine.
+func getCredentials(database *databasesamplev1beta1.Database, endpoint databasesamplev1beta1.DatabaseEndpoint,
+	username string, password []byte) map[string][]byte {
+
+	port := strconv.Itoa(int(endpoint.Port))
+	return map[string][]byte{
+		bindingKeyType:     []byte(database.GetEngine()),
+		bindingKeyHost:     []byte(endpoint.Host),
+		bindingKeyPort:     []byte(port),
+		bindingKeyDatabase: []byte(database.Name),
+		bindingKeyUsername: []byte(username),
+		bindingKeyPassword: password,
+		bindingKeyJDBCURL:  []byte("jdbc:" + database.GetEngine() + "://" + endpoint.Host + ":" + port + "/" + database.Name),
+	}
+}
+
+// Note: Connection secrets of databases and database users have the same entries
+func getBindingData(database *databasesamplev1beta1.Database, provider Provider,
+	username string, password []byte, caCertificate []byte) map[string][]byte {
+
+	data := provider.Credentials(database, username, password)
+	data[bindingKeyProvider] = []byte(bindingProvider)
+	if caCertificate != nil {
+		data[bindingKeyCACertificate] = caCertificate
+	}
+	return data
+}
+
+func (r *DatabaseReconciler) defineConnectionSecret(database *databasesamplev1beta1.Database, provider Provider,
+	password []byte, caCertificate []byte) *corev1.Secret {
+
+	data := getBindingData(database, provider, database.Spec.User, password, caCertificate)
+	secret := &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: getConnectionSecretName(database), Namespace: database.Namespace, Labels: getLabels(database)},
+		Type:       corev1.SecretType("servicebinding.io/" + string(data[bindingKeyType])),
+		Data:       data,
+	}
+
+	ctrl.SetControllerReference(database, secret, r.Scheme)
+	return secret
+}
+
+// Note: The connection secret is re-computed in every reconciliation so that it follows rotated credentials. The password
+// in the database is changed by reconcileUserPassword.
+func (r *DatabaseReconciler) reconcileConnectionSecret(ctx context.Context, database *databasesamplev1beta1.Database, provider Provider) (ctrl.Result, error) {
+	connectionSecretName := getConnectionSecretName(database)
+	log := log.FromContext(ctx)
+	passwordRef := database.GetPasswordSecretRef()
+	password, err := readSecretKey(ctx, r, database.Namespace, passwordRef.Name, passwordRef.Key)
+	if err != nil {
+		log.Info("Failed to read password. Re-running reconcile.")
+		return ctrl.Result{}, err
+	}
+	caCertificate, err := readCACertificate(ctx, r, database)
+	if err != nil {
+		log.Info("Failed to read CA certificate. Re-running reconcile.")
+		return ctrl.Result{}, err
+	}
+	desired := r.defineConnectionSecret(database, provider, password, caCertificate)
+
+	secret := &corev1.Secret{}
+	err = r.Get(ctx, types.NamespacedName{Name: connectionSecretName, Namespace: database.Namespace}, secret)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("Secret resource " + connectionSecretName + " not found. Creating or re-creating secret")
+			err = r.Create(ctx, desired)
+			if err != nil {
+				log.Info("Failed to create secret resource. Re-running reconcile.")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		}
+		log.Info("Failed to get secret resource " + connectionSecretName + ". Re-running reconcile.")
+		return ctrl.Result{}, err
+	}
+
+	// Note: The type of secrets is immutable. The deletion of the owned secret triggers its re-creation.
+	if secret.Type != desired.Type {
+		log.Info("Secret resource " + connectionSecretName + " has an unexpected type. Re-creating secret")
+		err = r.Delete(ctx, secret)
+		if err != nil {
+			log.Info("Failed to delete secret resource. Re-running reconcile.")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+	if !equality.Semantic.DeepEqual(secret.Data, desired.Data) {
+		log.Info("Secret resource " + connectionSecretName + " is out of date. Updating secret")
+		secret.Data = desired.Data
+		err = r.Update(ctx, secret)
+		if err != nil {
+			log.Info("Failed to update secret resource. Re-running reconcile.")
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// Note: Referenced secrets are not owned by databases, changes are mapped to the databases which reference them
+func (r *DatabaseReconciler) findDatabasesForSecret(object client.Object) []reconcile.Request {
+	databases := &databasesamplev1beta1.DatabaseList{}
+	err := r.List(context.Background(), databases, client.InNamespace(object.GetNamespace()))
+	if err != nil {
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, database := range databases.Items {
+		referenced := database.GetPasswordSecretRef().Name == object.GetName() ||
+			(database.Spec.TLSSecretRef != nil && database.Spec.TLSSecretRef.Name == object.GetName())
+		if referenced {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: database.Name, Namespace: database.Namespace},
+			})
+		}
+	}
+	return requests
+}
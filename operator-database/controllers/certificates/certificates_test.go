@@ -0,0 +1,88 @@
+package certificatescontroller
+
+import (
+	"bytes"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestRotateCertificates(t *testing.T) {
+	now := time.Now()
+	dnsNames := getServiceDNSNames("webhook-service", "test")
+	secret := &corev1.Secret{}
+
+	changed, err := rotateCertificates(secret, "webhook-ca", dnsNames, now)
+	if err != nil || !changed {
+		t.Fatalf("expected certificates to be created, changed: %v, error: %v", changed, err)
+	}
+	verifyServingCertificate(t, secret, dnsNames, now)
+
+	changed, err = rotateCertificates(secret, "webhook-ca", dnsNames, now.Add(time.Hour))
+	if err != nil || changed {
+		t.Fatalf("expected valid certificates to be kept, changed: %v, error: %v", changed, err)
+	}
+
+	// Note: The serving certificate is rotated before the certificate authority
+	caCert := secret.Data[caCertKey]
+	later := now.Add(servingCertValidity - rotationThreshold + time.Hour)
+	changed, err = rotateCertificates(secret, "webhook-ca", dnsNames, later)
+	if err != nil || !changed {
+		t.Fatalf("expected serving certificate to be rotated, changed: %v, error: %v", changed, err)
+	}
+	if !bytes.Equal(caCert, secret.Data[caCertKey]) {
+		t.Fatalf("expected certificate authority to be kept")
+	}
+	verifyServingCertificate(t, secret, dnsNames, later)
+
+	later = now.Add(caValidity - rotationThreshold + time.Hour)
+	changed, err = rotateCertificates(secret, "webhook-ca", dnsNames, later)
+	if err != nil || !changed {
+		t.Fatalf("expected certificate authority to be rotated, changed: %v, error: %v", changed, err)
+	}
+	if !bytes.Equal(caCert, secret.Data[previousCACertKey]) {
+		t.Fatalf("expected previous certificate authority to be kept in the CA bundle")
+	}
+	verifyServingCertificate(t, secret, dnsNames, later)
+
+	changed, err = rotateCertificates(secret, "webhook-ca", dnsNames, now.Add(caValidity+time.Hour))
+	if err != nil || !changed {
+		t.Fatalf("expected expired certificate authority to be removed, changed: %v, error: %v", changed, err)
+	}
+	if _, exists := secret.Data[previousCACertKey]; exists {
+		t.Fatalf("expected expired certificate authority to be removed from the CA bundle")
+	}
+}
+
+func TestRotateCertificatesWithChangedNamespace(t *testing.T) {
+	now := time.Now()
+	secret := &corev1.Secret{}
+	if _, err := rotateCertificates(secret, "webhook-ca", getServiceDNSNames("webhook-service", "old"), now); err != nil {
+		t.Fatal(err)
+	}
+	dnsNames := getServiceDNSNames("webhook-service", "new")
+	changed, err := rotateCertificates(secret, "webhook-ca", dnsNames, now)
+	if err != nil || !changed {
+		t.Fatalf("expected serving certificate to be re-issued, changed: %v, error: %v", changed, err)
+	}
+	verifyServingCertificate(t, secret, dnsNames, now)
+}
+
+func verifyServingCertificate(t *testing.T, secret *corev1.Secret, dnsNames []string, now time.Time) {
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(getCABundle(secret)) {
+		t.Fatalf("CA bundle contains no certificates")
+	}
+	servingCert, err := parseCertificate(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dnsName := range dnsNames {
+		_, err = servingCert.Verify(x509.VerifyOptions{DNSName: dnsName, Roots: roots, CurrentTime: now})
+		if err != nil {
+			t.Fatalf("serving certificate is not valid for %s: %v", dnsName, err)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package certificatescontroller
+
+import (
+	"context"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// Note: The reconciler replaces cert-manager. It stores a certificate authority and the serving certificate of the
+// webhooks in a secret, writes the serving certificate into CertDir and injects the CA bundle into the webhook
+// configurations and the conversion webhook of the CRD.
+// Note: The names of the resources are passed in, the package is the same in the database and the application operator
+// Note: Resources are read via the API reader since the certificates are needed before the manager and its cache start
+type CertificateReconciler struct {
+	client.Client
+	APIReader client.Reader
+	Scheme    *runtime.Scheme
+	Namespace string
+	CertDir   string
+	Names     Names
+}
+
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update
+//+kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=mutatingwebhookconfigurations,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=validatingwebhookconfigurations,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch;update;patch
+func (reconciler *CertificateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	nextRotation, err := reconciler.reconcileCertificates(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: nextRotation}, nil
+}
+
+// SetupCertificates creates or rotates the certificates before the webhook server starts
+// Note: Other replicas might create the secret at the same time
+func (reconciler *CertificateReconciler) SetupCertificates(ctx context.Context) error {
+	return retry.OnError(retry.DefaultBackoff, errors.IsAlreadyExists, func() error {
+		_, err := reconciler.reconcileCertificates(ctx)
+		return err
+	})
+}
+
+// Note: The CA bundle is injected before the files are written so that the API server trusts new serving certificates
+func (reconciler *CertificateReconciler) reconcileCertificates(ctx context.Context) (time.Duration, error) {
+	log := log.FromContext(ctx)
+	now := time.Now()
+	dnsNames := getServiceDNSNames(reconciler.Names.Service, reconciler.Namespace)
+
+	secret := &corev1.Secret{}
+	err := reconciler.APIReader.Get(ctx, types.NamespacedName{Name: reconciler.Names.Secret, Namespace: reconciler.Namespace}, secret)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Info("Failed to get secret resource " + reconciler.Names.Secret + ". Re-running reconcile.")
+			return 0, err
+		}
+		log.Info("Secret resource " + reconciler.Names.Secret + " not found. Creating certificates")
+		secret = &corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: metav1.ObjectMeta{Name: reconciler.Names.Secret, Namespace: reconciler.Namespace},
+			Type:       corev1.SecretTypeTLS,
+		}
+		_, err = rotateCertificates(secret, reconciler.Names.CertificateAuthority, dnsNames, now)
+		if err != nil {
+			log.Info("Failed to create certificates")
+			return 0, err
+		}
+		err = reconciler.Create(ctx, secret)
+		if err != nil {
+			log.Info("Failed to create secret resource " + reconciler.Names.Secret + ". Re-running reconcile.")
+			return 0, err
+		}
+	} else {
+		changed, err := rotateCertificates(secret, reconciler.Names.CertificateAuthority, dnsNames, now)
+		if err != nil {
+			log.Info("Failed to rotate certificates")
+			return 0, err
+		}
+		if changed {
+			log.Info("Rotating certificates in secret resource " + reconciler.Names.Secret)
+			err = reconciler.Update(ctx, secret)
+			if err != nil {
+				log.Info("Failed to update secret resource " + reconciler.Names.Secret + ". Re-running reconcile.")
+				return 0, err
+			}
+		}
+	}
+
+	err = reconciler.injectCABundle(ctx, getCABundle(secret))
+	if err != nil {
+		return 0, err
+	}
+	err = reconciler.writeCertificateFiles(ctx, secret)
+	if err != nil {
+		return 0, err
+	}
+	return getNextRotation(secret, now), nil
+}
+
+// Note: Every replica runs the webhook server, so all replicas write the files when the secret changes. The files are
+// only written once the leader has injected the CA bundle of the secret, see reconcileCertificates.
+func (reconciler *CertificateReconciler) reconcileCertificateFiles(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	secret := &corev1.Secret{}
+	err := reconciler.Get(ctx, req.NamespacedName, secret)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Info("Failed to get secret resource " + req.Name + ". Re-running reconcile.")
+		return ctrl.Result{}, err
+	}
+	injected, err := reconciler.isCABundleInjected(ctx, getCABundle(secret))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !injected {
+		log.Info("CA bundle of secret resource " + req.Name + " has not been injected yet")
+		return ctrl.Result{RequeueAfter: time.Second}, nil
+	}
+	return ctrl.Result{}, reconciler.writeCertificateFiles(ctx, secret)
+}
+
+// Note: The files are written by a controller which runs without leader election
+type certificateFilesController struct {
+	controller.Controller
+}
+
+func (c certificateFilesController) NeedLeaderElection() bool {
+	return false
+}
+
+// Note: Changes of the secret, the webhook configurations and the CRD all trigger the reconciliation of the secret
+// Note: Only the leader rotates the certificates, the files are written by all replicas
+func (reconciler *CertificateReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	hasName := func(name string) predicate.Predicate {
+		return predicate.NewPredicateFuncs(func(object client.Object) bool {
+			return object.GetName() == name
+		})
+	}
+	toSecret := handler.EnqueueRequestsFromMapFunc(func(object client.Object) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: reconciler.Names.Secret, Namespace: reconciler.Namespace}}}
+	})
+	isSecret := predicate.NewPredicateFuncs(func(object client.Object) bool {
+		return object.GetName() == reconciler.Names.Secret && object.GetNamespace() == reconciler.Namespace
+	})
+
+	filesController, err := controller.NewUnmanaged("webhookcertificatefiles", mgr, controller.Options{
+		Reconciler: reconcile.Func(reconciler.reconcileCertificateFiles),
+	})
+	if err != nil {
+		return err
+	}
+	err = filesController.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForObject{}, isSecret)
+	if err != nil {
+		return err
+	}
+	err = mgr.Add(certificateFilesController{filesController})
+	if err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("webhookcertificates").
+		For(&corev1.Secret{}, builder.WithPredicates(isSecret)).
+		Watches(&source.Kind{Type: &admissionregistrationv1.MutatingWebhookConfiguration{}}, toSecret,
+			builder.WithPredicates(hasName(reconciler.Names.MutatingWebhookConfiguration))).
+		Watches(&source.Kind{Type: &admissionregistrationv1.ValidatingWebhookConfiguration{}}, toSecret,
+			builder.WithPredicates(hasName(reconciler.Names.ValidatingWebhookConfiguration))).
+		Watches(&source.Kind{Type: &apiextensionsv1.CustomResourceDefinition{}}, toSecret,
+			builder.WithPredicates(hasName(reconciler.Names.CustomResourceDefinition))).
+		Complete(reconciler)
+}
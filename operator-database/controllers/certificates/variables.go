@@ -0,0 +1,60 @@
+package certificatescontroller
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Names of the resources which the certificates are created for and injected into
+type Names struct {
+	// Secret in the namespace of the operator which stores the certificate authority and the serving certificate
+	Secret string
+	// Service of the webhook server in the namespace of the operator
+	Service                        string
+	MutatingWebhookConfiguration   string
+	ValidatingWebhookConfiguration string
+	// CustomResourceDefinition whose conversion webhook gets the CA bundle
+	CustomResourceDefinition string
+	// Common name of the certificate authority
+	CertificateAuthority string
+}
+
+// NewNames returns the names of the kubebuilder scaffolding with the prefix which kustomize adds, e.g. 'operator-database-'
+func NewNames(namePrefix string, crdName string) Names {
+	return Names{
+		Secret:                         namePrefix + "webhook-server-cert",
+		Service:                        namePrefix + "webhook-service",
+		MutatingWebhookConfiguration:   namePrefix + "mutating-webhook-configuration",
+		ValidatingWebhookConfiguration: namePrefix + "validating-webhook-configuration",
+		CustomResourceDefinition:       crdName,
+		CertificateAuthority:           namePrefix + "webhook-ca",
+	}
+}
+
+// Note: Same directory as the default directory of the controller-runtime webhook server
+var DefaultCertDir = filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs")
+
+const caCertKey = "ca.crt"
+const caKeyKey = "ca.key"
+const previousCACertKey = "previous-ca.crt"
+
+const caValidity = 365 * 24 * time.Hour
+const servingCertValidity = 90 * 24 * time.Hour
+const rotationThreshold = 30 * 24 * time.Hour
+
+// Note: The namespace is set via the downward API, see config/default/manager_webhook_self_managed_patch.yaml
+const operatorNamespaceEnvName = "POD_NAMESPACE"
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// GetOperatorNamespace returns the namespace the operator runs in, outside of clusters the default namespace
+func GetOperatorNamespace(defaultOperatorNamespace string) string {
+	if namespace := strings.TrimSpace(os.Getenv(operatorNamespaceEnvName)); namespace != "" {
+		return namespace
+	}
+	if namespace, err := os.ReadFile(serviceAccountNamespaceFile); err == nil {
+		return strings.TrimSpace(string(namespace))
+	}
+	return defaultOperatorNamespace
+}
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"context"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const CONDITION_STATUS_TRUE = "True"
+const CONDITION_STATUS_FALSE = "False"
+
+// Note: Provisioning is True until the database accepts connections for the first time
+const CONDITION_TYPE_PROVISIONING = "Provisioning"
+const CONDITION_REASON_PROVISIONING_IN_PROGRESS = "ProvisioningInProgress"
+const CONDITION_MESSAGE_PROVISIONING_IN_PROGRESS = "Database is being provisioned"
+const CONDITION_REASON_PROVISIONING_SUCCEEDED = "ProvisioningSucceeded"
+const CONDITION_MESSAGE_PROVISIONING_SUCCEEDED = "Database has been provisioned"
+
+// Note: Ready is True when the provider reports that the database accepts connections, e.g. the readiness probe succeeds
+const CONDITION_TYPE_READY = "Ready"
+const CONDITION_REASON_READY = "DatabaseAcceptsConnections"
+const CONDITION_MESSAGE_READY = "Database accepts connections"
+const CONDITION_REASON_NOT_READY = "DatabaseNotReady"
+const CONDITION_MESSAGE_NOT_READY = "Database doesn't accept connections yet"
+
+// Note: Degraded is True if a provisioned database stops accepting connections or if resources cannot be reconciled
+const CONDITION_TYPE_DEGRADED = "Degraded"
+const CONDITION_REASON_NOT_DEGRADED = "AsExpected"
+const CONDITION_MESSAGE_NOT_DEGRADED = "Database works as expected"
+const CONDITION_REASON_DEGRADED_UNAVAILABLE = "DatabaseUnavailable"
+const CONDITION_MESSAGE_DEGRADED_UNAVAILABLE = "Database has been provisioned, but doesn't accept connections"
+const CONDITION_REASON_DEGRADED_RECONCILE_FAILED = "ReconcileFailed"
+
+// Note: Upgrading is True while the version of the engine is upgraded, UpgradeFailed is True if the upgrade is stuck
+const CONDITION_TYPE_UPGRADING = "Upgrading"
+const CONDITION_REASON_UPGRADE_IN_PROGRESS = "UpgradeInProgress"
+const CONDITION_REASON_UPGRADE_STOPPED = "UpgradeStopped"
+const CONDITION_REASON_VERSION_UP_TO_DATE = "VersionUpToDate"
+const CONDITION_MESSAGE_VERSION_UP_TO_DATE = "Database runs the version of the spec"
+const CONDITION_TYPE_UPGRADE_FAILED = "UpgradeFailed"
+const CONDITION_REASON_UPGRADE_NOT_FAILED = "AsExpected"
+const CONDITION_MESSAGE_UPGRADE_NOT_FAILED = "No upgrade has failed"
+const CONDITION_REASON_UPGRADE_NOT_SUPPORTED = "UpgradeNotSupported"
+const CONDITION_REASON_UPGRADE_BACKUP_FAILED = "BackupFailed"
+const CONDITION_MESSAGE_UPGRADE_BACKUP_FAILED = "Backup before the upgrade failed. Delete the backup to retry it or set the version back to cancel the upgrade."
+const CONDITION_REASON_UPGRADE_RESTORE_FAILED = "RestoreFailed"
+const CONDITION_MESSAGE_UPGRADE_RESTORE_FAILED = "Dump could not be restored into the new version. Delete the restore to retry it."
+
+// Note: Reachable is True when the last probe could connect, complete the TLS handshake and run SELECT 1 as the user
+const CONDITION_TYPE_REACHABLE = "Reachable"
+const CONDITION_REASON_REACHABLE = "ProbeSucceeded"
+const CONDITION_MESSAGE_REACHABLE = "Database accepts authenticated queries"
+const CONDITION_REASON_ENDPOINT_UNKNOWN = "EndpointUnknown"
+const CONDITION_MESSAGE_ENDPOINT_UNKNOWN = "Database has no endpoint and connection secret yet"
+const CONDITION_REASON_PROBE_NOT_POSSIBLE = "ProbeNotPossible"
+const CONDITION_REASON_CONNECT_FAILED = "ConnectFailed"
+const CONDITION_REASON_TLS_HANDSHAKE_FAILED = "TLSHandshakeFailed"
+const CONDITION_REASON_QUERY_FAILED = "QueryFailed"
+
+// Note: The status is computed in memory, see updateStatus
+func (r *DatabaseReconciler) setStatus(database *databasesamplev1beta1.Database, providerStatus *ProviderStatus, reconcileErr error) {
+	database.Status.ObservedGeneration = database.Generation
+	// Note: The last version is kept while the stateful set is re-created during major upgrades
+	if providerStatus != nil {
+		endpoint := providerStatus.Endpoint
+		database.Status.Endpoint = &endpoint
+		if providerStatus.Version != "" {
+			database.Status.Version = providerStatus.Version
+		}
+	}
+	// Note: The binding is only published when the connection secret has been written
+	if reconcileErr == nil {
+		connectionSecretName := getConnectionSecretName(database)
+		database.Status.ConnectionSecret = &corev1.LocalObjectReference{Name: connectionSecretName}
+		database.Status.Binding = &corev1.LocalObjectReference{Name: connectionSecretName}
+	}
+
+	ready := providerStatus != nil && providerStatus.Ready
+	provisioned := ready || meta.IsStatusConditionFalse(database.Status.Conditions, CONDITION_TYPE_PROVISIONING)
+
+	if provisioned {
+		r.setCondition(database, CONDITION_TYPE_PROVISIONING, CONDITION_STATUS_FALSE,
+			CONDITION_REASON_PROVISIONING_SUCCEEDED, CONDITION_MESSAGE_PROVISIONING_SUCCEEDED)
+	} else {
+		r.setCondition(database, CONDITION_TYPE_PROVISIONING, CONDITION_STATUS_TRUE,
+			CONDITION_REASON_PROVISIONING_IN_PROGRESS, CONDITION_MESSAGE_PROVISIONING_IN_PROGRESS)
+	}
+
+	if ready {
+		r.setCondition(database, CONDITION_TYPE_READY, CONDITION_STATUS_TRUE, CONDITION_REASON_READY, CONDITION_MESSAGE_READY)
+	} else {
+		r.setCondition(database, CONDITION_TYPE_READY, CONDITION_STATUS_FALSE, CONDITION_REASON_NOT_READY, CONDITION_MESSAGE_NOT_READY)
+	}
+
+	switch {
+	case reconcileErr != nil:
+		r.setCondition(database, CONDITION_TYPE_DEGRADED, CONDITION_STATUS_TRUE,
+			CONDITION_REASON_DEGRADED_RECONCILE_FAILED, reconcileErr.Error())
+	case provisioned && !ready:
+		r.setCondition(database, CONDITION_TYPE_DEGRADED, CONDITION_STATUS_TRUE,
+			CONDITION_REASON_DEGRADED_UNAVAILABLE, CONDITION_MESSAGE_DEGRADED_UNAVAILABLE)
+	default:
+		r.setCondition(database, CONDITION_TYPE_DEGRADED, CONDITION_STATUS_FALSE,
+			CONDITION_REASON_NOT_DEGRADED, CONDITION_MESSAGE_NOT_DEGRADED)
+	}
+}
+
+func (r *DatabaseReconciler) setCondition(database *databasesamplev1beta1.Database,
+	typeName string, status metav1.ConditionStatus, reason string, message string) {
+
+	meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{
+		Type:               typeName,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: database.Generation,
+	})
+}
+
+// Note: The status is only updated if it has changed to avoid unnecessary reconciliations
+func (r *DatabaseReconciler) updateStatus(ctx context.Context, original *databasesamplev1beta1.Database,
+	database *databasesamplev1beta1.Database) error {
+
+	log := log.FromContext(ctx)
+	if equality.Semantic.DeepEqual(original.Status, database.Status) {
+		return nil
+	}
+	err := r.Status().Update(ctx, database)
+	if err != nil {
+		log.Info("Database resource status update failed.")
+	}
+	return err
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"context"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func (r *resourceReconciler) defineInitConfigMap(database *databasesamplev1beta1.Database, scripts map[string]string) *corev1.ConfigMap {
+	configMap := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: getInitConfigMapName(database), Namespace: database.Namespace, Labels: getLabels(database)},
+		Data:       scripts,
+	}
+
+	ctrl.SetControllerReference(database, configMap, r.Scheme)
+	return configMap
+}
+
+func (r *resourceReconciler) reconcileInitConfigMap(ctx context.Context, database *databasesamplev1beta1.Database, scripts map[string]string) (ctrl.Result, error) {
+	initConfigMapName := getInitConfigMapName(database)
+	log := log.FromContext(ctx)
+	configMap := &corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Name: initConfigMapName, Namespace: database.Namespace}, configMap)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("ConfigMap resource " + initConfigMapName + " not found. Creating or re-creating config map")
+			err = r.Create(ctx, r.defineInitConfigMap(database, scripts))
+			if err != nil {
+				log.Info("Failed to create config map resource. Re-running reconcile.")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		}
+		log.Info("Failed to get config map resource " + initConfigMapName + ". Re-running reconcile.")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
@@ -3,32 +3,133 @@ package controllers
 import (
 	"context"
 
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
-	databasesamplev1alpha1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1alpha1"
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
 )
 
 type DatabaseReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// Note: Providers of the engines, see NewProviders. If not set, the providers of all supported engines are used.
+	Providers map[string]Provider
 }
 
 //+kubebuilder:rbac:groups=database.sample.third.party,resources=databases,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=database.sample.third.party,resources=databases/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=database.sample.third.party,resources=databases/finalizers,verbs=update
+//+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups="",resources=persistentvolumes,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=database.sample.third.party,resources=databasebackups,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=database.sample.third.party,resources=databaserestores,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 func (r *DatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 
 	log.Info("Reconcile started")
 
+	database := &databasesamplev1beta1.Database{}
+	err := r.Get(ctx, req.NamespacedName, database)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("Database resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		log.Info("Failed to get database resource. Re-running reconcile.")
+		return ctrl.Result{}, err
+	}
+	// Note: The status is also updated if resources cannot be reconciled, see condition Degraded
+	original := database.DeepCopy()
+	providerStatus, err := r.reconcileResources(ctx, database)
+	r.setStatus(database, providerStatus, err)
+	statusErr := r.updateStatus(ctx, original, database)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if statusErr != nil {
+		return ctrl.Result{}, statusErr
+	}
+	if database.Status.Upgrade != nil {
+		return ctrl.Result{RequeueAfter: upgradeRequeueInterval}, nil
+	}
+
 	return ctrl.Result{}, nil
 }
 
+// Note: The credentials, the connection secret, upgrades and password changes are the same for all engines, everything
+// else is done by the provider. Changes of the stateful set status trigger the reconciliation since the stateful set is owned.
+func (r *DatabaseReconciler) reconcileResources(ctx context.Context, database *databasesamplev1beta1.Database) (*ProviderStatus, error) {
+	provider, err := getProvider(r.Providers, database)
+	if err != nil {
+		return nil, err
+	}
+	_, err = r.reconcileInlineCredentials(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	_, err = r.reconcileCredentialsSecret(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	_, err = r.reconcileConnectionSecret(ctx, database, provider)
+	if err != nil {
+		return nil, err
+	}
+	version, err := r.reconcileUpgrade(ctx, database, provider)
+	if err != nil {
+		return nil, err
+	}
+	if version != "" {
+		err = provider.Provision(ctx, database, version)
+		if err != nil {
+			return nil, err
+		}
+	}
+	providerStatus, err := provider.Status(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	err = r.completeUpgrade(ctx, database, providerStatus)
+	if err != nil {
+		return providerStatus, err
+	}
+	_, err = r.reconcileUserPassword(ctx, database, provider)
+	return providerStatus, err
+}
+
+// Note: Changes of the status don't trigger the reconciliation, since the probe controller updates the connectivity in
+// the status of every database periodically. Deletions change the generation too.
 func (r *DatabaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Providers == nil {
+		r.Providers = NewProviders(mgr.GetClient(), mgr.GetScheme())
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&databasesamplev1alpha1.Database{}).
+		For(&databasesamplev1beta1.Database{}, builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{},
+			predicate.AnnotationChangedPredicate{}))).
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&corev1.Service{}).
+		Owns(&corev1.Secret{}).
+		Owns(&corev1.ConfigMap{}).
+		Owns(&databasesamplev1beta1.DatabaseBackup{}).
+		Owns(&databasesamplev1beta1.DatabaseRestore{}).
+		Owns(&batchv1.Job{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.findDatabasesForSecret)).
 		Complete(r)
 }
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const testTimeout = "10s"
+
+func newTestDatabase(name string, engine string) *databasesamplev1beta1.Database {
+	return &databasesamplev1beta1.Database{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       databasesamplev1beta1.DatabaseSpec{Engine: engine, User: "name"},
+	}
+}
+
+func getTestObject(name string, object client.Object) func() error {
+	return func() error {
+		return k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, object)
+	}
+}
+
+var _ = Describe("Database controller", func() {
+	// Note: The suite runs the fake provider, so the PostgreSQL provider is run by a reconciler of this spec
+	It("provisions a PostgreSQL instance which is owned by the database", func() {
+		database := newTestDatabase("provisioned-postgresql", databasesamplev1beta1.EnginePostgreSQL)
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		defer k8sClient.Delete(ctx, database)
+
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: scheme.Scheme, Providers: NewProviders(k8sClient, scheme.Scheme)}
+		request := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(database)}
+		Eventually(func() error {
+			_, err := reconciler.Reconcile(ctx, request)
+			return err
+		}, testTimeout).Should(Succeed())
+
+		statefulSet := &appsv1.StatefulSet{}
+		Expect(getTestObject(database.Name, statefulSet)()).To(Succeed())
+		Expect(metav1.IsControlledBy(statefulSet, database)).To(BeTrue())
+		container := statefulSet.Spec.Template.Spec.Containers[0]
+		Expect(container.Image).To(Equal(postgresImageRepository + ":" + postgresDefaultVersion))
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "DATABASE_USER", Value: "name"}))
+
+		for _, name := range []string{database.Name, database.Name + "-headless"} {
+			Expect(getTestObject(name, &corev1.Service{})()).To(Succeed())
+		}
+		configMap := &corev1.ConfigMap{}
+		Expect(getTestObject(database.Name+"-init", configMap)()).To(Succeed())
+		Expect(configMap.Data).To(HaveKeyWithValue(postgresInitScriptName, postgresInitScript))
+		adminSecret := &corev1.Secret{}
+		Expect(getTestObject(database.Name+"-admin", adminSecret)()).To(Succeed())
+		Expect(string(adminSecret.Data[secretKeyAdminUsername])).To(Equal(postgresAdminUser))
+	})
+
+	It("reports conditions, endpoint, version and connection secret in the status", func() {
+		database := newTestDatabase("status", databasesamplev1beta1.EnginePostgreSQL)
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		defer k8sClient.Delete(ctx, database)
+
+		Eventually(func() bool {
+			Expect(getTestObject(database.Name, database)()).To(Succeed())
+			return meta.IsStatusConditionTrue(database.Status.Conditions, CONDITION_TYPE_READY)
+		}, testTimeout).Should(BeTrue())
+		Expect(meta.IsStatusConditionFalse(database.Status.Conditions, CONDITION_TYPE_PROVISIONING)).To(BeTrue())
+		Expect(meta.IsStatusConditionFalse(database.Status.Conditions, CONDITION_TYPE_DEGRADED)).To(BeTrue())
+		Expect(database.Status.ObservedGeneration).To(Equal(database.Generation))
+		Expect(database.Status.Version).To(Equal(fakeDefaultVersion))
+		Expect(database.Status.Endpoint.Host).To(Equal("status.default.svc"))
+		Expect(database.Status.ConnectionSecret.Name).To(Equal("status-connection"))
+
+		// Note: Databases which stop accepting connections after they have been provisioned are degraded
+		fakeProvider.SetReady(false)
+		defer fakeProvider.SetReady(true)
+		database.Annotations = map[string]string{"test": "reconcile"}
+		Expect(k8sClient.Update(ctx, database)).To(Succeed())
+		Eventually(func() string {
+			Expect(getTestObject(database.Name, database)()).To(Succeed())
+			if condition := meta.FindStatusCondition(database.Status.Conditions, CONDITION_TYPE_DEGRADED); condition != nil {
+				return condition.Reason
+			}
+			return ""
+		}, testTimeout).Should(Equal(CONDITION_REASON_DEGRADED_UNAVAILABLE))
+		Expect(meta.IsStatusConditionFalse(database.Status.Conditions, CONDITION_TYPE_PROVISIONING)).To(BeTrue())
+	})
+
+	It("publishes the connection secret and changes the password when it is rotated", func() {
+		passwordSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "rotated-password", Namespace: "default"},
+			Data:       map[string][]byte{"password": []byte("old")},
+		}
+		Expect(k8sClient.Create(ctx, passwordSecret)).To(Succeed())
+		defer k8sClient.Delete(ctx, passwordSecret)
+		database := newTestDatabase("rotated", databasesamplev1beta1.EnginePostgreSQL)
+		database.Spec.PasswordSecretRef = &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: passwordSecret.Name},
+			Key:                  "password",
+		}
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		defer k8sClient.Delete(ctx, database)
+
+		connectionSecret := &corev1.Secret{}
+		Eventually(getTestObject("rotated-connection", connectionSecret), testTimeout).Should(Succeed())
+		Expect(connectionSecret.Type).To(Equal(corev1.SecretType("servicebinding.io/postgresql")))
+		Expect(string(connectionSecret.Data[bindingKeyUsername])).To(Equal("name"))
+		Expect(string(connectionSecret.Data[bindingKeyPassword])).To(Equal("old"))
+		Expect(string(connectionSecret.Data[bindingKeyHost])).To(Equal("rotated.default.svc"))
+
+		passwordSecret.Data["password"] = []byte("new")
+		Expect(k8sClient.Update(ctx, passwordSecret)).To(Succeed())
+		Eventually(func() string {
+			Expect(getTestObject("rotated-connection", connectionSecret)()).To(Succeed())
+			return string(connectionSecret.Data[bindingKeyPassword])
+		}, testTimeout).Should(Equal("new"))
+		Expect(getTestObject(database.Name, database)()).To(Succeed())
+		job := &batchv1.Job{}
+		Eventually(getTestObject(getPasswordJobName(database, []byte("new")), job), testTimeout).Should(Succeed())
+		Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: UserNameEnvName, Value: "name"}))
+	})
+
+	It("runs databases of every engine by the provider of the engine", func() {
+		database := newTestDatabase("provisioned-mysql", databasesamplev1beta1.EngineMySQL)
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		defer k8sClient.Delete(ctx, database)
+		Eventually(func() bool {
+			return fakeProvider.IsProvisioned(client.ObjectKeyFromObject(database))
+		}, testTimeout).Should(BeTrue())
+
+		reconciler := &DatabaseReconciler{Client: k8sClient, Scheme: scheme.Scheme, Providers: NewProviders(k8sClient, scheme.Scheme)}
+		request := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(database)}
+		Eventually(func() error {
+			_, err := reconciler.Reconcile(ctx, request)
+			return err
+		}, testTimeout).Should(Succeed())
+		statefulSet := &appsv1.StatefulSet{}
+		Expect(getTestObject(database.Name, statefulSet)()).To(Succeed())
+		container := statefulSet.Spec.Template.Spec.Containers[0]
+		Expect(container.Image).To(Equal(mysqlImageRepository + ":" + mysqlDefaultVersion))
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "MYSQL_USER", Value: "name"}))
+		Expect(getTestObject(database.Name+"-init", &corev1.ConfigMap{})()).NotTo(Succeed())
+	})
+})
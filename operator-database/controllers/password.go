@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const passwordLabel = "database.sample.third.party/password"
+
+// Note: The name of the job changes with the user and its password, so that every rotation runs a new job
+func getPasswordJobName(database *databasesamplev1beta1.Database, password []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(database.Spec.User + "\n"))
+	hash.Write(password)
+	return database.Name + "-password-" + hex.EncodeToString(hash.Sum(nil))[:userJobHashLength]
+}
+
+// Note: The password is read from the referenced secret, so that it is not part of the job
+func (r *DatabaseReconciler) definePasswordJob(database *databasesamplev1beta1.Database, name string, hooks *UserHooks) *batchv1.Job {
+	labels := map[string]string{passwordLabel: database.Name}
+	backoffLimit := backupJobBackoffLimit
+	passwordRef := database.GetPasswordSecretRef()
+	env := append([]corev1.EnvVar{}, hooks.Env...)
+	env = append(env,
+		corev1.EnvVar{Name: UserNameEnvName, Value: database.Spec.User},
+		secretEnvVar(UserPasswordEnvName, passwordRef.Name, passwordRef.Key),
+	)
+	container := corev1.Container{
+		Name:    "password",
+		Image:   hooks.Image,
+		Command: hooks.PasswordCommand,
+		Env:     env,
+	}
+
+	job := &batchv1.Job{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: database.Namespace, Labels: labels},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       getJobPodSpec(nil, container, nil),
+			},
+		},
+	}
+
+	ctrl.SetControllerReference(database, job, r.Scheme)
+	return job
+}
+
+// Note: The password of the user of the spec is set when the data directory is initialized. When the referenced
+// password is rotated later, a job changes the password in the database. The job of the current password is kept, so
+// that it is not run again. Databases which are not ready or are being upgraded are changed afterwards.
+func (r *DatabaseReconciler) reconcileUserPassword(ctx context.Context, database *databasesamplev1beta1.Database, provider Provider) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	if !meta.IsStatusConditionTrue(database.Status.Conditions, CONDITION_TYPE_READY) || database.Status.Upgrade != nil {
+		return ctrl.Result{}, nil
+	}
+	passwordRef := database.GetPasswordSecretRef()
+	password, err := readSecretKey(ctx, r, database.Namespace, passwordRef.Name, passwordRef.Key)
+	if err != nil {
+		log.Info("Failed to read password. Re-running reconcile.")
+		return ctrl.Result{}, err
+	}
+	jobName := getPasswordJobName(database, password)
+
+	job := &batchv1.Job{}
+	err = r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: database.Namespace}, job)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("Job resource " + jobName + " not found. Changing password of user " + database.Spec.User)
+			err = r.Create(ctx, r.definePasswordJob(database, jobName, provider.UserHooks(database)))
+			if err != nil {
+				log.Info("Failed to create job resource. Re-running reconcile.")
+			}
+			return ctrl.Result{}, err
+		}
+		log.Info("Failed to get job resource " + jobName + ". Re-running reconcile.")
+		return ctrl.Result{}, err
+	}
+	finished, succeeded := getJobResult(job)
+	switch {
+	case !finished:
+		return ctrl.Result{}, nil
+	case !succeeded:
+		return ctrl.Result{}, fmt.Errorf("password of user %s could not be changed, delete the job %s to retry it", database.Spec.User, jobName)
+	}
+	return ctrl.Result{}, r.deleteOutdatedPasswordJobs(ctx, database, jobName)
+}
+
+func (r *DatabaseReconciler) deleteOutdatedPasswordJobs(ctx context.Context, database *databasesamplev1beta1.Database, currentJobName string) error {
+	jobs := &batchv1.JobList{}
+	err := r.List(ctx, jobs, client.InNamespace(database.Namespace), client.MatchingLabels{passwordLabel: database.Name})
+	if err != nil {
+		return err
+	}
+	for i := range jobs.Items {
+		if jobs.Items[i].Name == currentJobName {
+			continue
+		}
+		err = r.Delete(ctx, &jobs.Items[i], client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Note: Probes authenticate with the entries of the connection secret, like clients of the database
+type ProbeCredentials struct {
+	Username string
+	Password string
+	Database string
+}
+
+// Note: The reason is used for the condition Reachable. Errors of providers without reason are failed queries.
+type probeError struct {
+	reason string
+	err    error
+}
+
+func (e *probeError) Error() string {
+	return e.err.Error()
+}
+
+func newProbeError(reason string, format string, args ...interface{}) error {
+	return &probeError{reason: reason, err: fmt.Errorf(format, args...)}
+}
+
+func getProbeErrorReason(err error) string {
+	var probeErr *probeError
+	if errors.As(err, &probeErr) {
+		return probeErr.reason
+	}
+	if isTLSHandshakeError(err) {
+		return CONDITION_REASON_TLS_HANDSHAKE_FAILED
+	}
+	return CONDITION_REASON_QUERY_FAILED
+}
+
+// probeDialer opens the connections of the drivers, so that failed connections can be told apart from failed queries.
+// The deadline covers the TLS handshake and the query, so that unresponsive databases don't block the controller.
+type probeDialer struct{}
+
+func (d probeDialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: probeTimeout}
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, newProbeError(CONDITION_REASON_CONNECT_FAILED, "connecting to %s failed: %v", address, err)
+	}
+	err = conn.SetDeadline(time.Now().Add(probeTimeout))
+	if err != nil {
+		conn.Close()
+		return nil, newProbeError(CONDITION_REASON_CONNECT_FAILED, "connecting to %s failed: %v", address, err)
+	}
+	return conn, nil
+}
+
+func (d probeDialer) Dial(network string, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// Note: Connections always time out after probeTimeout
+func (d probeDialer) DialTimeout(network string, address string, timeout time.Duration) (net.Conn, error) {
+	return d.Dial(network, address)
+}
+
+// Note: The drivers return the errors of the TLS handshake unchanged
+func isTLSHandshakeError(err error) bool {
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certificateInvalidErr x509.CertificateInvalidError
+	var recordHeaderErr tls.RecordHeaderError
+	return errors.As(err, &unknownAuthorityErr) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &certificateInvalidErr) || errors.As(err, &recordHeaderErr)
+}
+
+// Note: The CA certificate is checked before the probe, so that invalid certificates aren't reported as failed handshakes
+func checkProbeCACertificate(caCertificate []byte) error {
+	if caCertificate != nil && !x509.NewCertPool().AppendCertsFromPEM(caCertificate) {
+		return fmt.Errorf("CA certificate doesn't contain PEM encoded certificates")
+	}
+	return nil
+}
+
+// Note: nil is returned for databases without CA certificate, they are probed without TLS
+func newProbeTLSConfig(host string, caCertificate []byte) (*tls.Config, error) {
+	if caCertificate == nil {
+		return nil, nil
+	}
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(caCertificate) {
+		return nil, fmt.Errorf("CA certificate doesn't contain PEM encoded certificates")
+	}
+	return &tls.Config{RootCAs: certPool, ServerName: host, MinVersion: tls.VersionTLS12}, nil
+}
+
+// probeDatabase runs SELECT 1 with the connector of the provider. The latency is measured from connecting until the
+// result of the query has been received.
+func probeDatabase(ctx context.Context, provider Provider, address string, caCertificate []byte,
+	credentials ProbeCredentials) (time.Duration, error) {
+
+	connector, err := provider.ProbeConnector(address, caCertificate, credentials)
+	if err != nil {
+		return 0, newProbeError(CONDITION_REASON_PROBE_NOT_POSSIBLE, "%v", err)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	start := time.Now()
+	var result int
+	err = db.QueryRowContext(ctx, "SELECT 1").Scan(&result)
+	if err != nil {
+		return 0, err
+	}
+	if result != 1 {
+		return 0, fmt.Errorf("unexpected result of SELECT 1: %d", result)
+	}
+	return time.Since(start), nil
+}
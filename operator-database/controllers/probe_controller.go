@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// Note: Every database is probed in this interval, probes are canceled after the timeout
+const probeInterval = 30 * time.Second
+const probeTimeout = 5 * time.Second
+
+// Note: Probes wait for the network, so several databases are probed in parallel. Otherwise databases which don't
+// answer would delay the probes of all other databases by the timeout.
+const probeMaxConcurrentReconciles = 4
+
+// DatabaseProbeReconciler connects to the endpoints of databases periodically and reports the result in the status
+type DatabaseProbeReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Note: Providers of the engines, see NewProviders. If not set, the providers of all supported engines are used.
+	Providers map[string]Provider
+}
+
+//+kubebuilder:rbac:groups=database.sample.third.party,resources=databases,verbs=get;list;watch
+//+kubebuilder:rbac:groups=database.sample.third.party,resources=databases/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+func (r *DatabaseProbeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	database := &databasesamplev1beta1.Database{}
+	err := r.Get(ctx, req.NamespacedName, database)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("Database resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		log.Info("Failed to get database resource. Re-running reconcile.")
+		return ctrl.Result{}, err
+	}
+	if !database.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	original := database.DeepCopy()
+	r.probe(ctx, database)
+	err = r.updateProbeStatus(ctx, original, database)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: probeInterval}, nil
+}
+
+// Note: Databases are probed after the database controller has published the endpoint and the connection secret
+func (r *DatabaseProbeReconciler) probe(ctx context.Context, database *databasesamplev1beta1.Database) {
+	log := log.FromContext(ctx)
+	endpoint := database.Status.Endpoint
+	if endpoint == nil || database.Status.ConnectionSecret == nil {
+		r.setReachableCondition(database, CONDITION_STATUS_FALSE, CONDITION_REASON_ENDPOINT_UNKNOWN, CONDITION_MESSAGE_ENDPOINT_UNKNOWN)
+		return
+	}
+	provider, err := getProvider(r.Providers, database)
+	if err != nil {
+		r.setReachableCondition(database, CONDITION_STATUS_FALSE, CONDITION_REASON_PROBE_NOT_POSSIBLE, err.Error())
+		return
+	}
+	credentials, caCertificate, err := r.readProbeCredentials(ctx, database)
+	if err != nil {
+		r.setReachableCondition(database, CONDITION_STATUS_FALSE, CONDITION_REASON_PROBE_NOT_POSSIBLE, err.Error())
+		return
+	}
+	err = checkProbeCACertificate(caCertificate)
+	if err != nil {
+		r.setReachableCondition(database, CONDITION_STATUS_FALSE, CONDITION_REASON_PROBE_NOT_POSSIBLE, err.Error())
+		return
+	}
+
+	probeTime := metav1.Now()
+	address := net.JoinHostPort(endpoint.Host, strconv.Itoa(int(endpoint.Port)))
+	latency, err := probeDatabase(ctx, provider, address, caCertificate, credentials)
+	if database.Status.Connectivity == nil {
+		database.Status.Connectivity = &databasesamplev1beta1.DatabaseConnectivity{}
+	}
+	database.Status.Connectivity.LastProbeTime = probeTime
+	if err != nil {
+		log.Info("Database "+database.Name+" is not reachable.", "error", err.Error())
+		r.setReachableCondition(database, CONDITION_STATUS_FALSE, getProbeErrorReason(err), err.Error())
+		return
+	}
+	database.Status.Connectivity.LastSuccessTime = &probeTime
+	database.Status.Connectivity.Latency = &metav1.Duration{Duration: latency.Round(time.Microsecond)}
+	r.setReachableCondition(database, CONDITION_STATUS_TRUE, CONDITION_REASON_REACHABLE, CONDITION_MESSAGE_REACHABLE)
+}
+
+// Note: The CA certificate is optional, it is only part of the connection secret if the database has a TLS secret
+func (r *DatabaseProbeReconciler) readProbeCredentials(ctx context.Context,
+	database *databasesamplev1beta1.Database) (ProbeCredentials, []byte, error) {
+
+	secretName := database.Status.ConnectionSecret.Name
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: database.Namespace}, secret)
+	if err != nil {
+		return ProbeCredentials{}, nil, fmt.Errorf("secret %s cannot be read: %v", secretName, err)
+	}
+	for _, key := range []string{bindingKeyUsername, bindingKeyPassword, bindingKeyDatabase} {
+		if _, ok := secret.Data[key]; !ok {
+			return ProbeCredentials{}, nil, fmt.Errorf("secret %s doesn't contain the key %s", secretName, key)
+		}
+	}
+	credentials := ProbeCredentials{
+		Username: string(secret.Data[bindingKeyUsername]),
+		Password: string(secret.Data[bindingKeyPassword]),
+		Database: string(secret.Data[bindingKeyDatabase]),
+	}
+	return credentials, secret.Data[bindingKeyCACertificate], nil
+}
+
+func (r *DatabaseProbeReconciler) setReachableCondition(database *databasesamplev1beta1.Database,
+	status metav1.ConditionStatus, reason string, message string) {
+
+	meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{
+		Type:               CONDITION_TYPE_REACHABLE,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: database.Generation,
+	})
+}
+
+// Note: The status is only updated if it has changed. The database controller updates the same status, conflicts
+// re-run the probe.
+func (r *DatabaseProbeReconciler) updateProbeStatus(ctx context.Context, original *databasesamplev1beta1.Database,
+	database *databasesamplev1beta1.Database) error {
+
+	log := log.FromContext(ctx)
+	if equality.Semantic.DeepEqual(original.Status, database.Status) {
+		return nil
+	}
+	err := r.Status().Update(ctx, database)
+	if err != nil {
+		log.Info("Database resource status update failed.")
+	}
+	return err
+}
+
+// Note: Probes run periodically. Changes of the spec trigger them immediately, changes of the status don't, since every
+// probe changes the status.
+func (r *DatabaseProbeReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Providers == nil {
+		r.Providers = NewProviders(mgr.GetClient(), mgr.GetScheme())
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("databaseprobe").
+		For(&databasesamplev1beta1.Database{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: probeMaxConcurrentReconciles}).
+		Complete(r)
+}
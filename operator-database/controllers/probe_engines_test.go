@@ -0,0 +1,308 @@
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func runEngineProbe(provider Provider, port int32, caCertificate []byte, password string) error {
+	address := net.JoinHostPort("localhost", strconv.Itoa(int(port)))
+	credentials := ProbeCredentials{Username: "name", Password: password, Database: "database"}
+	_, err := probeDatabase(ctx, provider, address, caCertificate, credentials)
+	return err
+}
+
+type postgresStandIn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func (s *postgresStandIn) readMessage(typed bool) (byte, []byte) {
+	var messageType byte
+	if typed {
+		messageType, _ = s.reader.ReadByte()
+	}
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(s.reader, header); err != nil {
+		return 0, nil
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(header)-4)
+	io.ReadFull(s.reader, payload)
+	return messageType, payload
+}
+
+func (s *postgresStandIn) writeMessage(messageType byte, payload ...[]byte) {
+	message := bytes.Join(payload, nil)
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(message)+4))
+	s.conn.Write(append(append([]byte{messageType}, length...), message...))
+}
+
+// Note: The stand-in offers TLS if it has a server config and authenticates with MD5 passwords like PostgreSQL
+func handlePostgreSQLProbe(serverConfig *tls.Config, password string) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		standIn := &postgresStandIn{conn: conn, reader: bufio.NewReader(conn)}
+		_, payload := standIn.readMessage(false)
+		if len(payload) == 4 && binary.BigEndian.Uint32(payload) == 80877103 {
+			if serverConfig == nil {
+				conn.Write([]byte("N"))
+			} else {
+				conn.Write([]byte("S"))
+				tlsConn := tls.Server(conn, serverConfig)
+				if tlsConn.Handshake() != nil {
+					return
+				}
+				standIn = &postgresStandIn{conn: tlsConn, reader: bufio.NewReader(tlsConn)}
+			}
+			_, payload = standIn.readMessage(false)
+		}
+		if len(payload) < 4 {
+			return
+		}
+		parameters := strings.Split(string(payload[4:]), "\x00")
+		user := ""
+		for i := 0; i+1 < len(parameters); i += 2 {
+			if parameters[i] == "user" {
+				user = parameters[i+1]
+			}
+		}
+
+		salt := []byte{1, 2, 3, 4}
+		standIn.writeMessage('R', []byte{0, 0, 0, 5}, salt)
+		_, response := standIn.readMessage(true)
+		inner := md5.Sum([]byte(password + user))
+		outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), salt...))
+		if string(response) != "md5"+hex.EncodeToString(outer[:])+"\x00" {
+			standIn.writeMessage('E', []byte("SFATAL\x00C28P01\x00Mpassword authentication failed for user \""+user+"\"\x00\x00"))
+			return
+		}
+		standIn.writeMessage('R', []byte{0, 0, 0, 0})
+		standIn.writeMessage('S', []byte("server_version\x0014.10\x00"))
+		standIn.writeMessage('Z', []byte("I"))
+
+		_, query := standIn.readMessage(true)
+		if string(query) != "SELECT 1\x00" {
+			return
+		}
+		standIn.writeMessage('T', []byte{0, 1}, []byte("?column?\x00"), []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 23, 0, 4, 0xff, 0xff, 0xff, 0xff, 0, 0})
+		standIn.writeMessage('D', []byte{0, 1, 0, 0, 0, 1, '1'})
+		standIn.writeMessage('C', []byte("SELECT 1\x00"))
+		standIn.writeMessage('Z', []byte("I"))
+		standIn.readMessage(true)
+	}
+}
+
+type mysqlStandIn struct {
+	conn     net.Conn
+	sequence byte
+}
+
+func (s *mysqlStandIn) readPacket() ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(s.conn, header); err != nil {
+		return nil, err
+	}
+	payload := make([]byte, int(header[0])|int(header[1])<<8|int(header[2])<<16)
+	_, err := io.ReadFull(s.conn, payload)
+	s.sequence = header[3] + 1
+	return payload, err
+}
+
+func (s *mysqlStandIn) writePacket(payload []byte) {
+	length := len(payload)
+	s.conn.Write(append([]byte{byte(length), byte(length >> 8), byte(length >> 16), s.sequence}, payload...))
+	s.sequence++
+}
+
+func readNullTerminated(reader *bytes.Reader) string {
+	value := &strings.Builder{}
+	for {
+		b, err := reader.ReadByte()
+		if err != nil || b == 0 {
+			return value.String()
+		}
+		value.WriteByte(b)
+	}
+}
+
+// Note: The stand-in offers TLS if it has a server config, authenticates with the plugin and answers SELECT 1 with a
+// result set. Received packets which contain the password are passed to the channel, so it must not be part of the
+// plugin name.
+func handleMySQLProbe(serverConfig *tls.Config, password string, plugin string, received chan<- string) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		standIn := &mysqlStandIn{conn: conn}
+		scramble := []byte("abcdefghijklmnopqrst")
+		capabilities := uint32(0x0200 | 0x8000 | 0x00080000 | 0x0008)
+		if serverConfig != nil {
+			capabilities |= 0x0800
+		}
+		greeting := &bytes.Buffer{}
+		greeting.WriteByte(10)
+		greeting.WriteString("8.0.36\x00")
+		greeting.Write([]byte{1, 0, 0, 0})
+		greeting.Write(scramble[:8])
+		greeting.WriteByte(0)
+		greeting.Write([]byte{byte(capabilities), byte(capabilities >> 8), 45, 2, 0,
+			byte(capabilities >> 16), byte(capabilities >> 24), 21})
+		greeting.Write(make([]byte, 10))
+		greeting.Write(scramble[8:])
+		greeting.WriteString("\x00" + plugin + "\x00")
+		standIn.writePacket(greeting.Bytes())
+
+		response, err := standIn.readPacket()
+		if err != nil {
+			return
+		}
+		if len(response) == 32 && serverConfig != nil {
+			tlsConn := tls.Server(conn, serverConfig)
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			standIn.conn = tlsConn
+			response, err = standIn.readPacket()
+			if err != nil {
+				return
+			}
+		}
+		if strings.Contains(string(response), password) {
+			received <- string(response)
+		}
+		if len(response) < 33 {
+			return
+		}
+		reader := bytes.NewReader(response[32:])
+		username := readNullTerminated(reader)
+		authLength, _ := reader.ReadByte()
+		authResponse := make([]byte, authLength)
+		reader.Read(authResponse)
+		database := readNullTerminated(reader)
+
+		// Note: The server only knows SHA1(SHA1(password)) and recovers SHA1(password) from the response
+		hash := sha1.Sum([]byte(password))
+		storedHash := sha1.Sum(hash[:])
+		salted := sha1.Sum(append(append([]byte{}, scramble...), storedHash[:]...))
+		recovered := make([]byte, len(authResponse))
+		for i := range authResponse {
+			recovered[i] = authResponse[i] ^ salted[i%len(salted)]
+		}
+		authenticated := sha1.Sum(recovered) == storedHash
+		if plugin == "mysql_clear_password" {
+			authenticated = string(authResponse) == password+"\x00"
+		}
+		if username != "name" || database != "database" || !authenticated {
+			standIn.writePacket(append([]byte{0xff, 0x15, 0x04}, "#28000Access denied for user 'name'"...))
+			return
+		}
+		standIn.writePacket([]byte{0x00, 0, 0, 2, 0, 0, 0})
+
+		query, err := standIn.readPacket()
+		if err != nil || string(query) != "\x03SELECT 1" {
+			return
+		}
+		standIn.writePacket([]byte{1})
+		standIn.writePacket([]byte("\x03def\x00\x00\x00\x011\x00\x0c\x3f\x00\x01\x00\x00\x00\x08\x81\x00\x00\x00\x00"))
+		standIn.writePacket([]byte{0xfe, 0, 0, 2, 0})
+		standIn.writePacket([]byte{1, '1'})
+		standIn.writePacket([]byte{0xfe, 0, 0, 2, 0})
+		standIn.readPacket()
+	}
+}
+
+var _ = Describe("Database probes of the engines", func() {
+	var certificates *testCertificates
+	var otherCertificates *testCertificates
+
+	BeforeEach(func() {
+		certificates = newTestCertificates()
+		otherCertificates = newTestCertificates()
+	})
+
+	It("runs SELECT 1 with the PostgreSQL driver", func() {
+		provider := &postgreSQLProvider{}
+		for _, test := range []struct {
+			name          string
+			serverConfig  *tls.Config
+			caCertificate []byte
+			password      string
+			reason        string
+		}{
+			{"tcp", nil, nil, "password", ""},
+			{"tls", certificates.serverConfig, certificates.caCertificate, "password", ""},
+			{"tls not offered", nil, certificates.caCertificate, "password", ""},
+			{"unknown CA", certificates.serverConfig, otherCertificates.caCertificate, "password", CONDITION_REASON_TLS_HANDSHAKE_FAILED},
+			{"wrong password", nil, nil, "wrong", CONDITION_REASON_QUERY_FAILED},
+		} {
+			By(test.name)
+			port, stop := startStandIn(handlePostgreSQLProbe(test.serverConfig, "password"))
+			err := runEngineProbe(provider, port, test.caCertificate, test.password)
+			stop()
+			if test.reason == "" {
+				Expect(err).NotTo(HaveOccurred())
+				continue
+			}
+			Expect(err).To(HaveOccurred())
+			Expect(getProbeErrorReason(err)).To(Equal(test.reason))
+		}
+
+		port, stop := startStandIn(handlePostgreSQLProbe(nil, "password"))
+		defer stop()
+		err := runEngineProbe(provider, port, nil, "wrong")
+		Expect(err).To(MatchError(ContainSubstring("password authentication failed")))
+	})
+
+	It("runs SELECT 1 with the MySQL driver", func() {
+		provider := &mySQLProvider{}
+		received := make(chan string, 10)
+		for _, test := range []struct {
+			name          string
+			serverConfig  *tls.Config
+			caCertificate []byte
+			password      string
+			reason        string
+		}{
+			{"tcp", nil, nil, "s3cret", ""},
+			{"tls", certificates.serverConfig, certificates.caCertificate, "s3cret", ""},
+			{"tls not offered", nil, certificates.caCertificate, "s3cret", ""},
+			{"unknown CA", certificates.serverConfig, otherCertificates.caCertificate, "s3cret", CONDITION_REASON_TLS_HANDSHAKE_FAILED},
+			{"wrong password", nil, nil, "wrong", CONDITION_REASON_QUERY_FAILED},
+		} {
+			By(test.name)
+			port, stop := startStandIn(handleMySQLProbe(test.serverConfig, "s3cret", "mysql_native_password", received))
+			err := runEngineProbe(provider, port, test.caCertificate, test.password)
+			stop()
+			if test.reason == "" {
+				Expect(err).NotTo(HaveOccurred())
+				continue
+			}
+			Expect(err).To(HaveOccurred())
+			Expect(getProbeErrorReason(err)).To(Equal(test.reason))
+		}
+
+		port, stop := startStandIn(handleMySQLProbe(nil, "s3cret", "mysql_native_password", received))
+		defer stop()
+		err := runEngineProbe(provider, port, nil, "wrong")
+		Expect(err).To(MatchError(ContainSubstring("Access denied")))
+		Expect(received).To(BeEmpty())
+	})
+
+	It("doesn't send passwords to MySQL databases in clear text", func() {
+		received := make(chan string, 1)
+		port, stop := startStandIn(handleMySQLProbe(nil, "s3cret", "mysql_clear_password", received))
+		defer stop()
+		err := runEngineProbe(&mySQLProvider{}, port, nil, "s3cret")
+		Expect(err).To(MatchError(ContainSubstring("Access denied")))
+		Expect(received).To(BeEmpty())
+	})
+})
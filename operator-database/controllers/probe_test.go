@@ -0,0 +1,283 @@
+package controllers
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql/driver"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Note: The stand-ins listen on localhost and use a certificate for localhost which is signed by their own CA
+type testCertificates struct {
+	caCertificate []byte
+	serverConfig  *tls.Config
+}
+
+func newTestCertificates() *testCertificates {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "database-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	Expect(err).NotTo(HaveOccurred())
+	serverKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	serverTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	serverDER, err := x509.CreateCertificate(rand.Reader, serverTemplate, caTemplate, &serverKey.PublicKey, caKey)
+	Expect(err).NotTo(HaveOccurred())
+	return &testCertificates{
+		caCertificate: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		serverConfig: &tls.Config{Certificates: []tls.Certificate{{
+			Certificate: [][]byte{serverDER},
+			PrivateKey:  serverKey,
+		}}},
+	}
+}
+
+// startStandIn serves every connection with the handler and returns the port and the function which stops it
+func startStandIn(handle func(conn net.Conn)) (int32, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.SetDeadline(time.Now().Add(probeTimeout))
+				handle(conn)
+			}()
+		}
+	}()
+	return int32(listener.Addr().(*net.TCPAddr).Port), func() { listener.Close() }
+}
+
+func getClosedPort() int32 {
+	port, stop := startStandIn(func(conn net.Conn) {})
+	stop()
+	return port
+}
+
+// Note: The stand-in of the fake provider answers with 1 if the credentials match. It only offers TLS if it has a
+// server config.
+func handleFakeProbe(serverConfig *tls.Config, password string) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		reader := bufio.NewReader(conn)
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if line == "TLS\n" {
+			if serverConfig == nil {
+				conn.Write([]byte("N\n"))
+			} else {
+				conn.Write([]byte("S\n"))
+				tlsConn := tls.Server(conn, serverConfig)
+				if tlsConn.Handshake() != nil {
+					return
+				}
+				conn = tlsConn
+				reader = bufio.NewReader(conn)
+			}
+			line, err = reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+		}
+		if strings.HasPrefix(line, "SELECT 1 name "+password+" ") {
+			conn.Write([]byte("1\n"))
+		} else {
+			conn.Write([]byte("ERROR: password authentication failed\n"))
+		}
+	}
+}
+
+// Note: The endpoints of the databases don't resolve in envtest, so the probes connect to the stand-in on localhost
+type standInProvider struct {
+	Provider
+	port int32
+}
+
+func (p *standInProvider) ProbeConnector(address string, caCertificate []byte, credentials ProbeCredentials) (driver.Connector, error) {
+	return p.Provider.ProbeConnector(net.JoinHostPort("localhost", strconv.Itoa(int(p.port))), caCertificate, credentials)
+}
+
+// createProbedDatabase creates a database and waits until the database controller has published the endpoint and the
+// connection secret. The password of the connection secret is returned, so that the stand-ins can check it.
+func createProbedDatabase(name string, caCertificate []byte) (*databasesamplev1beta1.Database, string) {
+	database := newTestDatabase(name, databasesamplev1beta1.EnginePostgreSQL)
+	if caCertificate != nil {
+		tlsSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name + "-ca", Namespace: "default"},
+			Data:       map[string][]byte{databasesamplev1beta1.CACertificateSecretKey: caCertificate},
+		}
+		Expect(k8sClient.Create(ctx, tlsSecret)).To(Succeed())
+		database.Spec.TLSSecretRef = &corev1.LocalObjectReference{Name: tlsSecret.Name}
+	}
+	Expect(k8sClient.Create(ctx, database)).To(Succeed())
+
+	connectionSecret := &corev1.Secret{}
+	Eventually(func() bool {
+		if getTestObject(database.Name, database)() != nil || database.Status.ConnectionSecret == nil {
+			return false
+		}
+		if getTestObject(database.Status.ConnectionSecret.Name, connectionSecret)() != nil {
+			return false
+		}
+		_, ok := connectionSecret.Data[bindingKeyCACertificate]
+		return ok == (caCertificate != nil)
+	}, testTimeout).Should(BeTrue())
+	return database, string(connectionSecret.Data[bindingKeyPassword])
+}
+
+// runTestProbe probes the database with the provider and returns the database with the new status
+// Note: The database controller updates the same status, so conflicts are retried
+func runTestProbe(database *databasesamplev1beta1.Database, provider Provider) *databasesamplev1beta1.Database {
+	reconciler := &DatabaseProbeReconciler{
+		Client:    k8sClient,
+		Scheme:    scheme.Scheme,
+		Providers: map[string]Provider{database.Spec.Engine: provider},
+	}
+	request := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(database)}
+	Eventually(func() error {
+		result, err := reconciler.Reconcile(ctx, request)
+		Expect(result.RequeueAfter).To(Or(Equal(probeInterval), BeZero()))
+		return err
+	}, testTimeout).Should(Succeed())
+	probed := &databasesamplev1beta1.Database{}
+	Expect(getTestObject(database.Name, probed)()).To(Succeed())
+	return probed
+}
+
+func getReachableReason(database *databasesamplev1beta1.Database) string {
+	condition := meta.FindStatusCondition(database.Status.Conditions, CONDITION_TYPE_REACHABLE)
+	if condition == nil {
+		return ""
+	}
+	return condition.Reason
+}
+
+var _ = Describe("Database probe controller", func() {
+	It("records the latency of reachable databases and keeps the last success", func() {
+		database, password := createProbedDatabase("probed", nil)
+		defer k8sClient.Delete(ctx, database)
+		port, stop := startStandIn(handleFakeProbe(nil, password))
+		defer stop()
+
+		database = runTestProbe(database, &standInProvider{Provider: fakeProvider, port: port})
+		Expect(meta.IsStatusConditionTrue(database.Status.Conditions, CONDITION_TYPE_REACHABLE)).To(BeTrue())
+		connectivity := database.Status.Connectivity
+		Expect(connectivity).NotTo(BeNil())
+		Expect(connectivity.Latency).NotTo(BeNil())
+		Expect(connectivity.LastSuccessTime).NotTo(BeNil())
+		Expect(connectivity.LastSuccessTime.Equal(&connectivity.LastProbeTime)).To(BeTrue())
+
+		// Note: The last success and its latency are kept when the database stops accepting connections
+		lastSuccessTime := connectivity.LastSuccessTime
+		time.Sleep(time.Second)
+		database = runTestProbe(database, &standInProvider{Provider: fakeProvider, port: getClosedPort()})
+		Expect(getReachableReason(database)).To(Equal(CONDITION_REASON_CONNECT_FAILED))
+		Expect(database.Status.Connectivity.Latency).NotTo(BeNil())
+		Expect(database.Status.Connectivity.LastSuccessTime.Equal(lastSuccessTime)).To(BeTrue())
+		Expect(database.Status.Connectivity.LastProbeTime.After(lastSuccessTime.Time)).To(BeTrue())
+	})
+
+	It("reports why databases are not reachable", func() {
+		certificates := newTestCertificates()
+		otherCertificates := newTestCertificates()
+		for _, test := range []struct {
+			name          string
+			serverConfig  *tls.Config
+			caCertificate []byte
+			wrongPassword bool
+			closed        bool
+			reason        string
+		}{
+			{"tcp", nil, nil, false, false, CONDITION_REASON_REACHABLE},
+			{"tls", certificates.serverConfig, certificates.caCertificate, false, false, CONDITION_REASON_REACHABLE},
+			{"connection-refused", nil, nil, false, true, CONDITION_REASON_CONNECT_FAILED},
+			{"unknown-ca", certificates.serverConfig, otherCertificates.caCertificate, false, false, CONDITION_REASON_TLS_HANDSHAKE_FAILED},
+			{"wrong-password", nil, nil, true, false, CONDITION_REASON_QUERY_FAILED},
+			{"invalid-ca", nil, []byte("certificate"), false, false, CONDITION_REASON_PROBE_NOT_POSSIBLE},
+		} {
+			By(test.name)
+			database, password := createProbedDatabase("probe-"+test.name, test.caCertificate)
+			if test.wrongPassword {
+				password = "wrong"
+			}
+			port, stop := startStandIn(handleFakeProbe(test.serverConfig, password))
+			if test.closed {
+				stop()
+			}
+			database = runTestProbe(database, &standInProvider{Provider: fakeProvider, port: port})
+			stop()
+			Expect(k8sClient.Delete(ctx, database)).To(Succeed())
+			Expect(getReachableReason(database)).To(Equal(test.reason))
+		}
+	})
+
+	// Note: The databases which the operator provisions don't get the TLS secret, so they may not offer TLS
+	It("probes databases with a TLS secret without TLS if they don't offer it", func() {
+		certificates := newTestCertificates()
+		database, password := createProbedDatabase("probe-tls-not-offered", certificates.caCertificate)
+		defer k8sClient.Delete(ctx, database)
+
+		port, stop := startStandIn(handlePostgreSQLProbe(nil, password))
+		defer stop()
+		database = runTestProbe(database, &standInProvider{Provider: &postgreSQLProvider{}, port: port})
+		Expect(getReachableReason(database)).To(Equal(CONDITION_REASON_REACHABLE))
+
+		port, stop = startStandIn(handlePostgreSQLProbe(certificates.serverConfig, password))
+		defer stop()
+		database = runTestProbe(database, &standInProvider{Provider: &postgreSQLProvider{}, port: port})
+		Expect(getReachableReason(database)).To(Equal(CONDITION_REASON_REACHABLE))
+	})
+
+	It("waits for the endpoint and the connection secret", func() {
+		database := newTestDatabase("unprobed", databasesamplev1beta1.EnginePostgreSQL)
+		reconciler := &DatabaseProbeReconciler{Client: k8sClient, Scheme: scheme.Scheme, Providers: map[string]Provider{
+			databasesamplev1beta1.EnginePostgreSQL: fakeProvider,
+		}}
+		reconciler.probe(ctx, database)
+		Expect(getReachableReason(database)).To(Equal(CONDITION_REASON_ENDPOINT_UNKNOWN))
+		Expect(database.Status.Connectivity).To(BeNil())
+	})
+})
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Provider is implemented by every database engine. The reconciler only uses providers, so that new engines can be
+// added by implementing this interface and registering them in NewProviders.
+type Provider interface {
+	// Provision creates the resources which run the database with the given version of the engine
+	Provision(ctx context.Context, database *databasesamplev1beta1.Database, version string) error
+
+	// Status reports whether the database accepts connections and which version it runs, if it has been provisioned
+	Status(ctx context.Context, database *databasesamplev1beta1.Database) (*ProviderStatus, error)
+
+	// DefaultVersion returns the version which is used if the spec doesn't define one
+	DefaultVersion() string
+
+	// UpgradeStrategy returns how the database is upgraded between the versions or an error if it can't be upgraded
+	UpgradeStrategy(fromVersion string, toVersion string) (string, error)
+
+	// Credentials returns the entries of connection secrets for the given user and password
+	Credentials(database *databasesamplev1beta1.Database, username string, password []byte) map[string][]byte
+
+	// BackupHooks returns how the data of the database is dumped and restored
+	BackupHooks(database *databasesamplev1beta1.Database) *BackupHooks
+
+	// UserHooks returns how additional users of the database are created, altered and dropped
+	UserHooks(database *databasesamplev1beta1.Database) *UserHooks
+
+	// ProbeConnector returns the connector of database/sql with which the user connects to the address, see probeDatabase.
+	// Connections are opened with probeDialer. If caCertificate is set, they use TLS if the database offers it and
+	// the certificate of the database is verified with it.
+	ProbeConnector(address string, caCertificate []byte, credentials ProbeCredentials) (driver.Connector, error)
+}
+
+// Note: Version is empty if the database hasn't been provisioned
+type ProviderStatus struct {
+	Ready    bool
+	Version  string
+	Endpoint databasesamplev1beta1.DatabaseEndpoint
+}
+
+// Note: Backups and restores run the commands in jobs with the given image. The dump is written to and read from
+// the file in the environment variable BACKUP_FILE.
+type BackupHooks struct {
+	Image          string
+	Env            []corev1.EnvVar
+	BackupCommand  []string
+	RestoreCommand []string
+}
+
+const BackupFileEnvName = "BACKUP_FILE"
+
+// Note: Users are managed by jobs which run the commands with the given image. The commands get the user, its password
+// and the comma-separated privileges in the environment variables USER_NAME, USER_PASSWORD and USER_PRIVILEGES.
+// The ensure command needs to be idempotent. The password command only changes the password of an existing user, e.g.
+// of the user of the spec when the referenced password is rotated.
+type UserHooks struct {
+	Image           string
+	Env             []corev1.EnvVar
+	EnsureCommand   []string
+	DropCommand     []string
+	PasswordCommand []string
+}
+
+const UserNameEnvName = "USER_NAME"
+const UserPasswordEnvName = "USER_PASSWORD"
+const UserPrivilegesEnvName = "USER_PRIVILEGES"
+
+// NewProviders returns the providers of all supported engines
+func NewProviders(client client.Client, scheme *runtime.Scheme) map[string]Provider {
+	resources := resourceReconciler{Client: client, Scheme: scheme}
+	return map[string]Provider{
+		databasesamplev1beta1.EnginePostgreSQL: &postgreSQLProvider{resources},
+		databasesamplev1beta1.EngineMySQL:      &mySQLProvider{resources},
+	}
+}
+
+func getProvider(providers map[string]Provider, database *databasesamplev1beta1.Database) (Provider, error) {
+	provider, ok := providers[database.GetEngine()]
+	if !ok {
+		return nil, fmt.Errorf("engine %s is not supported", database.GetEngine())
+	}
+	return provider, nil
+}
+
+// Note: All engines are reachable via the client service
+func getEndpoint(database *databasesamplev1beta1.Database, port int32) databasesamplev1beta1.DatabaseEndpoint {
+	return databasesamplev1beta1.DatabaseEndpoint{
+		Host: getServiceName(database) + "." + database.Namespace + ".svc",
+		Port: port,
+	}
+}
+
+// Note: The version of the spec is used for jobs too, since clients of newer versions can dump and restore older ones
+func getVersion(database *databasesamplev1beta1.Database, provider Provider) string {
+	if database.Spec.Version != "" {
+		return database.Spec.Version
+	}
+	return provider.DefaultVersion()
+}
+
+// Note: Versions with the same major version are upgraded by restarting the database with the new image. Major upgrades
+// dump and restore the data, since the data directories of different major versions are not compatible.
+func getUpgradeStrategy(fromVersion string, toVersion string, majorVersions []int) (string, error) {
+	from, err := databasesamplev1beta1.ParseVersion(fromVersion)
+	if err != nil {
+		return "", err
+	}
+	to, err := databasesamplev1beta1.ParseVersion(toVersion)
+	if err != nil {
+		return "", err
+	}
+	supported := false
+	for _, major := range majorVersions {
+		supported = supported || major == to[0]
+	}
+	if !supported {
+		return "", fmt.Errorf("major version %d is not supported", to[0])
+	}
+	comparison, err := databasesamplev1beta1.CompareVersions(fromVersion, toVersion)
+	if err != nil {
+		return "", err
+	}
+	if comparison > 0 {
+		return "", fmt.Errorf("version cannot be downgraded from %s to %s", fromVersion, toVersion)
+	}
+	if from[0] == to[0] {
+		return databasesamplev1beta1.UpgradeStrategyRollingRestart, nil
+	}
+	return databasesamplev1beta1.UpgradeStrategyDumpRestore, nil
+}
@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const fakeDefaultVersion = "1"
+const fakeImage = "docker.io/library/busybox:latest"
+
+var fakeMajorVersions = []int{1, 2}
+
+// FakeProvider keeps provisioned databases in memory instead of running them. It is used by envtest which doesn't run pods.
+type FakeProvider struct {
+	mutex       sync.Mutex
+	provisioned map[types.NamespacedName]string
+	notReady    bool
+}
+
+var _ Provider = &FakeProvider{}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{provisioned: map[types.NamespacedName]string{}}
+}
+
+// IsProvisioned returns whether the database has been provisioned
+func (p *FakeProvider) IsProvisioned(name types.NamespacedName) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.provisioned[name] != ""
+}
+
+// Note: Databases accept connections as soon as they have been provisioned unless this is set to false
+func (p *FakeProvider) SetReady(ready bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.notReady = !ready
+}
+
+// Note: Databases are upgraded immediately with both strategies
+func (p *FakeProvider) Provision(ctx context.Context, database *databasesamplev1beta1.Database, version string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.provisioned[types.NamespacedName{Name: database.Name, Namespace: database.Namespace}] = version
+	return nil
+}
+
+func (p *FakeProvider) Status(ctx context.Context, database *databasesamplev1beta1.Database) (*ProviderStatus, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	version := p.provisioned[types.NamespacedName{Name: database.Name, Namespace: database.Namespace}]
+	ready := version != "" && !p.notReady
+	return &ProviderStatus{Ready: ready, Version: version, Endpoint: getEndpoint(database, 0)}, nil
+}
+
+func (p *FakeProvider) DefaultVersion() string {
+	return fakeDefaultVersion
+}
+
+func (p *FakeProvider) UpgradeStrategy(fromVersion string, toVersion string) (string, error) {
+	return getUpgradeStrategy(fromVersion, toVersion, fakeMajorVersions)
+}
+
+func (p *FakeProvider) Credentials(database *databasesamplev1beta1.Database, username string, password []byte) map[string][]byte {
+	return getCredentials(database, getEndpoint(database, 0), username, password)
+}
+
+func (p *FakeProvider) BackupHooks(database *databasesamplev1beta1.Database) *BackupHooks {
+	return &BackupHooks{
+		Image:          fakeImage,
+		BackupCommand:  []string{"sh", "-c", `echo "` + database.Name + `" > "$` + BackupFileEnvName + `"`},
+		RestoreCommand: []string{"sh", "-c", `cat "$` + BackupFileEnvName + `"`},
+	}
+}
+
+func (p *FakeProvider) UserHooks(database *databasesamplev1beta1.Database) *UserHooks {
+	return &UserHooks{
+		Image:           fakeImage,
+		EnsureCommand:   []string{"sh", "-c", `echo "Ensuring user $` + UserNameEnvName + ` with $` + UserPrivilegesEnvName + `"`},
+		DropCommand:     []string{"sh", "-c", `echo "Dropping user $` + UserNameEnvName + `"`},
+		PasswordCommand: []string{"sh", "-c", `echo "Changing password of user $` + UserNameEnvName + `"`},
+	}
+}
+
+// Note: The fake protocol sends the query with the credentials in one line and expects the result in one line, so that
+// probes can be tested with local stand-ins. Clients which have a CA certificate send TLS first and start the TLS
+// handshake if the stand-in answers with S.
+func (p *FakeProvider) ProbeConnector(address string, caCertificate []byte, credentials ProbeCredentials) (driver.Connector, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := newProbeTLSConfig(host, caCertificate)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeProbeConnector{address: address, tlsConfig: tlsConfig, credentials: credentials}, nil
+}
+
+// Note: The query is run when connecting, the connection only returns the result
+type fakeProbeConnector struct {
+	address     string
+	tlsConfig   *tls.Config
+	credentials ProbeCredentials
+}
+
+func (c *fakeProbeConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := probeDialer{}.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	if c.tlsConfig != nil {
+		_, err = fmt.Fprintln(conn, "TLS")
+		if err != nil {
+			return nil, err
+		}
+		answer, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if answer == "S\n" {
+			tlsConn := tls.Client(conn, c.tlsConfig)
+			err = tlsConn.Handshake()
+			if err != nil {
+				return nil, err
+			}
+			conn = tlsConn
+			reader = bufio.NewReader(conn)
+		}
+	}
+	_, err = fmt.Fprintf(conn, "SELECT 1 %s %s %s\n", c.credentials.Username, c.credentials.Password, c.credentials.Database)
+	if err != nil {
+		return nil, err
+	}
+	result, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	result = strings.TrimSpace(result)
+	if _, err := strconv.Atoi(result); err != nil {
+		return nil, errors.New(result)
+	}
+	return &fakeProbeConn{result: result}, nil
+}
+
+func (c *fakeProbeConnector) Driver() driver.Driver {
+	return fakeProbeDriver{}
+}
+
+type fakeProbeDriver struct{}
+
+func (d fakeProbeDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("connections are only opened by the connector")
+}
+
+type fakeProbeConn struct {
+	result string
+}
+
+func (c *fakeProbeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if query != "SELECT 1" {
+		return nil, fmt.Errorf("unexpected query %s", query)
+	}
+	return &fakeProbeRows{values: []string{c.result}}, nil
+}
+
+func (c *fakeProbeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("statements are not supported")
+}
+
+func (c *fakeProbeConn) Close() error {
+	return nil
+}
+
+func (c *fakeProbeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type fakeProbeRows struct {
+	values []string
+}
+
+func (r *fakeProbeRows) Columns() []string {
+	return []string{"?column?"}
+}
+
+func (r *fakeProbeRows) Close() error {
+	return nil
+}
+
+func (r *fakeProbeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"context"
+	"database/sql/driver"
+	"net"
+	"strconv"
+
+	"github.com/go-sql-driver/mysql"
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type mySQLProvider struct {
+	resourceReconciler
+}
+
+var _ Provider = &mySQLProvider{}
+
+// Note: The mysql image creates the database and the user from the spec itself, no init script is needed
+func (p *mySQLProvider) Provision(ctx context.Context, database *databasesamplev1beta1.Database, version string) error {
+	_, err := p.reconcileSecret(ctx, database, mysqlAdminUser)
+	if err != nil {
+		return err
+	}
+	_, err = p.reconcileServices(ctx, database, mysqlPort)
+	if err != nil {
+		return err
+	}
+	_, err = p.reconcileStatefulSet(ctx, database, p.defineStatefulSet(database, p.defineContainer(database, version), nil, mysqlUser, version))
+	return err
+}
+
+func (p *mySQLProvider) defineContainer(database *databasesamplev1beta1.Database, version string) corev1.Container {
+	passwordRef := database.GetPasswordSecretRef()
+	return corev1.Container{
+		Name:  databasesamplev1beta1.EngineMySQL,
+		Image: mysqlImageRepository + ":" + version,
+		Ports: []corev1.ContainerPort{{
+			Name:          databasesamplev1beta1.EngineMySQL,
+			ContainerPort: mysqlPort,
+			Protocol:      corev1.ProtocolTCP,
+		}},
+		Env: []corev1.EnvVar{
+			{Name: "MYSQL_DATABASE", Value: database.Name},
+			{Name: "MYSQL_USER", Value: database.Spec.User},
+			secretEnvVar("MYSQL_ROOT_PASSWORD", getAdminSecretName(database), secretKeyAdminPassword),
+			secretEnvVar("MYSQL_PASSWORD", passwordRef.Name, passwordRef.Key),
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: dataVolumeName, MountPath: mysqlDataPath},
+		},
+		ReadinessProbe: &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				Exec: &corev1.ExecAction{Command: []string{"sh", "-c",
+					`MYSQL_PWD="$MYSQL_ROOT_PASSWORD" mysqladmin ping --host 127.0.0.1 --user root`}},
+			},
+			InitialDelaySeconds: 5,
+			PeriodSeconds:       10,
+		},
+	}
+}
+
+func (p *mySQLProvider) Status(ctx context.Context, database *databasesamplev1beta1.Database) (*ProviderStatus, error) {
+	ready, version, err := p.getStatefulSetStatus(ctx, database, mysqlDefaultVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &ProviderStatus{Ready: ready, Version: version, Endpoint: getEndpoint(database, mysqlPort)}, nil
+}
+
+func (p *mySQLProvider) DefaultVersion() string {
+	return mysqlDefaultVersion
+}
+
+func (p *mySQLProvider) UpgradeStrategy(fromVersion string, toVersion string) (string, error) {
+	return getUpgradeStrategy(fromVersion, toVersion, mysqlMajorVersions)
+}
+
+func (p *mySQLProvider) Credentials(database *databasesamplev1beta1.Database, username string, password []byte) map[string][]byte {
+	return getCredentials(database, getEndpoint(database, mysqlPort), username, password)
+}
+
+// Note: Backup and user jobs connect as admin via the client service
+func (p *mySQLProvider) getAdminEnv(database *databasesamplev1beta1.Database) []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{Name: "DATABASE_HOST", Value: getEndpoint(database, mysqlPort).Host},
+		{Name: "DATABASE_PORT", Value: strconv.Itoa(int(mysqlPort))},
+		{Name: "DATABASE_NAME", Value: database.Name},
+		secretEnvVar("DATABASE_ADMIN_USER", getAdminSecretName(database), secretKeyAdminUsername),
+		secretEnvVar("MYSQL_PWD", getAdminSecretName(database), secretKeyAdminPassword),
+	}
+}
+
+// Note: The dump contains the tables without the statements to create the database, so that it can be restored into
+// existing databases with other names
+func (p *mySQLProvider) BackupHooks(database *databasesamplev1beta1.Database) *BackupHooks {
+	return &BackupHooks{
+		Image: mysqlImageRepository + ":" + getVersion(database, p),
+		Env:   p.getAdminEnv(database),
+		BackupCommand: []string{"sh", "-c", `mysqldump --host "$DATABASE_HOST" --port "$DATABASE_PORT" --user "$DATABASE_ADMIN_USER" ` +
+			`--single-transaction "$DATABASE_NAME" > "$` + BackupFileEnvName + `"`},
+		RestoreCommand: []string{"sh", "-c", `mysql --host "$DATABASE_HOST" --port "$DATABASE_PORT" --user "$DATABASE_ADMIN_USER" ` +
+			`"$DATABASE_NAME" < "$` + BackupFileEnvName + `"`},
+	}
+}
+
+// Note: Users can connect from all hosts and get the privileges on all tables of the database
+func (p *mySQLProvider) UserHooks(database *databasesamplev1beta1.Database) *UserHooks {
+	return &UserHooks{
+		Image:           mysqlImageRepository + ":" + getVersion(database, p),
+		Env:             p.getAdminEnv(database),
+		EnsureCommand:   []string{"sh", "-c", mysqlEnsureUserScript},
+		DropCommand:     []string{"sh", "-c", mysqlDropUserScript},
+		PasswordCommand: []string{"sh", "-c", mysqlChangePasswordScript},
+	}
+}
+
+// Note: The connections of the probes are opened by the driver with the dial function of this network
+const mysqlProbeNetwork = "probe"
+
+func init() {
+	mysql.RegisterDialContext(mysqlProbeNetwork, func(ctx context.Context, address string) (net.Conn, error) {
+		return probeDialer{}.DialContext(ctx, "tcp", address)
+	})
+}
+
+// Note: Databases which don't offer TLS are probed without it. The driver refuses to send passwords in clear text, e.g.
+// when the server requests mysql_clear_password.
+func (p *mySQLProvider) ProbeConnector(address string, caCertificate []byte, credentials ProbeCredentials) (driver.Connector, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := newProbeTLSConfig(host, caCertificate)
+	if err != nil {
+		return nil, err
+	}
+	config := mysql.NewConfig()
+	config.Net = mysqlProbeNetwork
+	config.Addr = address
+	config.User = credentials.Username
+	config.Passwd = credentials.Password
+	config.DBName = credentials.Database
+	config.TLS = tlsConfig
+	config.AllowFallbackToPlaintext = true
+	return mysql.NewConnector(config)
+}
@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/lib/pq"
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type postgreSQLProvider struct {
+	resourceReconciler
+}
+
+var _ Provider = &postgreSQLProvider{}
+
+// Note: The user from the spec is created by an init script when the data directory is initialized
+func (p *postgreSQLProvider) Provision(ctx context.Context, database *databasesamplev1beta1.Database, version string) error {
+	_, err := p.reconcileSecret(ctx, database, postgresAdminUser)
+	if err != nil {
+		return err
+	}
+	_, err = p.reconcileInitConfigMap(ctx, database, map[string]string{postgresInitScriptName: postgresInitScript})
+	if err != nil {
+		return err
+	}
+	_, err = p.reconcileServices(ctx, database, postgresPort)
+	if err != nil {
+		return err
+	}
+	_, err = p.reconcileStatefulSet(ctx, database, p.defineStatefulSet(database, p.defineContainer(database, version), []corev1.Volume{{
+		Name: initVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: getInitConfigMapName(database)},
+			},
+		},
+	}}, postgresUser, version))
+	return err
+}
+
+func (p *postgreSQLProvider) defineContainer(database *databasesamplev1beta1.Database, version string) corev1.Container {
+	secretName := getAdminSecretName(database)
+	passwordRef := database.GetPasswordSecretRef()
+	return corev1.Container{
+		Name:  databasesamplev1beta1.EnginePostgreSQL,
+		Image: postgresImageRepository + ":" + version,
+		Ports: []corev1.ContainerPort{{
+			Name:          databasesamplev1beta1.EnginePostgreSQL,
+			ContainerPort: postgresPort,
+			Protocol:      corev1.ProtocolTCP,
+		}},
+		Env: []corev1.EnvVar{
+			{Name: "POSTGRES_DB", Value: database.Name},
+			{Name: "PGDATA", Value: postgresDataPath + "/pgdata"},
+			{Name: "DATABASE_USER", Value: database.Spec.User},
+			secretEnvVar("POSTGRES_USER", secretName, secretKeyAdminUsername),
+			secretEnvVar("POSTGRES_PASSWORD", secretName, secretKeyAdminPassword),
+			secretEnvVar("DATABASE_PASSWORD", passwordRef.Name, passwordRef.Key),
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: dataVolumeName, MountPath: postgresDataPath},
+			{Name: initVolumeName, MountPath: postgresInitPath, ReadOnly: true},
+		},
+		ReadinessProbe: &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				Exec: &corev1.ExecAction{Command: []string{"sh", "-c",
+					`pg_isready --host 127.0.0.1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB"`}},
+			},
+			InitialDelaySeconds: 5,
+			PeriodSeconds:       10,
+		},
+	}
+}
+
+func (p *postgreSQLProvider) Status(ctx context.Context, database *databasesamplev1beta1.Database) (*ProviderStatus, error) {
+	ready, version, err := p.getStatefulSetStatus(ctx, database, postgresDefaultVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &ProviderStatus{Ready: ready, Version: version, Endpoint: getEndpoint(database, postgresPort)}, nil
+}
+
+func (p *postgreSQLProvider) DefaultVersion() string {
+	return postgresDefaultVersion
+}
+
+func (p *postgreSQLProvider) UpgradeStrategy(fromVersion string, toVersion string) (string, error) {
+	return getUpgradeStrategy(fromVersion, toVersion, postgresMajorVersions)
+}
+
+func (p *postgreSQLProvider) Credentials(database *databasesamplev1beta1.Database, username string, password []byte) map[string][]byte {
+	return getCredentials(database, getEndpoint(database, postgresPort), username, password)
+}
+
+// Note: Backup and user jobs connect as admin via the client service
+func (p *postgreSQLProvider) getAdminEnv(database *databasesamplev1beta1.Database) []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{Name: "PGHOST", Value: getEndpoint(database, postgresPort).Host},
+		{Name: "PGPORT", Value: strconv.Itoa(int(postgresPort))},
+		{Name: "PGDATABASE", Value: database.Name},
+		secretEnvVar("PGUSER", getAdminSecretName(database), secretKeyAdminUsername),
+		secretEnvVar("PGPASSWORD", getAdminSecretName(database), secretKeyAdminPassword),
+	}
+}
+
+// Note: The dump contains statements to drop existing objects, so that it can be restored into existing databases.
+// Owners and privileges are not dumped, the restore runs with the role of the owner of the target database, so that
+// the restored objects belong to it.
+func (p *postgreSQLProvider) BackupHooks(database *databasesamplev1beta1.Database) *BackupHooks {
+	return &BackupHooks{
+		Image:         postgresImageRepository + ":" + getVersion(database, p),
+		Env:           append(p.getAdminEnv(database), corev1.EnvVar{Name: "DATABASE_OWNER", Value: database.Spec.User}),
+		BackupCommand: []string{"sh", "-c", `pg_dump --clean --if-exists --no-owner --no-acl --file "$` + BackupFileEnvName + `"`},
+		RestoreCommand: []string{"sh", "-c", `PGOPTIONS="-c role=$DATABASE_OWNER" psql -v ON_ERROR_STOP=1 --file "$` +
+			BackupFileEnvName + `"`},
+	}
+}
+
+// Note: Privileges are granted on the tables of the schema public, including tables which the owner of the database
+// creates later. Objects of dropped users are reassigned to the owner.
+func (p *postgreSQLProvider) UserHooks(database *databasesamplev1beta1.Database) *UserHooks {
+	return &UserHooks{
+		Image:           postgresImageRepository + ":" + getVersion(database, p),
+		Env:             append(p.getAdminEnv(database), corev1.EnvVar{Name: "DATABASE_OWNER", Value: database.Spec.User}),
+		EnsureCommand:   []string{"sh", "-c", postgresEnsureUserScript},
+		DropCommand:     []string{"sh", "-c", postgresDropUserScript},
+		PasswordCommand: []string{"sh", "-c", postgresChangePasswordScript},
+	}
+}
+
+// Note: lib/pq reads the CA certificate from a file, which is written for every probe and removed by Close when the
+// probe has finished. Databases which don't offer TLS are probed without it.
+type postgreSQLProbeConnector struct {
+	*pq.Connector
+	tlsConnector *pq.Connector
+	caFile       string
+}
+
+func (c *postgreSQLProbeConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.tlsConnector != nil {
+		conn, err := c.tlsConnector.Connect(ctx)
+		if !errors.Is(err, pq.ErrSSLNotSupported) {
+			return conn, err
+		}
+	}
+	return c.Connector.Connect(ctx)
+}
+
+func (c *postgreSQLProbeConnector) Close() error {
+	if c.caFile == "" {
+		return nil
+	}
+	return os.Remove(c.caFile)
+}
+
+// Note: The certificate of the database is verified with the sslmode verify-full, like the certificates of HTTPS servers
+func (p *postgreSQLProvider) ProbeConnector(address string, caCertificate []byte, credentials ProbeCredentials) (driver.Connector, error) {
+	probeConnector := &postgreSQLProbeConnector{}
+	var err error
+	probeConnector.Connector, err = newPostgreSQLConnector(address, credentials, url.Values{"sslmode": {"disable"}})
+	if err != nil || caCertificate == nil {
+		return probeConnector, err
+	}
+
+	caFile, err := os.CreateTemp("", "probe-ca-*.crt")
+	if err != nil {
+		return nil, err
+	}
+	probeConnector.caFile = caFile.Name()
+	_, err = caFile.Write(caCertificate)
+	if closeErr := caFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		probeConnector.tlsConnector, err = newPostgreSQLConnector(address, credentials,
+			url.Values{"sslmode": {"verify-full"}, "sslrootcert": {caFile.Name()}})
+	}
+	if err != nil {
+		probeConnector.Close()
+		return nil, err
+	}
+	return probeConnector, nil
+}
+
+func newPostgreSQLConnector(address string, credentials ProbeCredentials, parameters url.Values) (*pq.Connector, error) {
+	dsn := &url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(credentials.Username, credentials.Password),
+		Host:     address,
+		Path:     "/" + credentials.Database,
+		RawQuery: parameters.Encode(),
+	}
+	connector, err := pq.NewConnector(dsn.String())
+	if err != nil {
+		return nil, err
+	}
+	connector.Dialer(probeDialer{})
+	return connector, nil
+}
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"context"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Note: The providers of engines which run in the cluster share the reconciliation of the Kubernetes resources
+type resourceReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// Note: A database accepts connections when its pod is ready, i.e. when the readiness probe of the engine succeeds, and
+// the pod runs the current revision of the stateful set. Stateful sets without annotation run the default version.
+func (r *resourceReconciler) getStatefulSetStatus(ctx context.Context, database *databasesamplev1beta1.Database,
+	defaultVersion string) (bool, string, error) {
+
+	statefulSet := &appsv1.StatefulSet{}
+	err := r.Get(ctx, types.NamespacedName{Name: getStatefulSetName(database), Namespace: database.Namespace}, statefulSet)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, "", nil
+		}
+		return false, "", err
+	}
+	version := statefulSet.Annotations[versionAnnotation]
+	if version == "" {
+		version = defaultVersion
+	}
+	updated := statefulSet.Status.ObservedGeneration >= statefulSet.Generation &&
+		statefulSet.Status.CurrentRevision == statefulSet.Status.UpdateRevision
+	return statefulSet.Status.ReadyReplicas > 0 && updated, version, nil
+}
@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+type DatabaseRestoreReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Note: Providers of the engines, see NewProviders. If not set, the providers of all supported engines are used.
+	Providers map[string]Provider
+}
+
+//+kubebuilder:rbac:groups=database.sample.third.party,resources=databaserestores,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=database.sample.third.party,resources=databaserestores/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=database.sample.third.party,resources=databaserestores/finalizers,verbs=update
+//+kubebuilder:rbac:groups=database.sample.third.party,resources=databasebackups,verbs=get;list;watch
+//+kubebuilder:rbac:groups=database.sample.third.party,resources=databases,verbs=get;list;watch;create
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+func (r *DatabaseRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	log.Info("Reconcile started")
+
+	restore := &databasesamplev1beta1.DatabaseRestore{}
+	err := r.Get(ctx, req.NamespacedName, restore)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("DatabaseRestore resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		log.Info("Failed to get database restore resource. Re-running reconcile.")
+		return ctrl.Result{}, err
+	}
+
+	original := restore.DeepCopy()
+	if meta.FindStatusCondition(restore.Status.Conditions, CONDITION_TYPE_SUCCEEDED) == nil {
+		setJobCondition(&restore.Status.Conditions, restore.Generation, CONDITION_TYPE_SUCCEEDED, "Unknown",
+			CONDITION_REASON_NO_RESULT, CONDITION_MESSAGE_NO_RESULT)
+	}
+	err = r.reconcileRestore(ctx, restore)
+	if err != nil {
+		setJobCondition(&restore.Status.Conditions, restore.Generation, CONDITION_TYPE_PROGRESSING, CONDITION_STATUS_FALSE,
+			CONDITION_REASON_RECONCILE_FAILED, err.Error())
+	}
+	statusErr := r.updateRestoreStatus(ctx, original, restore)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if statusErr != nil {
+		return ctrl.Result{}, statusErr
+	}
+	return ctrl.Result{}, nil
+}
+
+// Note: Restores run once. When the restore job has finished, restores are not reconciled anymore.
+func (r *DatabaseRestoreReconciler) reconcileRestore(ctx context.Context, restore *databasesamplev1beta1.DatabaseRestore) error {
+	conditions := &restore.Status.Conditions
+	if !meta.IsStatusConditionPresentAndEqual(*conditions, CONDITION_TYPE_SUCCEEDED, "Unknown") {
+		return nil
+	}
+
+	backup := &databasesamplev1beta1.DatabaseBackup{}
+	err := r.Get(ctx, types.NamespacedName{Name: restore.Spec.BackupName, Namespace: restore.Namespace}, backup)
+	if err != nil {
+		return fmt.Errorf("backup %s cannot be read: %v", restore.Spec.BackupName, err)
+	}
+	err = validateBackupTarget(backup)
+	if err != nil {
+		return err
+	}
+	backupFile := restore.Spec.BackupFile
+	if backupFile == "" {
+		if backup.Status.LastBackup == nil {
+			setJobCondition(conditions, restore.Generation, CONDITION_TYPE_PROGRESSING, CONDITION_STATUS_TRUE,
+				CONDITION_REASON_WAITING_FOR_BACKUP, CONDITION_MESSAGE_WAITING_FOR_BACKUP)
+			return nil
+		}
+		backupFile = backup.Status.LastBackup.Name
+	}
+	restore.Status.BackupFile = backupFile
+
+	database, err := r.reconcileRestoreDatabase(ctx, restore)
+	if err != nil {
+		return err
+	}
+	if database == nil || !meta.IsStatusConditionTrue(database.Status.Conditions, CONDITION_TYPE_READY) {
+		setJobCondition(conditions, restore.Generation, CONDITION_TYPE_PROGRESSING, CONDITION_STATUS_TRUE,
+			CONDITION_REASON_WAITING_FOR_DATABASE, CONDITION_MESSAGE_WAITING_FOR_DATABASE)
+		return nil
+	}
+	if backup.Status.Engine != database.GetEngine() {
+		setJobCondition(conditions, restore.Generation, CONDITION_TYPE_PROGRESSING, CONDITION_STATUS_FALSE,
+			CONDITION_REASON_JOB_NOT_RUNNING, CONDITION_MESSAGE_JOB_NOT_RUNNING)
+		setJobCondition(conditions, restore.Generation, CONDITION_TYPE_SUCCEEDED, CONDITION_STATUS_FALSE,
+			CONDITION_REASON_ENGINE_MISMATCH, fmt.Sprintf("Dumps of engine %s cannot be restored into databases with engine %s",
+				backup.Status.Engine, database.GetEngine()))
+		return nil
+	}
+	provider, err := getProvider(r.Providers, database)
+	if err != nil {
+		return err
+	}
+
+	job, err := r.reconcileRestoreJob(ctx, restore, backup, backupFile, provider.BackupHooks(database))
+	if err != nil {
+		return err
+	}
+	finished, succeeded := getJobResult(job)
+	switch {
+	case !finished:
+		setJobCondition(conditions, restore.Generation, CONDITION_TYPE_PROGRESSING, CONDITION_STATUS_TRUE,
+			CONDITION_REASON_JOB_RUNNING, CONDITION_MESSAGE_RESTORE_RUNNING)
+	case succeeded:
+		restore.Status.CompletionTime = job.Status.CompletionTime
+		setJobCondition(conditions, restore.Generation, CONDITION_TYPE_PROGRESSING, CONDITION_STATUS_FALSE,
+			CONDITION_REASON_JOB_NOT_RUNNING, CONDITION_MESSAGE_JOB_NOT_RUNNING)
+		setJobCondition(conditions, restore.Generation, CONDITION_TYPE_SUCCEEDED, CONDITION_STATUS_TRUE,
+			CONDITION_REASON_RESTORE_SUCCEEDED, CONDITION_MESSAGE_RESTORE_SUCCEEDED)
+	default:
+		setJobCondition(conditions, restore.Generation, CONDITION_TYPE_PROGRESSING, CONDITION_STATUS_FALSE,
+			CONDITION_REASON_JOB_NOT_RUNNING, CONDITION_MESSAGE_JOB_NOT_RUNNING)
+		setJobCondition(conditions, restore.Generation, CONDITION_TYPE_SUCCEEDED, CONDITION_STATUS_FALSE,
+			CONDITION_REASON_RESTORE_FAILED, CONDITION_MESSAGE_RESTORE_FAILED)
+	}
+	return nil
+}
+
+// Note: Databases which are created from the template are not owned by the restore, so they survive its deletion.
+// nil is returned while the new database is being created.
+func (r *DatabaseRestoreReconciler) reconcileRestoreDatabase(ctx context.Context, restore *databasesamplev1beta1.DatabaseRestore) (*databasesamplev1beta1.Database, error) {
+	log := log.FromContext(ctx)
+	database := &databasesamplev1beta1.Database{}
+	err := r.Get(ctx, types.NamespacedName{Name: restore.Spec.DatabaseName, Namespace: restore.Namespace}, database)
+	if err != nil {
+		if errors.IsNotFound(err) && restore.Spec.DatabaseTemplate != nil {
+			log.Info("Database resource " + restore.Spec.DatabaseName + " not found. Creating database from template")
+			database = &databasesamplev1beta1.Database{
+				ObjectMeta: metav1.ObjectMeta{Name: restore.Spec.DatabaseName, Namespace: restore.Namespace},
+				Spec:       *restore.Spec.DatabaseTemplate.DeepCopy(),
+			}
+			err = r.Create(ctx, database)
+			if err != nil {
+				log.Info("Failed to create database resource. Re-running reconcile.")
+				return nil, err
+			}
+			return nil, nil
+		}
+		return nil, fmt.Errorf("database %s cannot be read: %v", restore.Spec.DatabaseName, err)
+	}
+	return database, nil
+}
+
+func (r *DatabaseRestoreReconciler) reconcileRestoreJob(ctx context.Context, restore *databasesamplev1beta1.DatabaseRestore,
+	backup *databasesamplev1beta1.DatabaseBackup, backupFile string, hooks *BackupHooks) (*batchv1.Job, error) {
+
+	log := log.FromContext(ctx)
+	jobName := getRestoreJobName(restore)
+	job := &batchv1.Job{}
+	err := r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: restore.Namespace}, job)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("Job resource " + jobName + " not found. Creating or re-creating job")
+			job = r.defineRestoreJob(restore, backup, backupFile, hooks)
+			err = r.Create(ctx, job)
+			if err != nil {
+				log.Info("Failed to create job resource. Re-running reconcile.")
+				return nil, err
+			}
+			return job, nil
+		}
+		log.Info("Failed to get job resource " + jobName + ". Re-running reconcile.")
+		return nil, err
+	}
+	return job, nil
+}
+
+// Note: The status is only updated if it has changed to avoid unnecessary reconciliations
+func (r *DatabaseRestoreReconciler) updateRestoreStatus(ctx context.Context, original *databasesamplev1beta1.DatabaseRestore,
+	restore *databasesamplev1beta1.DatabaseRestore) error {
+
+	log := log.FromContext(ctx)
+	if equality.Semantic.DeepEqual(original.Status, restore.Status) {
+		return nil
+	}
+	err := r.Status().Update(ctx, restore)
+	if err != nil {
+		log.Info("DatabaseRestore resource status update failed.")
+	}
+	return err
+}
+
+// Note: Restores wait for backups and databases, so changes of them are mapped to the restores which reference them
+func (r *DatabaseRestoreReconciler) findRestoresFor(matches func(restore *databasesamplev1beta1.DatabaseRestore, name string) bool) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(object client.Object) []reconcile.Request {
+		restores := &databasesamplev1beta1.DatabaseRestoreList{}
+		err := r.List(context.Background(), restores, client.InNamespace(object.GetNamespace()))
+		if err != nil {
+			return nil
+		}
+		requests := []reconcile.Request{}
+		for i := range restores.Items {
+			if matches(&restores.Items[i], object.GetName()) {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: restores.Items[i].Name, Namespace: restores.Items[i].Namespace},
+				})
+			}
+		}
+		return requests
+	})
+}
+
+func (r *DatabaseRestoreReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Providers == nil {
+		r.Providers = NewProviders(mgr.GetClient(), mgr.GetScheme())
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&databasesamplev1beta1.DatabaseRestore{}).
+		Owns(&batchv1.Job{}).
+		Watches(&source.Kind{Type: &databasesamplev1beta1.DatabaseBackup{}},
+			r.findRestoresFor(func(restore *databasesamplev1beta1.DatabaseRestore, name string) bool {
+				return restore.Spec.BackupName == name
+			})).
+		Watches(&source.Kind{Type: &databasesamplev1beta1.Database{}},
+			r.findRestoresFor(func(restore *databasesamplev1beta1.DatabaseRestore, name string) bool {
+				return restore.Spec.DatabaseName == name
+			})).
+		Complete(r)
+}
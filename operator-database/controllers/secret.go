@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const passwordCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func generatePassword(length int) (string, error) {
+	password := make([]byte, length)
+	for i := range password {
+		index, err := rand.Int(rand.Reader, big.NewInt(int64(len(passwordCharacters))))
+		if err != nil {
+			return "", err
+		}
+		password[i] = passwordCharacters[index.Int64()]
+	}
+	return string(password), nil
+}
+
+// Note: The admin password is generated once
+func (r *resourceReconciler) defineSecret(database *databasesamplev1beta1.Database, adminUser string) (*corev1.Secret, error) {
+	adminPassword, err := generatePassword(adminPasswordLength)
+	if err != nil {
+		return nil, err
+	}
+	secret := &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: getAdminSecretName(database), Namespace: database.Namespace, Labels: getLabels(database)},
+		Type:       corev1.SecretTypeOpaque,
+		StringData: map[string]string{
+			secretKeyAdminUsername: adminUser,
+			secretKeyAdminPassword: adminPassword,
+		},
+	}
+
+	ctrl.SetControllerReference(database, secret, r.Scheme)
+	return secret, nil
+}
+
+func (r *resourceReconciler) reconcileSecret(ctx context.Context, database *databasesamplev1beta1.Database, adminUser string) (ctrl.Result, error) {
+	secretName := getAdminSecretName(database)
+	log := log.FromContext(ctx)
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: database.Namespace}, secret)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("Secret resource " + secretName + " not found. Creating or re-creating secret")
+			secret, err = r.defineSecret(database, adminUser)
+			if err != nil {
+				log.Info("Failed to generate admin password. Re-running reconcile.")
+				return ctrl.Result{}, err
+			}
+			err = r.Create(ctx, secret)
+			if err != nil {
+				log.Info("Failed to create secret resource. Re-running reconcile.")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		}
+		log.Info("Failed to get secret resource " + secretName + ". Re-running reconcile.")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *DatabaseReconciler) defineCredentialsSecret(database *databasesamplev1beta1.Database) (*corev1.Secret, error) {
+	password, err := generatePassword(adminPasswordLength)
+	if err != nil {
+		return nil, err
+	}
+	passwordRef := database.GetPasswordSecretRef()
+	labels := getLabels(database)
+	labels[databasesamplev1beta1.GeneratedSecretLabel] = database.Name
+	secret := &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: passwordRef.Name, Namespace: database.Namespace, Labels: labels},
+		Type:       corev1.SecretTypeOpaque,
+		StringData: map[string]string{passwordRef.Key: password},
+	}
+
+	ctrl.SetControllerReference(database, secret, r.Scheme)
+	return secret, nil
+}
+
+// Note: Referenced secrets are owned by users. Only the secret of databases without reference is generated.
+// Secrets which have been generated during the conversion from v1alpha1 by earlier versions of the operator are adopted.
+func (r *DatabaseReconciler) reconcileCredentialsSecret(ctx context.Context, database *databasesamplev1beta1.Database) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	passwordRef := database.GetPasswordSecretRef()
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: passwordRef.Name, Namespace: database.Namespace}, secret)
+	if err != nil {
+		if errors.IsNotFound(err) && database.Spec.PasswordSecretRef == nil {
+			log.Info("Secret resource " + passwordRef.Name + " not found. Creating or re-creating secret with generated password")
+			secret, err = r.defineCredentialsSecret(database)
+			if err != nil {
+				log.Info("Failed to generate password. Re-running reconcile.")
+				return ctrl.Result{}, err
+			}
+			err = r.Create(ctx, secret)
+			if err != nil {
+				log.Info("Failed to create secret resource. Re-running reconcile.")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		}
+		log.Info("Failed to get secret resource " + passwordRef.Name + ". Re-running reconcile.")
+		return ctrl.Result{}, fmt.Errorf("password secret %s cannot be read: %v", passwordRef.Name, err)
+	}
+	if len(secret.Data[passwordRef.Key]) == 0 {
+		return ctrl.Result{}, fmt.Errorf("password secret %s doesn't contain the key %s", passwordRef.Name, passwordRef.Key)
+	}
+
+	if secret.Labels[databasesamplev1beta1.GeneratedSecretLabel] == database.Name && metav1.GetControllerOf(secret) == nil {
+		log.Info("Adopting secret resource " + passwordRef.Name)
+		ctrl.SetControllerReference(database, secret, r.Scheme)
+		err = r.Update(ctx, secret)
+		if err != nil {
+			log.Info("Failed to update secret resource. Re-running reconcile.")
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// Note: Inline credentials of databases which have been written via v1alpha1 are only written into the generated
+// secrets. Secrets which the database references otherwise are never changed.
+func (r *DatabaseReconciler) reconcileInlineCredentials(ctx context.Context, database *databasesamplev1beta1.Database) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	stashed, ok := database.Annotations[databasesamplev1beta1.InlineCredentialsAnnotation]
+	if !ok {
+		return ctrl.Result{}, nil
+	}
+	inlineCredentials := databasesamplev1beta1.InlineCredentials{}
+	err := json.Unmarshal([]byte(stashed), &inlineCredentials)
+	if err != nil {
+		log.Info("Annotation " + databasesamplev1beta1.InlineCredentialsAnnotation + " cannot be parsed. Ignoring inline credentials")
+	}
+
+	passwordSecretName := databasesamplev1beta1.GetGeneratedPasswordSecretName(database)
+	if err == nil && inlineCredentials.Password != "" {
+		if database.GetPasswordSecretRef().Name != passwordSecretName {
+			log.Info("Database references the password secret " + database.GetPasswordSecretRef().Name + ". Ignoring inline password")
+		} else {
+			err = r.writeGeneratedSecretKey(ctx, database, passwordSecretName, databasesamplev1beta1.PasswordSecretKey, inlineCredentials.Password)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+	tlsSecretName := databasesamplev1beta1.GetGeneratedTLSSecretName(database)
+	if err == nil && inlineCredentials.Certificate != "" {
+		if database.Spec.TLSSecretRef == nil || database.Spec.TLSSecretRef.Name != tlsSecretName {
+			log.Info("Database doesn't reference the TLS secret " + tlsSecretName + ". Ignoring inline certificate")
+		} else {
+			err = r.writeGeneratedSecretKey(ctx, database, tlsSecretName, databasesamplev1beta1.CACertificateSecretKey, inlineCredentials.Certificate)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	log.Info("Removing annotation " + databasesamplev1beta1.InlineCredentialsAnnotation + " from database resource")
+	delete(database.Annotations, databasesamplev1beta1.InlineCredentialsAnnotation)
+	err = r.Update(ctx, database)
+	if err != nil {
+		log.Info("Failed to update database resource. Re-running reconcile.")
+	}
+	return ctrl.Result{}, err
+}
+
+// Note: Secrets which exist already are only changed if the database controls them
+func (r *DatabaseReconciler) writeGeneratedSecretKey(ctx context.Context, database *databasesamplev1beta1.Database,
+	name string, key string, value string) error {
+
+	log := log.FromContext(ctx)
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, secret)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Info("Failed to get secret resource " + name + ". Re-running reconcile.")
+			return err
+		}
+		log.Info("Moving inline credentials of database " + database.Name + " into secret " + name)
+		labels := getLabels(database)
+		labels[databasesamplev1beta1.GeneratedSecretLabel] = database.Name
+		secret = &corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: database.Namespace, Labels: labels},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       map[string][]byte{key: []byte(value)},
+		}
+		ctrl.SetControllerReference(database, secret, r.Scheme)
+		err = r.Create(ctx, secret)
+		if err != nil {
+			log.Info("Failed to create secret resource. Re-running reconcile.")
+		}
+		return err
+	}
+	if !metav1.IsControlledBy(secret, database) && secret.Labels[databasesamplev1beta1.GeneratedSecretLabel] != database.Name {
+		return fmt.Errorf("secret %s already exists and is not managed by the database", name)
+	}
+	if string(secret.Data[key]) == value {
+		return nil
+	}
+	log.Info("Moving inline credentials of database " + database.Name + " into secret " + name)
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[key] = []byte(value)
+	err = r.Update(ctx, secret)
+	if err != nil {
+		log.Info("Failed to update secret resource. Re-running reconcile.")
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestInlineCredentialsDatabase(name string) *databasesamplev1beta1.Database {
+	database := newTestDatabase(name, databasesamplev1beta1.EnginePostgreSQL)
+	database.Annotations = map[string]string{databasesamplev1beta1.InlineCredentialsAnnotation: `{"password":"password"}`}
+	return database
+}
+
+var _ = Describe("Inline credentials", func() {
+	It("are moved into generated secrets which are owned by the database", func() {
+		database := newTestInlineCredentialsDatabase("inline-credentials")
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		defer k8sClient.Delete(ctx, database)
+
+		secret := &corev1.Secret{}
+		Eventually(getTestObject(databasesamplev1beta1.GetGeneratedPasswordSecretName(database), secret), testTimeout).Should(Succeed())
+		Expect(string(secret.Data[databasesamplev1beta1.PasswordSecretKey])).To(Equal("password"))
+		Expect(metav1.IsControlledBy(secret, database)).To(BeTrue())
+		Eventually(func() map[string]string {
+			Expect(getTestObject(database.Name, database)()).To(Succeed())
+			return database.Annotations
+		}, testTimeout).ShouldNot(HaveKey(databasesamplev1beta1.InlineCredentialsAnnotation))
+	})
+
+	It("don't overwrite secrets which are not managed by the database", func() {
+		database := newTestInlineCredentialsDatabase("inline-credentials-other")
+		other := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: databasesamplev1beta1.GetGeneratedPasswordSecretName(database), Namespace: "default"},
+			Data:       map[string][]byte{databasesamplev1beta1.PasswordSecretKey: []byte("other")},
+		}
+		Expect(k8sClient.Create(ctx, other)).To(Succeed())
+		defer k8sClient.Delete(ctx, other)
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		defer k8sClient.Delete(ctx, database)
+
+		Eventually(func() string {
+			Expect(getTestObject(database.Name, database)()).To(Succeed())
+			if condition := meta.FindStatusCondition(database.Status.Conditions, CONDITION_TYPE_DEGRADED); condition != nil {
+				return condition.Reason
+			}
+			return ""
+		}, testTimeout).Should(Equal(CONDITION_REASON_DEGRADED_RECONCILE_FAILED))
+		Expect(database.Annotations).To(HaveKey(databasesamplev1beta1.InlineCredentialsAnnotation))
+		Expect(getTestObject(other.Name, other)()).To(Succeed())
+		Expect(string(other.Data[databasesamplev1beta1.PasswordSecretKey])).To(Equal("other"))
+	})
+})
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"context"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Note: The headless service gives the pods of the stateful set stable DNS names
+func (r *resourceReconciler) defineHeadlessService(database *databasesamplev1beta1.Database, port int32) *corev1.Service {
+	service := r.defineService(database, port)
+	service.Name = getHeadlessServiceName(database)
+	service.Spec.ClusterIP = corev1.ClusterIPNone
+	service.Spec.PublishNotReadyAddresses = true
+	return service
+}
+
+// Note: The ports of services and containers are named after the engine
+func (r *resourceReconciler) defineService(database *databasesamplev1beta1.Database, port int32) *corev1.Service {
+	service := &corev1.Service{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{Name: getServiceName(database), Namespace: database.Namespace, Labels: getLabels(database)},
+		Spec: corev1.ServiceSpec{
+			Selector: getLabels(database),
+			Ports: []corev1.ServicePort{{
+				Name:       database.GetEngine(),
+				Protocol:   corev1.ProtocolTCP,
+				Port:       port,
+				TargetPort: intstr.FromString(database.GetEngine()),
+			}},
+		},
+	}
+
+	ctrl.SetControllerReference(database, service, r.Scheme)
+	return service
+}
+
+func (r *resourceReconciler) reconcileServices(ctx context.Context, database *databasesamplev1beta1.Database, port int32) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	for _, serviceDefinition := range []*corev1.Service{r.defineHeadlessService(database, port), r.defineService(database, port)} {
+		service := &corev1.Service{}
+		err := r.Get(ctx, types.NamespacedName{Name: serviceDefinition.Name, Namespace: database.Namespace}, service)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				log.Info("Service resource " + serviceDefinition.Name + " not found. Creating or re-creating service")
+				err = r.Create(ctx, serviceDefinition)
+				if err != nil {
+					log.Info("Failed to create service resource. Re-running reconcile.")
+					return ctrl.Result{}, err
+				}
+				continue
+			}
+			log.Info("Failed to get service resource " + serviceDefinition.Name + ". Re-running reconcile.")
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{}, nil
+}
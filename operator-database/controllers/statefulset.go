@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func secretEnvVar(name string, secretName string, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+				Key:                  key,
+			},
+		},
+	}
+}
+
+// Note: The stateful set is the same for all engines, only the container, its volumes, the user and the version differ
+func (r *resourceReconciler) defineStatefulSet(database *databasesamplev1beta1.Database,
+	container corev1.Container, volumes []corev1.Volume, user int64, version string) *appsv1.StatefulSet {
+
+	replicas := int32(1)
+	labels := getLabels(database)
+	allowPrivilegeEscalation := false
+	runAsNonRoot := true
+	container.SecurityContext = &corev1.SecurityContext{
+		AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+		Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+	}
+
+	statefulSet := &appsv1.StatefulSet{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "StatefulSet"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        getStatefulSetName(database),
+			Namespace:   database.Namespace,
+			Labels:      labels,
+			Annotations: map[string]string{versionAnnotation: version},
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    &replicas,
+			ServiceName: getHeadlessServiceName(database),
+			Selector:    &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					SecurityContext: &corev1.PodSecurityContext{
+						RunAsUser:    &user,
+						RunAsGroup:   &user,
+						FSGroup:      &user,
+						RunAsNonRoot: &runAsNonRoot,
+					},
+					Containers: []corev1.Container{container},
+					Volumes:    volumes,
+				},
+			},
+			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{
+				ObjectMeta: metav1.ObjectMeta{Name: dataVolumeName, Labels: labels},
+				Spec: corev1.PersistentVolumeClaimSpec{
+					AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceStorage: database.GetStorageSize()},
+					},
+				},
+			}},
+		},
+	}
+
+	// Note: Persistent volume claims are not deleted with the stateful set, so data survives the deletion of databases
+	ctrl.SetControllerReference(database, statefulSet, r.Scheme)
+	return statefulSet
+}
+
+// Note: The engine of existing stateful sets is not changed, since the data directory can't be migrated. If the version
+// changes, the pod template is replaced, which restarts the pod with the new image.
+func (r *resourceReconciler) reconcileStatefulSet(ctx context.Context, database *databasesamplev1beta1.Database,
+	definition *appsv1.StatefulSet) (*appsv1.StatefulSet, error) {
+
+	statefulSetName := getStatefulSetName(database)
+	log := log.FromContext(ctx)
+	statefulSet := &appsv1.StatefulSet{}
+	err := r.Get(ctx, types.NamespacedName{Name: statefulSetName, Namespace: database.Namespace}, statefulSet)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("StatefulSet resource " + statefulSetName + " not found. Creating or re-creating stateful set")
+			statefulSet = definition
+			err = r.Create(ctx, statefulSet)
+			if err != nil {
+				log.Info("Failed to create stateful set resource. Re-running reconcile.")
+				return nil, err
+			}
+			return statefulSet, nil
+		}
+		log.Info("Failed to get stateful set resource " + statefulSetName + ". Re-running reconcile.")
+		return nil, err
+	}
+	if statefulSet.Labels[labelName] != database.GetEngine() {
+		return nil, fmt.Errorf("engine of database cannot be changed from %s to %s", statefulSet.Labels[labelName], database.GetEngine())
+	}
+	version := definition.Annotations[versionAnnotation]
+	if statefulSet.Annotations[versionAnnotation] != version {
+		log.Info("StatefulSet resource " + statefulSetName + " runs another version. Updating stateful set to version " + version)
+		if statefulSet.Annotations == nil {
+			statefulSet.Annotations = map[string]string{}
+		}
+		statefulSet.Annotations[versionAnnotation] = version
+		statefulSet.Spec.Template = definition.Spec.Template
+		err = r.Update(ctx, statefulSet)
+		if err != nil {
+			log.Info("Failed to update stateful set resource. Re-running reconcile.")
+			return nil, err
+		}
+	}
+	return statefulSet, nil
+}
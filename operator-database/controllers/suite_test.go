@@ -17,6 +17,7 @@ limitations under the License.
 package controllers
 
 import (
+	"context"
 	"path/filepath"
 	"testing"
 
@@ -24,13 +25,16 @@ import (
 	. "github.com/onsi/gomega"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	"sigs.k8s.io/controller-runtime/pkg/envtest/printer"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	databasesamplev1alpha1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1alpha1"
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -40,6 +44,17 @@ import (
 var cfg *rest.Config
 var k8sClient client.Client
 var testEnv *envtest.Environment
+var ctx context.Context
+var cancel context.CancelFunc
+
+// Note: envtest doesn't run pods, so all engines are provided by the in-memory fake provider
+var fakeProvider *FakeProvider
+
+// Note: Specs which run the steps of a reconciler one by one use a fake client, since the controllers of the suite
+// would reconcile the same resources in between
+func newTestFakeClient(objects ...client.Object) client.Client {
+	return fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()
+}
 
 func TestAPIs(t *testing.T) {
 	RegisterFailHandler(Fail)
@@ -65,16 +80,46 @@ var _ = BeforeSuite(func() {
 	err = databasesamplev1alpha1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
+	err = databasesamplev1beta1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
 	//+kubebuilder:scaffold:scheme
 
 	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
 	Expect(err).NotTo(HaveOccurred())
 	Expect(k8sClient).NotTo(BeNil())
 
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{Scheme: scheme.Scheme, MetricsBindAddress: "0"})
+	Expect(err).NotTo(HaveOccurred())
+
+	fakeProvider = NewFakeProvider()
+	providers := map[string]Provider{
+		databasesamplev1beta1.EnginePostgreSQL: fakeProvider,
+		databasesamplev1beta1.EngineMySQL:      fakeProvider,
+	}
+	err = (&DatabaseReconciler{Client: mgr.GetClient(), Scheme: mgr.GetScheme(), Providers: providers}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+	err = (&DatabaseBackupReconciler{Client: mgr.GetClient(), Scheme: mgr.GetScheme(), Providers: providers}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+	err = (&DatabaseRestoreReconciler{Client: mgr.GetClient(), Scheme: mgr.GetScheme(), Providers: providers}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+	err = (&DatabaseUserReconciler{Client: mgr.GetClient(), Scheme: mgr.GetScheme(), Providers: providers}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		defer GinkgoRecover()
+		err := mgr.Start(ctx)
+		Expect(err).NotTo(HaveOccurred())
+	}()
+
 }, 60)
 
 var _ = AfterSuite(func() {
 	By("tearing down the test environment")
+	if cancel != nil {
+		cancel()
+	}
 	err := testEnv.Stop()
 	Expect(err).NotTo(HaveOccurred())
 })
@@ -0,0 +1,349 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Note: Upgrades are polled since the data volume which is deleted during major upgrades is not watched
+const upgradeRequeueInterval = 10 * time.Second
+
+// Note: Every spec generation gets its own backup, so that canceled upgrades never reuse old dumps
+func getUpgradeBackupName(database *databasesamplev1beta1.Database) string {
+	return fmt.Sprintf("%s-upgrade-%d", database.Name, database.Generation)
+}
+
+// reconcileUpgrade returns the version which the database is provisioned with. The version is empty while the stateful
+// set and the data volume are deleted during major upgrades.
+func (r *DatabaseReconciler) reconcileUpgrade(ctx context.Context, database *databasesamplev1beta1.Database,
+	provider Provider) (string, error) {
+
+	log := log.FromContext(ctx)
+	r.setCondition(database, CONDITION_TYPE_UPGRADE_FAILED, CONDITION_STATUS_FALSE,
+		CONDITION_REASON_UPGRADE_NOT_FAILED, CONDITION_MESSAGE_UPGRADE_NOT_FAILED)
+	version := getVersion(database, provider)
+	upgrade := database.Status.Upgrade
+
+	// Note: Upgrades can be canceled or redirected to other versions as long as the data hasn't been touched
+	if upgrade != nil && upgrade.Phase == databasesamplev1beta1.UpgradePhaseBackingUp && upgrade.ToVersion != version {
+		log.Info("Version of database " + database.Name + " has been changed during the backup. Canceling upgrade to " + upgrade.ToVersion)
+		database.Status.Upgrade = nil
+		upgrade = nil
+	}
+
+	if upgrade == nil {
+		current, err := provider.Status(ctx, database)
+		if err != nil {
+			return "", err
+		}
+		// Note: Upgrading to the same version checks whether the version is supported
+		fromVersion := current.Version
+		if fromVersion == "" {
+			fromVersion = version
+		}
+		strategy, err := provider.UpgradeStrategy(fromVersion, version)
+		if err != nil {
+			if current.Version == "" {
+				return "", err
+			}
+			r.setCondition(database, CONDITION_TYPE_UPGRADE_FAILED, CONDITION_STATUS_TRUE,
+				CONDITION_REASON_UPGRADE_NOT_SUPPORTED, err.Error())
+			return current.Version, nil
+		}
+		if fromVersion == version {
+			return version, nil
+		}
+		log.Info("Upgrading database " + database.Name + " from version " + fromVersion + " to " + version + " with strategy " + strategy)
+		upgrade = &databasesamplev1beta1.DatabaseUpgrade{
+			FromVersion: fromVersion,
+			ToVersion:   version,
+			Strategy:    strategy,
+			Phase:       databasesamplev1beta1.UpgradePhaseBackingUp,
+			BackupName:  getUpgradeBackupName(database),
+		}
+		database.Status.Upgrade = upgrade
+	}
+
+	if upgrade.Phase == databasesamplev1beta1.UpgradePhaseBackingUp {
+		backedUp, err := r.reconcileUpgradeBackup(ctx, database, upgrade)
+		if err != nil || !backedUp {
+			return upgrade.FromVersion, err
+		}
+		upgrade.Phase = databasesamplev1beta1.UpgradePhaseRestarting
+		if upgrade.Strategy == databasesamplev1beta1.UpgradeStrategyDumpRestore {
+			upgrade.Phase = databasesamplev1beta1.UpgradePhaseReplacing
+		}
+	}
+	if upgrade.Phase == databasesamplev1beta1.UpgradePhaseReplacing {
+		replaced, err := r.reconcileDataVolumeDeletion(ctx, database, upgrade)
+		if err != nil || !replaced {
+			return "", err
+		}
+		upgrade.Phase = databasesamplev1beta1.UpgradePhaseRestoring
+	}
+	return upgrade.ToVersion, nil
+}
+
+// Note: Upgrades are completed when the database runs the new version and, for major upgrades, the dump has been restored
+func (r *DatabaseReconciler) completeUpgrade(ctx context.Context, database *databasesamplev1beta1.Database,
+	providerStatus *ProviderStatus) error {
+
+	log := log.FromContext(ctx)
+	upgrade := database.Status.Upgrade
+	if upgrade != nil && providerStatus.Ready && providerStatus.Version == upgrade.ToVersion {
+		completed := upgrade.Phase == databasesamplev1beta1.UpgradePhaseRestarting
+		if upgrade.Phase == databasesamplev1beta1.UpgradePhaseRestoring {
+			var err error
+			completed, err = r.reconcileUpgradeRestore(ctx, database, upgrade)
+			if err != nil {
+				return err
+			}
+		}
+		if completed && upgrade.PreviousVolumeName != "" {
+			err := r.releasePreviousVolume(ctx, upgrade)
+			if err != nil {
+				return err
+			}
+		}
+		if completed {
+			log.Info("Database " + database.Name + " has been upgraded from version " + upgrade.FromVersion + " to " + upgrade.ToVersion)
+			database.Status.Upgrade = nil
+			upgrade = nil
+		}
+	}
+
+	switch {
+	case upgrade == nil:
+		r.setCondition(database, CONDITION_TYPE_UPGRADING, CONDITION_STATUS_FALSE,
+			CONDITION_REASON_VERSION_UP_TO_DATE, CONDITION_MESSAGE_VERSION_UP_TO_DATE)
+	case meta.IsStatusConditionTrue(database.Status.Conditions, CONDITION_TYPE_UPGRADE_FAILED):
+		r.setCondition(database, CONDITION_TYPE_UPGRADING, CONDITION_STATUS_FALSE, CONDITION_REASON_UPGRADE_STOPPED,
+			fmt.Sprintf("Upgrade from version %s to %s stopped in phase %s", upgrade.FromVersion, upgrade.ToVersion, upgrade.Phase))
+	default:
+		r.setCondition(database, CONDITION_TYPE_UPGRADING, CONDITION_STATUS_TRUE, CONDITION_REASON_UPGRADE_IN_PROGRESS,
+			fmt.Sprintf("Upgrading from version %s to %s, phase %s", upgrade.FromVersion, upgrade.ToVersion, upgrade.Phase))
+	}
+	return nil
+}
+
+// Note: Dumps are written to a claim which is not owned by the database, so that they survive the deletion of databases
+func (r *DatabaseReconciler) reconcileUpgradeBackup(ctx context.Context, database *databasesamplev1beta1.Database,
+	upgrade *databasesamplev1beta1.DatabaseUpgrade) (bool, error) {
+
+	log := log.FromContext(ctx)
+	upgradeBackupClaimName := getUpgradeBackupClaimName(database)
+	claim := &corev1.PersistentVolumeClaim{}
+	err := r.Get(ctx, types.NamespacedName{Name: upgradeBackupClaimName, Namespace: database.Namespace}, claim)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Info("Failed to get persistent volume claim resource " + upgradeBackupClaimName + ". Re-running reconcile.")
+			return false, err
+		}
+		log.Info("PersistentVolumeClaim resource " + upgradeBackupClaimName + " not found. Creating or re-creating claim")
+		err = r.Create(ctx, r.defineUpgradeBackupClaim(database))
+		if err != nil {
+			log.Info("Failed to create persistent volume claim resource. Re-running reconcile.")
+			return false, err
+		}
+	}
+
+	backup := &databasesamplev1beta1.DatabaseBackup{}
+	err = r.Get(ctx, types.NamespacedName{Name: upgrade.BackupName, Namespace: database.Namespace}, backup)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Info("Failed to get database backup resource " + upgrade.BackupName + ". Re-running reconcile.")
+			return false, err
+		}
+		log.Info("DatabaseBackup resource " + upgrade.BackupName + " not found. Creating or re-creating backup")
+		err = r.Create(ctx, r.defineUpgradeBackup(database, upgrade))
+		if err != nil {
+			log.Info("Failed to create database backup resource. Re-running reconcile.")
+		}
+		return false, err
+	}
+
+	succeeded := meta.FindStatusCondition(backup.Status.Conditions, CONDITION_TYPE_SUCCEEDED)
+	if succeeded != nil && succeeded.Status == CONDITION_STATUS_FALSE {
+		r.setCondition(database, CONDITION_TYPE_UPGRADE_FAILED, CONDITION_STATUS_TRUE,
+			CONDITION_REASON_UPGRADE_BACKUP_FAILED, CONDITION_MESSAGE_UPGRADE_BACKUP_FAILED)
+		return false, nil
+	}
+	return succeeded != nil && succeeded.Status == CONDITION_STATUS_TRUE && backup.Status.LastBackup != nil, nil
+}
+
+func (r *DatabaseReconciler) defineUpgradeBackupClaim(database *databasesamplev1beta1.Database) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolumeClaim"},
+		ObjectMeta: metav1.ObjectMeta{Name: getUpgradeBackupClaimName(database), Namespace: database.Namespace, Labels: getLabels(database)},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: database.GetStorageSize()},
+			},
+		},
+	}
+}
+
+func (r *DatabaseReconciler) defineUpgradeBackup(database *databasesamplev1beta1.Database,
+	upgrade *databasesamplev1beta1.DatabaseUpgrade) *databasesamplev1beta1.DatabaseBackup {
+
+	backup := &databasesamplev1beta1.DatabaseBackup{
+		TypeMeta:   metav1.TypeMeta{APIVersion: databasesamplev1beta1.GroupVersion.String(), Kind: "DatabaseBackup"},
+		ObjectMeta: metav1.ObjectMeta{Name: upgrade.BackupName, Namespace: database.Namespace},
+		Spec: databasesamplev1beta1.DatabaseBackupSpec{
+			DatabaseName: database.Name,
+			Target: databasesamplev1beta1.BackupTarget{
+				PersistentVolumeClaim: &databasesamplev1beta1.PersistentVolumeClaimTarget{ClaimName: getUpgradeBackupClaimName(database)},
+			},
+		},
+	}
+
+	ctrl.SetControllerReference(database, backup, r.Scheme)
+	return backup
+}
+
+// Note: The stateful set is deleted first, since the data volume is only deleted when no pod uses it anymore. Claims
+// cannot be renamed, so the volume is retained when its claim is deleted, until the dump has been restored.
+func (r *DatabaseReconciler) reconcileDataVolumeDeletion(ctx context.Context, database *databasesamplev1beta1.Database,
+	upgrade *databasesamplev1beta1.DatabaseUpgrade) (bool, error) {
+
+	log := log.FromContext(ctx)
+	statefulSetName := getStatefulSetName(database)
+	dataClaimName := getDataClaimName(database)
+	statefulSet := &appsv1.StatefulSet{}
+	err := r.Get(ctx, types.NamespacedName{Name: statefulSetName, Namespace: database.Namespace}, statefulSet)
+	if err == nil {
+		if statefulSet.DeletionTimestamp.IsZero() {
+			log.Info("Deleting stateful set resource " + statefulSetName + " for the major upgrade")
+			err = r.Delete(ctx, statefulSet, client.PropagationPolicy(metav1.DeletePropagationForeground))
+		}
+		return false, client.IgnoreNotFound(err)
+	}
+	if !errors.IsNotFound(err) {
+		log.Info("Failed to get stateful set resource " + statefulSetName + ". Re-running reconcile.")
+		return false, err
+	}
+
+	claim := &corev1.PersistentVolumeClaim{}
+	err = r.Get(ctx, types.NamespacedName{Name: dataClaimName, Namespace: database.Namespace}, claim)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		log.Info("Failed to get persistent volume claim resource " + dataClaimName + ". Re-running reconcile.")
+		return false, err
+	}
+	if claim.Spec.VolumeName != "" && upgrade.PreviousVolumeName == "" {
+		err = r.retainVolume(ctx, claim.Spec.VolumeName)
+		if err != nil {
+			return false, err
+		}
+		upgrade.PreviousVolumeName = claim.Spec.VolumeName
+	}
+	if claim.DeletionTimestamp.IsZero() {
+		log.Info("Deleting persistent volume claim resource " + dataClaimName + " for the major upgrade")
+		err = r.Delete(ctx, claim)
+	}
+	return false, client.IgnoreNotFound(err)
+}
+
+// Note: The original reclaim policy is stored in an annotation of the volume, so that it is restored after the upgrade
+func (r *DatabaseReconciler) retainVolume(ctx context.Context, volumeName string) error {
+	log := log.FromContext(ctx)
+	volume := &corev1.PersistentVolume{}
+	err := r.Get(ctx, types.NamespacedName{Name: volumeName}, volume)
+	if err != nil {
+		log.Info("Failed to get persistent volume resource " + volumeName + ". Re-running reconcile.")
+		return err
+	}
+	if volume.Spec.PersistentVolumeReclaimPolicy == corev1.PersistentVolumeReclaimRetain {
+		return nil
+	}
+	log.Info("Retaining persistent volume resource " + volumeName + " until the dump has been restored")
+	if volume.Annotations == nil {
+		volume.Annotations = map[string]string{}
+	}
+	volume.Annotations[reclaimPolicyAnnotation] = string(volume.Spec.PersistentVolumeReclaimPolicy)
+	volume.Spec.PersistentVolumeReclaimPolicy = corev1.PersistentVolumeReclaimRetain
+	err = r.Update(ctx, volume)
+	if err != nil {
+		log.Info("Failed to update persistent volume resource. Re-running reconcile.")
+	}
+	return err
+}
+
+// Note: Volumes which had the policy Retain before the upgrade are kept
+func (r *DatabaseReconciler) releasePreviousVolume(ctx context.Context, upgrade *databasesamplev1beta1.DatabaseUpgrade) error {
+	log := log.FromContext(ctx)
+	volume := &corev1.PersistentVolume{}
+	err := r.Get(ctx, types.NamespacedName{Name: upgrade.PreviousVolumeName}, volume)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		log.Info("Failed to get persistent volume resource " + upgrade.PreviousVolumeName + ". Re-running reconcile.")
+		return err
+	}
+	reclaimPolicy, ok := volume.Annotations[reclaimPolicyAnnotation]
+	if !ok {
+		return nil
+	}
+	log.Info("Releasing persistent volume resource " + upgrade.PreviousVolumeName + " with reclaim policy " + reclaimPolicy)
+	delete(volume.Annotations, reclaimPolicyAnnotation)
+	volume.Spec.PersistentVolumeReclaimPolicy = corev1.PersistentVolumeReclaimPolicy(reclaimPolicy)
+	err = r.Update(ctx, volume)
+	if err != nil {
+		log.Info("Failed to update persistent volume resource. Re-running reconcile.")
+	}
+	return err
+}
+
+// Note: The restore has the name of the backup, it waits until the database accepts connections
+func (r *DatabaseReconciler) reconcileUpgradeRestore(ctx context.Context, database *databasesamplev1beta1.Database,
+	upgrade *databasesamplev1beta1.DatabaseUpgrade) (bool, error) {
+
+	log := log.FromContext(ctx)
+	restore := &databasesamplev1beta1.DatabaseRestore{}
+	err := r.Get(ctx, types.NamespacedName{Name: upgrade.BackupName, Namespace: database.Namespace}, restore)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Info("Failed to get database restore resource " + upgrade.BackupName + ". Re-running reconcile.")
+			return false, err
+		}
+		log.Info("DatabaseRestore resource " + upgrade.BackupName + " not found. Creating or re-creating restore")
+		restore = &databasesamplev1beta1.DatabaseRestore{
+			TypeMeta:   metav1.TypeMeta{APIVersion: databasesamplev1beta1.GroupVersion.String(), Kind: "DatabaseRestore"},
+			ObjectMeta: metav1.ObjectMeta{Name: upgrade.BackupName, Namespace: database.Namespace},
+			Spec: databasesamplev1beta1.DatabaseRestoreSpec{
+				BackupName:   upgrade.BackupName,
+				DatabaseName: database.Name,
+			},
+		}
+		ctrl.SetControllerReference(database, restore, r.Scheme)
+		err = r.Create(ctx, restore)
+		if err != nil {
+			log.Info("Failed to create database restore resource. Re-running reconcile.")
+		}
+		return false, err
+	}
+
+	succeeded := meta.FindStatusCondition(restore.Status.Conditions, CONDITION_TYPE_SUCCEEDED)
+	if succeeded != nil && succeeded.Status == CONDITION_STATUS_FALSE {
+		r.setCondition(database, CONDITION_TYPE_UPGRADE_FAILED, CONDITION_STATUS_TRUE,
+			CONDITION_REASON_UPGRADE_RESTORE_FAILED, CONDITION_MESSAGE_UPGRADE_RESTORE_FAILED)
+		return false, nil
+	}
+	return succeeded != nil && succeeded.Status == CONDITION_STATUS_TRUE, nil
+}
@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Database upgrades", func() {
+	var database *databasesamplev1beta1.Database
+	var provider *FakeProvider
+	var reconciler *DatabaseReconciler
+	var runningVersion string
+
+	BeforeEach(func() {
+		database = newTestDatabase("database", databasesamplev1beta1.EnginePostgreSQL)
+		database.Generation = 2
+		provider = NewFakeProvider()
+	})
+
+	JustBeforeEach(func() {
+		reconciler = &DatabaseReconciler{
+			Client:    newTestFakeClient(database),
+			Scheme:    scheme.Scheme,
+			Providers: map[string]Provider{databasesamplev1beta1.EnginePostgreSQL: provider},
+		}
+		Expect(provider.Provision(ctx, database, runningVersion)).To(Succeed())
+	})
+
+	table.DescribeTable("choose the strategy by the versions",
+		func(from string, to string, expected string) {
+			strategy, err := getUpgradeStrategy(from, to, postgresMajorVersions)
+			Expect(strategy).To(Equal(expected))
+			Expect(err == nil).To(Equal(expected != ""))
+		},
+		table.Entry("minor", "14", "14.10", databasesamplev1beta1.UpgradeStrategyRollingRestart),
+		table.Entry("major", "14", "16", databasesamplev1beta1.UpgradeStrategyDumpRestore),
+		table.Entry("downgrade", "15", "14", ""),
+		table.Entry("unsupported major", "14", "17", ""),
+	)
+
+	Context("of minor versions", func() {
+		BeforeEach(func() {
+			runningVersion = "1"
+			database.Spec.Version = "1.1"
+		})
+
+		It("wait for the backup before the restart", func() {
+			version, err := reconciler.reconcileUpgrade(ctx, database, provider)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal("1"))
+			upgrade := database.Status.Upgrade
+			Expect(upgrade).NotTo(BeNil())
+			Expect(upgrade.Phase).To(Equal(databasesamplev1beta1.UpgradePhaseBackingUp))
+			Expect(upgrade.BackupName).To(Equal("database-upgrade-2"))
+			backup := &databasesamplev1beta1.DatabaseBackup{}
+			Expect(reconciler.Get(ctx, types.NamespacedName{Name: upgrade.BackupName, Namespace: database.Namespace}, backup)).To(Succeed())
+			Expect(backup.Spec.Target.PersistentVolumeClaim).NotTo(BeNil())
+			Expect(backup.Spec.Target.PersistentVolumeClaim.ClaimName).To(Equal("database-upgrade-backups"))
+
+			backup.Status.LastBackup = &databasesamplev1beta1.BackupFile{Name: "database-upgrade-2-backup.dump"}
+			setJobCondition(&backup.Status.Conditions, backup.Generation, CONDITION_TYPE_SUCCEEDED, CONDITION_STATUS_TRUE,
+				CONDITION_REASON_BACKUP_SUCCEEDED, "")
+			Expect(reconciler.Status().Update(ctx, backup)).To(Succeed())
+			version, err = reconciler.reconcileUpgrade(ctx, database, provider)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal("1.1"))
+			Expect(upgrade.Phase).To(Equal(databasesamplev1beta1.UpgradePhaseRestarting))
+
+			Expect(provider.Provision(ctx, database, version)).To(Succeed())
+			status, _ := provider.Status(ctx, database)
+			Expect(reconciler.completeUpgrade(ctx, database, status)).To(Succeed())
+			Expect(database.Status.Upgrade).To(BeNil())
+			Expect(meta.IsStatusConditionFalse(database.Status.Conditions, CONDITION_TYPE_UPGRADING)).To(BeTrue())
+		})
+	})
+
+	Context("of unsupported versions", func() {
+		BeforeEach(func() {
+			runningVersion = "2"
+			database.Spec.Version = "1"
+		})
+
+		It("keep the running version", func() {
+			version, err := reconciler.reconcileUpgrade(ctx, database, provider)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal("2"))
+			Expect(database.Status.Upgrade).To(BeNil())
+			Expect(meta.IsStatusConditionTrue(database.Status.Conditions, CONDITION_TYPE_UPGRADE_FAILED)).To(BeTrue())
+		})
+	})
+
+	Context("of major versions", func() {
+		BeforeEach(func() {
+			runningVersion = "1"
+			database.Spec.Version = "2"
+		})
+
+		It("retain the data volume until the dump has been restored", func() {
+			volume := &corev1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pv-data"},
+				Spec:       corev1.PersistentVolumeSpec{PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimDelete},
+			}
+			claim := &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: getDataClaimName(database), Namespace: database.Namespace},
+				Spec:       corev1.PersistentVolumeClaimSpec{VolumeName: volume.Name},
+			}
+			for _, object := range []client.Object{volume, claim} {
+				Expect(reconciler.Create(ctx, object)).To(Succeed())
+			}
+			database.Status.Upgrade = &databasesamplev1beta1.DatabaseUpgrade{
+				FromVersion: "1",
+				ToVersion:   "2",
+				Strategy:    databasesamplev1beta1.UpgradeStrategyDumpRestore,
+				Phase:       databasesamplev1beta1.UpgradePhaseReplacing,
+				BackupName:  "database-upgrade-2",
+			}
+			upgrade := database.Status.Upgrade
+
+			for upgrade.Phase == databasesamplev1beta1.UpgradePhaseReplacing {
+				_, err := reconciler.reconcileUpgrade(ctx, database, provider)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(volume), volume)).To(Succeed())
+			Expect(upgrade.PreviousVolumeName).To(Equal(volume.Name))
+			Expect(volume.Spec.PersistentVolumeReclaimPolicy).To(Equal(corev1.PersistentVolumeReclaimRetain))
+
+			Expect(provider.Provision(ctx, database, upgrade.ToVersion)).To(Succeed())
+			status, _ := provider.Status(ctx, database)
+			Expect(reconciler.completeUpgrade(ctx, database, status)).To(Succeed())
+			restore := &databasesamplev1beta1.DatabaseRestore{}
+			Expect(reconciler.Get(ctx, types.NamespacedName{Name: upgrade.BackupName, Namespace: database.Namespace}, restore)).To(Succeed())
+
+			By("keeping the volume after a failed restore")
+			setJobCondition(&restore.Status.Conditions, restore.Generation, CONDITION_TYPE_SUCCEEDED, CONDITION_STATUS_FALSE,
+				CONDITION_REASON_RESTORE_FAILED, "")
+			Expect(reconciler.Status().Update(ctx, restore)).To(Succeed())
+			Expect(reconciler.completeUpgrade(ctx, database, status)).To(Succeed())
+			Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(volume), volume)).To(Succeed())
+			Expect(database.Status.Upgrade).NotTo(BeNil())
+			Expect(volume.Spec.PersistentVolumeReclaimPolicy).To(Equal(corev1.PersistentVolumeReclaimRetain))
+
+			By("releasing the volume after a successful restore")
+			setJobCondition(&restore.Status.Conditions, restore.Generation, CONDITION_TYPE_SUCCEEDED, CONDITION_STATUS_TRUE,
+				CONDITION_REASON_RESTORE_SUCCEEDED, "")
+			Expect(reconciler.Status().Update(ctx, restore)).To(Succeed())
+			Expect(reconciler.completeUpgrade(ctx, database, status)).To(Succeed())
+			Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(volume), volume)).To(Succeed())
+			Expect(database.Status.Upgrade).To(BeNil())
+			Expect(volume.Spec.PersistentVolumeReclaimPolicy).To(Equal(corev1.PersistentVolumeReclaimDelete))
+		})
+	})
+})
@@ -0,0 +1,11 @@
+package controllers
+
+// Note: Ready is True when the user has been created with the current password and privileges
+const CONDITION_REASON_USER_READY = "UserReady"
+const CONDITION_MESSAGE_USER_READY = "User has been created with the current password and privileges"
+const CONDITION_MESSAGE_USER_JOB_RUNNING = "User job is running"
+const CONDITION_REASON_USER_JOB_FAILED = "UserJobFailed"
+const CONDITION_MESSAGE_USER_JOB_FAILED = "User job failed, see the logs of the job"
+const CONDITION_REASON_INVALID_USERNAME = "InvalidUsername"
+const CONDITION_REASON_DROP_JOB_FAILED = "DropJobFailed"
+const CONDITION_MESSAGE_DROP_JOB_FAILED = "User cannot be dropped, see the logs of the job. Remove the finalizer to delete the resource anyway."
@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// Note: Users are dropped from the database before the resource is deleted
+const userFinalizer = "database.sample.third.party/user"
+
+const userPasswordLength = 24
+
+type DatabaseUserReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Note: Providers of the engines, see NewProviders. If not set, the providers of all supported engines are used.
+	Providers map[string]Provider
+}
+
+//+kubebuilder:rbac:groups=database.sample.third.party,resources=databaseusers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=database.sample.third.party,resources=databaseusers/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=database.sample.third.party,resources=databaseusers/finalizers,verbs=update
+//+kubebuilder:rbac:groups=database.sample.third.party,resources=databases,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+func (r *DatabaseUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	log.Info("Reconcile started")
+
+	user := &databasesamplev1beta1.DatabaseUser{}
+	err := r.Get(ctx, req.NamespacedName, user)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("DatabaseUser resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		log.Info("Failed to get database user resource. Re-running reconcile.")
+		return ctrl.Result{}, err
+	}
+
+	if !user.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.finalizeUser(ctx, user)
+	}
+	if !controllerutil.ContainsFinalizer(user, userFinalizer) {
+		controllerutil.AddFinalizer(user, userFinalizer)
+		err = r.Update(ctx, user)
+		if err != nil {
+			log.Info("Failed to add finalizer to database user resource. Re-running reconcile.")
+			return ctrl.Result{}, err
+		}
+	}
+
+	original := user.DeepCopy()
+	user.Status.ObservedGeneration = user.Generation
+	err = r.reconcileUser(ctx, user)
+	if err != nil {
+		r.setUserCondition(user, CONDITION_STATUS_FALSE, CONDITION_REASON_RECONCILE_FAILED, err.Error())
+	}
+	statusErr := r.updateUserStatus(ctx, original, user)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if statusErr != nil {
+		return ctrl.Result{}, statusErr
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *DatabaseUserReconciler) reconcileUser(ctx context.Context, user *databasesamplev1beta1.DatabaseUser) error {
+	if user.Status.Username != "" && user.Status.Username != user.Spec.Username {
+		return fmt.Errorf("username cannot be changed from %s to %s", user.Status.Username, user.Spec.Username)
+	}
+	database := &databasesamplev1beta1.Database{}
+	err := r.Get(ctx, types.NamespacedName{Name: user.Spec.DatabaseName, Namespace: user.Namespace}, database)
+	if err != nil {
+		return fmt.Errorf("database %s cannot be read: %v", user.Spec.DatabaseName, err)
+	}
+	// Note: The webhook rejects these users as well, but it cannot check the owner of databases which don't exist yet
+	errorList := databasesamplev1beta1.ValidateDatabaseUsername(field.NewPath("spec", "username"), user.Spec.Username, database)
+	if len(errorList) > 0 {
+		r.setUserCondition(user, CONDITION_STATUS_FALSE, CONDITION_REASON_INVALID_USERNAME, errorList.ToAggregate().Error())
+		return nil
+	}
+	if !meta.IsStatusConditionTrue(database.Status.Conditions, CONDITION_TYPE_READY) {
+		r.setUserCondition(user, CONDITION_STATUS_FALSE, CONDITION_REASON_WAITING_FOR_DATABASE, CONDITION_MESSAGE_WAITING_FOR_DATABASE)
+		return nil
+	}
+	provider, err := getProvider(r.Providers, database)
+	if err != nil {
+		return err
+	}
+
+	password, err := r.reconcileUserSecret(ctx, user, database, provider)
+	if err != nil {
+		return err
+	}
+	hooks := provider.UserHooks(database)
+	job, err := r.reconcileUserJob(ctx, user, getEnsureUserJobName(user, password), user.Spec.Username, hooks.EnsureCommand, hooks)
+	if err != nil {
+		return err
+	}
+	finished, succeeded := getJobResult(job)
+	switch {
+	case !finished:
+		r.setUserCondition(user, CONDITION_STATUS_FALSE, CONDITION_REASON_JOB_RUNNING, CONDITION_MESSAGE_USER_JOB_RUNNING)
+	case succeeded:
+		user.Status.Username = user.Spec.Username
+		r.setUserCondition(user, CONDITION_STATUS_TRUE, CONDITION_REASON_USER_READY, CONDITION_MESSAGE_USER_READY)
+		return r.deleteOutdatedUserJobs(ctx, user, job.Name)
+	default:
+		r.setUserCondition(user, CONDITION_STATUS_FALSE, CONDITION_REASON_USER_JOB_FAILED, CONDITION_MESSAGE_USER_JOB_FAILED)
+	}
+	return nil
+}
+
+// Note: The password is generated when the secret is created and when the rotation annotation changes. The connection
+// details are updated in every reconciliation.
+func (r *DatabaseUserReconciler) reconcileUserSecret(ctx context.Context, user *databasesamplev1beta1.DatabaseUser,
+	database *databasesamplev1beta1.Database, provider Provider) ([]byte, error) {
+
+	log := log.FromContext(ctx)
+	caCertificate, err := readCACertificate(ctx, r, database)
+	if err != nil {
+		return nil, err
+	}
+	rotation := user.Annotations[databasesamplev1beta1.RotatePasswordAnnotation]
+	secret := &corev1.Secret{}
+	err = r.Get(ctx, types.NamespacedName{Name: user.Spec.SecretName, Namespace: user.Namespace}, secret)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("Secret resource " + user.Spec.SecretName + " not found. Creating or re-creating secret")
+			password, err := r.generateUserPassword(user, rotation)
+			if err != nil {
+				return nil, err
+			}
+			secret = r.defineUserSecret(user, getBindingData(database, provider, user.Spec.Username, password, caCertificate))
+			err = r.Create(ctx, secret)
+			if err != nil {
+				log.Info("Failed to create secret resource. Re-running reconcile.")
+				return nil, err
+			}
+			return password, nil
+		}
+		log.Info("Failed to get secret resource " + user.Spec.SecretName + ". Re-running reconcile.")
+		return nil, err
+	}
+	if !metav1.IsControlledBy(secret, user) {
+		return nil, fmt.Errorf("secret %s already exists and is not managed by the database user", user.Spec.SecretName)
+	}
+
+	password := secret.Data[bindingKeyPassword]
+	if len(password) == 0 || rotation != user.Status.PasswordRotation {
+		log.Info("Rotating password of database user " + user.Name)
+		password, err = r.generateUserPassword(user, rotation)
+		if err != nil {
+			return nil, err
+		}
+	}
+	data := getBindingData(database, provider, user.Spec.Username, password, caCertificate)
+	if !equality.Semantic.DeepEqual(secret.Data, data) {
+		log.Info("Secret resource " + user.Spec.SecretName + " is out of date. Updating secret")
+		secret.Data = data
+		err = r.Update(ctx, secret)
+		if err != nil {
+			log.Info("Failed to update secret resource. Re-running reconcile.")
+			return nil, err
+		}
+	}
+	return password, nil
+}
+
+func (r *DatabaseUserReconciler) generateUserPassword(user *databasesamplev1beta1.DatabaseUser, rotation string) ([]byte, error) {
+	password, err := generatePassword(userPasswordLength)
+	if err != nil {
+		return nil, err
+	}
+	now := metav1.Now()
+	user.Status.PasswordRotation = rotation
+	user.Status.PasswordUpdateTime = &now
+	return []byte(password), nil
+}
+
+func (r *DatabaseUserReconciler) reconcileUserJob(ctx context.Context, user *databasesamplev1beta1.DatabaseUser, jobName string,
+	username string, command []string, hooks *UserHooks) (*batchv1.Job, error) {
+
+	log := log.FromContext(ctx)
+	job := &batchv1.Job{}
+	err := r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: user.Namespace}, job)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("Job resource " + jobName + " not found. Creating or re-creating job")
+			job = r.defineUserJob(user, jobName, username, command, hooks)
+			err = r.Create(ctx, job)
+			if err != nil {
+				log.Info("Failed to create job resource. Re-running reconcile.")
+				return nil, err
+			}
+			return job, nil
+		}
+		log.Info("Failed to get job resource " + jobName + ". Re-running reconcile.")
+		return nil, err
+	}
+	return job, nil
+}
+
+// Note: Jobs of previous passwords and privileges are deleted when the current job has succeeded
+func (r *DatabaseUserReconciler) deleteOutdatedUserJobs(ctx context.Context, user *databasesamplev1beta1.DatabaseUser, currentJobName string) error {
+	jobs := &batchv1.JobList{}
+	err := r.List(ctx, jobs, client.InNamespace(user.Namespace), client.MatchingLabels{userLabel: user.Name})
+	if err != nil {
+		return err
+	}
+	for i := range jobs.Items {
+		if jobs.Items[i].Name == currentJobName {
+			continue
+		}
+		err = r.Delete(ctx, &jobs.Items[i], client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Note: Users which have never been created and users of deleted databases don't need to be dropped
+func (r *DatabaseUserReconciler) finalizeUser(ctx context.Context, user *databasesamplev1beta1.DatabaseUser) error {
+	log := log.FromContext(ctx)
+	if !controllerutil.ContainsFinalizer(user, userFinalizer) {
+		return nil
+	}
+
+	database := &databasesamplev1beta1.Database{}
+	err := r.Get(ctx, types.NamespacedName{Name: user.Spec.DatabaseName, Namespace: user.Namespace}, database)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	// Note: Users which have been created before invalid usernames were rejected, e.g. the owner, are never dropped
+	if err == nil && database.DeletionTimestamp.IsZero() && user.Status.Username != "" &&
+		len(databasesamplev1beta1.ValidateDatabaseUsername(field.NewPath("status", "username"), user.Status.Username, database)) == 0 {
+		provider, err := getProvider(r.Providers, database)
+		if err != nil {
+			return err
+		}
+		hooks := provider.UserHooks(database)
+		job, err := r.reconcileUserJob(ctx, user, getDropUserJobName(user), user.Status.Username, hooks.DropCommand, hooks)
+		if err != nil {
+			return err
+		}
+		finished, succeeded := getJobResult(job)
+		if !finished {
+			return nil
+		}
+		if !succeeded {
+			original := user.DeepCopy()
+			r.setUserCondition(user, CONDITION_STATUS_FALSE, CONDITION_REASON_DROP_JOB_FAILED, CONDITION_MESSAGE_DROP_JOB_FAILED)
+			return r.updateUserStatus(ctx, original, user)
+		}
+	}
+
+	log.Info("Removing finalizer of database user " + user.Name)
+	controllerutil.RemoveFinalizer(user, userFinalizer)
+	err = r.Update(ctx, user)
+	if err != nil {
+		log.Info("Failed to remove finalizer of database user resource. Re-running reconcile.")
+	}
+	return err
+}
+
+func (r *DatabaseUserReconciler) setUserCondition(user *databasesamplev1beta1.DatabaseUser,
+	status metav1.ConditionStatus, reason string, message string) {
+
+	setJobCondition(&user.Status.Conditions, user.Generation, CONDITION_TYPE_READY, status, reason, message)
+}
+
+// Note: The status is only updated if it has changed to avoid unnecessary reconciliations
+func (r *DatabaseUserReconciler) updateUserStatus(ctx context.Context, original *databasesamplev1beta1.DatabaseUser,
+	user *databasesamplev1beta1.DatabaseUser) error {
+
+	log := log.FromContext(ctx)
+	if equality.Semantic.DeepEqual(original.Status, user.Status) {
+		return nil
+	}
+	err := r.Status().Update(ctx, user)
+	if err != nil {
+		log.Info("DatabaseUser resource status update failed.")
+	}
+	return err
+}
+
+// Note: Users wait for their databases, so changes of databases are mapped to the users which reference them
+func (r *DatabaseUserReconciler) findUsersForDatabase(object client.Object) []reconcile.Request {
+	users := &databasesamplev1beta1.DatabaseUserList{}
+	err := r.List(context.Background(), users, client.InNamespace(object.GetNamespace()))
+	if err != nil {
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, user := range users.Items {
+		if user.Spec.DatabaseName == object.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: user.Name, Namespace: user.Namespace},
+			})
+		}
+	}
+	return requests
+}
+
+func (r *DatabaseUserReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Providers == nil {
+		r.Providers = NewProviders(mgr.GetClient(), mgr.GetScheme())
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&databasesamplev1beta1.DatabaseUser{}).
+		Owns(&corev1.Secret{}).
+		Owns(&batchv1.Job{}).
+		Watches(&source.Kind{Type: &databasesamplev1beta1.Database{}}, handler.EnqueueRequestsFromMapFunc(r.findUsersForDatabase)).
+		Complete(r)
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const userLabel = "database.sample.third.party/user"
+const userJobHashLength = 10
+
+func getUserPrivileges(user *databasesamplev1beta1.DatabaseUser) string {
+	privileges := []string{}
+	for _, privilege := range user.Spec.Privileges {
+		privileges = append(privileges, string(privilege))
+	}
+	return strings.Join(privileges, ", ")
+}
+
+// Note: The name of the ensure job changes with the user, its privileges and its password, so that every change
+// runs a new job
+func getEnsureUserJobName(user *databasesamplev1beta1.DatabaseUser, password []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(user.Spec.Username + "\n" + getUserPrivileges(user) + "\n"))
+	hash.Write(password)
+	return user.Name + "-user-" + hex.EncodeToString(hash.Sum(nil))[:userJobHashLength]
+}
+
+func getDropUserJobName(user *databasesamplev1beta1.DatabaseUser) string {
+	return user.Name + "-user-drop"
+}
+
+// Note: The password is read from the secret of the user, so that it is not part of the job
+func (r *DatabaseUserReconciler) defineUserJob(user *databasesamplev1beta1.DatabaseUser, name string, username string,
+	command []string, hooks *UserHooks) *batchv1.Job {
+
+	labels := map[string]string{userLabel: user.Name}
+	backoffLimit := backupJobBackoffLimit
+	env := append([]corev1.EnvVar{}, hooks.Env...)
+	env = append(env,
+		corev1.EnvVar{Name: UserNameEnvName, Value: username},
+		secretEnvVar(UserPasswordEnvName, user.Spec.SecretName, bindingKeyPassword),
+		corev1.EnvVar{Name: UserPrivilegesEnvName, Value: getUserPrivileges(user)},
+	)
+	container := corev1.Container{
+		Name:    "user",
+		Image:   hooks.Image,
+		Command: command,
+		Env:     env,
+	}
+
+	job := &batchv1.Job{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: user.Namespace, Labels: labels},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       getJobPodSpec(nil, container, nil),
+			},
+		},
+	}
+
+	ctrl.SetControllerReference(user, job, r.Scheme)
+	return job
+}
+
+func (r *DatabaseUserReconciler) defineUserSecret(user *databasesamplev1beta1.DatabaseUser, data map[string][]byte) *corev1.Secret {
+	secret := &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: user.Spec.SecretName, Namespace: user.Namespace, Labels: map[string]string{userLabel: user.Name}},
+		Type:       corev1.SecretType("servicebinding.io/" + string(data[bindingKeyType])),
+		Data:       data,
+	}
+
+	ctrl.SetControllerReference(user, secret, r.Scheme)
+	return secret
+}
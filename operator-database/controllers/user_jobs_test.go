@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func newTestUser(name string, databaseName string, privileges ...databasesamplev1beta1.Privilege) *databasesamplev1beta1.DatabaseUser {
+	return &databasesamplev1beta1.DatabaseUser{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: databasesamplev1beta1.DatabaseUserSpec{
+			DatabaseName: databaseName,
+			Username:     "reader",
+			Privileges:   privileges,
+			SecretName:   name + "-connection",
+		},
+	}
+}
+
+var _ = Describe("Database user controller", func() {
+	It("names the jobs after the privileges and the password", func() {
+		user := newTestUser("reader", "database", databasesamplev1beta1.PrivilegeSelect)
+		name := getEnsureUserJobName(user, []byte("secret"))
+		Expect(getEnsureUserJobName(newTestUser("reader", "database", databasesamplev1beta1.PrivilegeSelect), []byte("secret"))).To(Equal(name))
+		Expect(getEnsureUserJobName(user, []byte("rotated"))).NotTo(Equal(name))
+		Expect(getEnsureUserJobName(newTestUser("reader", "database", databasesamplev1beta1.PrivilegeSelect,
+			databasesamplev1beta1.PrivilegeInsert), []byte("secret"))).NotTo(Equal(name))
+	})
+
+	It("reads the password of the jobs from the secret of the user", func() {
+		reconciler := &DatabaseUserReconciler{Scheme: scheme.Scheme}
+		user := newTestUser("reader", "database", databasesamplev1beta1.PrivilegeSelect, databasesamplev1beta1.PrivilegeUpdate)
+		hooks := NewFakeProvider().UserHooks(newTestDatabase("database", databasesamplev1beta1.EnginePostgreSQL))
+		job := reconciler.defineUserJob(user, getDropUserJobName(user), "reader", hooks.DropCommand, hooks)
+
+		env := job.Spec.Template.Spec.Containers[0].Env
+		Expect(env).To(ContainElement(corev1.EnvVar{Name: UserPrivilegesEnvName, Value: "SELECT, UPDATE"}))
+		for _, envVar := range env {
+			if envVar.ValueFrom != nil {
+				Expect(envVar.Name).To(Equal(UserPasswordEnvName))
+				Expect(envVar.ValueFrom.SecretKeyRef.Name).To(Equal("reader-connection"))
+			}
+		}
+		Expect(job.Labels).To(HaveKeyWithValue(userLabel, "reader"))
+		Expect(metav1.GetControllerOf(job)).NotTo(BeNil())
+	})
+
+	// Note: envtest doesn't run the webhooks, so the users are rejected by the controller
+	It("doesn't create users of the owner, the admin or the engine", func() {
+		database := newTestDatabase("users", databasesamplev1beta1.EnginePostgreSQL)
+		Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		defer k8sClient.Delete(ctx, database)
+
+		for name, username := range map[string]string{
+			"users-owner":    database.Spec.User,
+			"users-admin":    databasesamplev1beta1.PostgreSQLAdminUser,
+			"users-reserved": "pg_monitor",
+		} {
+			By(name)
+			user := newTestUser(name, database.Name, databasesamplev1beta1.PrivilegeSelect)
+			user.Spec.Username = username
+			Expect(k8sClient.Create(ctx, user)).To(Succeed())
+			defer k8sClient.Delete(ctx, user)
+			Eventually(func() string {
+				Expect(getTestObject(user.Name, user)()).To(Succeed())
+				if condition := meta.FindStatusCondition(user.Status.Conditions, CONDITION_TYPE_READY); condition != nil {
+					return condition.Reason
+				}
+				return ""
+			}, testTimeout).Should(Equal(CONDITION_REASON_INVALID_USERNAME))
+			Expect(getTestObject(user.Spec.SecretName, &corev1.Secret{})()).NotTo(Succeed())
+		}
+	})
+
+	// Note: The scripts are run with a mysql client which only prints the statements it receives
+	It("escapes the passwords in the scripts of MySQL", func() {
+		directory, err := os.MkdirTemp("", "mysql")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(directory)
+		Expect(os.WriteFile(filepath.Join(directory, "mysql"), []byte("#!/bin/sh\ncat\n"), 0755)).To(Succeed())
+
+		for _, script := range []string{mysqlEnsureUserScript, mysqlChangePasswordScript} {
+			command := exec.Command("bash", "-c", script)
+			command.Env = append(os.Environ(),
+				"PATH="+directory+string(os.PathListSeparator)+os.Getenv("PATH"),
+				UserNameEnvName+"=reader",
+				UserPasswordEnvName+`=it's a \secret`,
+			)
+			output, err := command.Output()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(output)).To(ContainSubstring(`IDENTIFIED BY 'it''s a \\secret';`))
+			Expect(string(output)).NotTo(ContainSubstring("it's"))
+		}
+	})
+})
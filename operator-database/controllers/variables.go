@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	databasesamplev1beta1 "github.com/nheidloff/operator-sample-go/operator-database/api/v1beta1"
+)
+
+// Note: The default version is used if the spec doesn't define a version. Images are tagged with the versions.
+const postgresDefaultVersion = databasesamplev1beta1.PostgreSQLDefaultVersion
+const postgresImageRepository = "docker.io/library/postgres"
+
+var postgresMajorVersions = []int{14, 15, 16}
+
+const postgresPort int32 = 5432
+const postgresAdminUser = databasesamplev1beta1.PostgreSQLAdminUser
+const postgresDataPath = "/var/lib/postgresql/data"
+const postgresInitPath = "/docker-entrypoint-initdb.d"
+const postgresInitScriptName = "create-user.sh"
+
+// Note: The official postgres and mysql images run as this user
+var postgresUser int64 = 999
+var mysqlUser int64 = 999
+
+const mysqlDefaultVersion = databasesamplev1beta1.MySQLDefaultVersion
+const mysqlImageRepository = "docker.io/library/mysql"
+
+var mysqlMajorVersions = []int{8}
+
+const mysqlPort int32 = 3306
+const mysqlAdminUser = databasesamplev1beta1.MySQLAdminUser
+const mysqlDataPath = "/var/lib/mysql"
+
+const dataVolumeName = "data"
+const initVolumeName = "init"
+
+// Note: The user from the spec is created by an init script when the data directory is initialized
+const postgresInitScript = `#!/bin/bash
+set -e
+psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" \
+  -v user="$DATABASE_USER" -v password="$DATABASE_PASSWORD" -v database="$POSTGRES_DB" <<-'EOSQL'
+	CREATE USER :"user" WITH PASSWORD :'password';
+	ALTER DATABASE :"database" OWNER TO :"user";
+EOSQL
+`
+
+// Note: Privileges are validated by the API and can be part of the statements. \gexec runs the generated statement.
+const postgresEnsureUserScript = `set -e
+psql -v ON_ERROR_STOP=1 -v user="$USER_NAME" -v password="$USER_PASSWORD" -v owner="$DATABASE_OWNER" -v database="$PGDATABASE" <<EOSQL
+SELECT format('CREATE ROLE %I LOGIN', :'user') WHERE NOT EXISTS (SELECT FROM pg_roles WHERE rolname = :'user') \gexec
+ALTER ROLE :"user" WITH LOGIN PASSWORD :'password';
+GRANT CONNECT ON DATABASE :"database" TO :"user";
+GRANT USAGE ON SCHEMA public TO :"user";
+REVOKE ALL ON ALL TABLES IN SCHEMA public FROM :"user";
+ALTER DEFAULT PRIVILEGES FOR ROLE :"owner" IN SCHEMA public REVOKE ALL ON TABLES FROM :"user";
+GRANT $USER_PRIVILEGES ON ALL TABLES IN SCHEMA public TO :"user";
+ALTER DEFAULT PRIVILEGES FOR ROLE :"owner" IN SCHEMA public GRANT $USER_PRIVILEGES ON TABLES TO :"user";
+EOSQL
+`
+
+const postgresDropUserScript = `set -e
+psql -v ON_ERROR_STOP=1 -v user="$USER_NAME" -v owner="$DATABASE_OWNER" <<'EOSQL'
+SELECT format('REASSIGN OWNED BY %I TO %I', :'user', :'owner') WHERE EXISTS (SELECT FROM pg_roles WHERE rolname = :'user') \gexec
+SELECT format('DROP OWNED BY %I', :'user') WHERE EXISTS (SELECT FROM pg_roles WHERE rolname = :'user') \gexec
+DROP ROLE IF EXISTS :"user";
+EOSQL
+`
+
+const postgresChangePasswordScript = `set -e
+psql -v ON_ERROR_STOP=1 -v user="$USER_NAME" -v password="$USER_PASSWORD" <<'EOSQL'
+ALTER ROLE :"user" WITH PASSWORD :'password';
+EOSQL
+`
+
+// Note: Referenced passwords are chosen by users, so quotes and backslashes are escaped
+const mysqlChangePasswordScript = `set -e
+PASSWORD=$(printf '%s' "$USER_PASSWORD" | sed -e 's/\\/\\\\/g' -e "s/'/''/g")
+mysql --host "$DATABASE_HOST" --port "$DATABASE_PORT" --user "$DATABASE_ADMIN_USER" <<EOSQL
+ALTER USER '$USER_NAME'@'%' IDENTIFIED BY '$PASSWORD';
+EOSQL
+`
+
+// Note: Passwords can be referenced from secrets of users as well, so they are escaped like in mysqlChangePasswordScript
+const mysqlEnsureUserScript = `set -e
+PASSWORD=$(printf '%s' "$USER_PASSWORD" | sed -e 's/\\/\\\\/g' -e "s/'/''/g")
+mysql --host "$DATABASE_HOST" --port "$DATABASE_PORT" --user "$DATABASE_ADMIN_USER" <<EOSQL
+CREATE USER IF NOT EXISTS '$USER_NAME'@'%' IDENTIFIED BY '$PASSWORD';
+ALTER USER '$USER_NAME'@'%' IDENTIFIED BY '$PASSWORD';
+REVOKE ALL PRIVILEGES, GRANT OPTION FROM '$USER_NAME'@'%';
+GRANT $USER_PRIVILEGES ON \` + "`" + `$DATABASE_NAME\` + "`" + `.* TO '$USER_NAME'@'%';
+EOSQL
+`
+
+const mysqlDropUserScript = `set -e
+mysql --host "$DATABASE_HOST" --port "$DATABASE_PORT" --user "$DATABASE_ADMIN_USER" <<EOSQL
+DROP USER IF EXISTS '$USER_NAME'@'%';
+EOSQL
+`
+
+const secretKeyAdminUsername = "admin-username"
+const secretKeyAdminPassword = "admin-password"
+const adminPasswordLength = 24
+
+// Note: Keys of the connection secret, see https://github.com/servicebinding/spec#well-known-secret-entries
+const bindingProvider = "operator-database"
+const bindingKeyType = "type"
+const bindingKeyProvider = "provider"
+const bindingKeyHost = "host"
+const bindingKeyPort = "port"
+const bindingKeyDatabase = "database"
+const bindingKeyUsername = "username"
+const bindingKeyPassword = "password"
+const bindingKeyJDBCURL = "jdbc-url"
+const bindingKeyCACertificate = "ca.crt"
+
+const labelName = "app.kubernetes.io/name"
+const labelInstance = "app.kubernetes.io/instance"
+const labelManagedBy = "app.kubernetes.io/managed-by"
+
+// Note: Stateful sets are annotated with the version of the engine which they run
+const versionAnnotation = "database.sample.third.party/version"
+
+// Note: Volumes of data directories which are retained during major upgrades are annotated with their reclaim policy
+const reclaimPolicyAnnotation = "database.sample.third.party/reclaim-policy"
+
+// Note: The names of the resources are derived from the database, since providers, backups and restores run outside of
+// the reconciliation of databases
+func getStatefulSetName(database *databasesamplev1beta1.Database) string {
+	return database.Name
+}
+
+func getHeadlessServiceName(database *databasesamplev1beta1.Database) string {
+	return database.Name + "-headless"
+}
+
+func getServiceName(database *databasesamplev1beta1.Database) string {
+	return database.Name
+}
+
+func getAdminSecretName(database *databasesamplev1beta1.Database) string {
+	return database.Name + "-admin"
+}
+
+func getInitConfigMapName(database *databasesamplev1beta1.Database) string {
+	return database.Name + "-init"
+}
+
+func getConnectionSecretName(database *databasesamplev1beta1.Database) string {
+	return database.Name + "-connection"
+}
+
+// Note: The claim is created by the stateful set from its volume claim template for the only pod
+func getDataClaimName(database *databasesamplev1beta1.Database) string {
+	return dataVolumeName + "-" + getStatefulSetName(database) + "-0"
+}
+
+func getUpgradeBackupClaimName(database *databasesamplev1beta1.Database) string {
+	return database.Name + "-upgrade-backups"
+}
+
+func getLabels(database *databasesamplev1beta1.Database) map[string]string {
+	return map[string]string{
+		labelName:      database.GetEngine(),
+		labelInstance:  database.Name,
+		labelManagedBy: "operator-database",
+	}
+}